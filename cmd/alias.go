@@ -0,0 +1,23 @@
+package cmd
+
+import "strings"
+
+// expandAlias replaces alias from the CLI config with its command.
+// Built in commands can't be overridden by aliases.
+func expandAlias(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	if c, _, err := rootCmd.Find(args[:1]); err == nil && c != rootCmd {
+		return args
+	}
+	c, err := loadUserConfig()
+	if err != nil {
+		return args
+	}
+	alias, ok := c.Aliases[args[0]]
+	if !ok {
+		return args
+	}
+	return append(strings.Fields(alias), args[1:]...)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var allocFilter deploy.AllocFilter
+
+var allocCmd = &cobra.Command{
+	Use:   "alloc",
+	Short: "Nomad allocations",
+}
+
+var allocLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "Lists allocations with task states and last events",
+	Long: `Lists allocations in datacenter, across all jobs or of a single service,
+  with task states, restart counts, last task event and the last error
+  (driver, setup or download error, non zero exit code).
+
+  Examples:
+    pitwall alloc ls --dc pg1 --status failed
+    pitwall alloc ls --dc pg1 --service backend_api
+    pitwall alloc ls --dc pg1 --node app1 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Allocs(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			Output:     output,
+		}, allocFilter))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(allocCmd)
+	allocCmd.AddCommand(allocLsCmd)
+
+	allocLsCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the allocations")
+	allocLsCmd.MarkFlagRequired("dc")
+	allocLsCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+	allocLsCmd.Flags().StringVar(&allocFilter.Service, "service", "", "allocations of the service job")
+	allocLsCmd.Flags().StringVar(&allocFilter.Status, "status", "", "client status: pending, running, complete, failed or lost")
+	allocLsCmd.Flags().StringVar(&allocFilter.Node, "node", "", "node name or ID prefix")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var auditSince time.Duration
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [service]",
+	Short: "Shows deploy audit log",
+	Long: `Shows who deployed what and when in the deployment.
+  Audit log is stored in Consul KV, or in file set by audit.file in config.yml.
+
+  Examples:
+    pitwall audit -d s2
+    pitwall audit backend_api -d s2 --dc pg1 --since 168h`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		setDeploymentConsul()
+		exit(deploy.Audit(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    service,
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+			Consul:     consul,
+			Dc:         dc,
+		}, auditSince))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment")
+	auditCmd.MarkFlagRequired("dep")
+	auditCmd.Flags().StringVar(&dc, "dc", "", "show only deploys to this datacenter")
+	auditCmd.Flags().DurationVar(&auditSince, "since", 0, "show only deploys newer than this")
+}
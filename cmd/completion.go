@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+// bashCompletionFunc completes service names for command arguments and
+// datacenter names for --dc flag from the deployment configs
+const bashCompletionFunc = `__pitwall_complete()
+{
+    local out
+    if out=$(pitwall __complete "$1" 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${out[*]}" -- "$cur" ) )
+    fi
+}
+
+__pitwall_complete_dcs()
+{
+    __pitwall_complete dcs
+}
+
+__custom_func()
+{
+    case ${last_command} in
+        pitwall | pitwall_completion | pitwall_config)
+            return
+            ;;
+        *)
+            __pitwall_complete services
+            return
+            ;;
+    esac
+}
+`
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh]",
+	Short: "Generates shell completion script",
+	Long: `Generates shell completion script.
+  Services and datacenters are completed from the deployment repository configs.
+
+  Examples:
+    source <(pitwall completion bash)
+    pitwall completion zsh > "${fpath[1]}/_pitwall"`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		markDcCompletion(rootCmd)
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletion(os.Stdout)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		}
+		return fmt.Errorf("unsupported shell %s", args[0])
+	},
+}
+
+// markDcCompletion sets custom completion for --dc flag of all commands
+func markDcCompletion(c *cobra.Command) {
+	if c.Flags().Lookup("dc") != nil {
+		c.MarkFlagCustom("dc", "__pitwall_complete_dcs")
+	}
+	for _, sc := range c.Commands() {
+		markDcCompletion(sc)
+	}
+}
+
+// completeCmd lists names for the completion script
+var completeCmd = &cobra.Command{
+	Use:    "__complete [services|dcs]",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		services, dcs, err := deploy.Names(deploy.Options{
+			Deployment: dep,
+			Path:       path,
+			Consul:     consul,
+			Env:        envName,
+			Config:     configSource,
+		})
+		if err != nil {
+			return err
+		}
+		names := services
+		if args[0] == "dcs" {
+			names = dcs
+		}
+		for _, n := range names {
+			fmt.Println(n)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(completeCmd)
+	rootCmd.BashCompletionFunction = bashCompletionFunc
+	completeCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment, all if not set")
+}
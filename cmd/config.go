@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var resolved bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Deployment config commands",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Shows deployment config",
+	Long: `Shows config.yml of the deployment and environment overlay selected by --env.
+  With --resolved shows effective config with overlay, environment variables
+  and defaults applied. Json and yaml output (-o) are resolved config.
+
+  Examples:
+    pitwall config show -d s2
+    pitwall config show -d s2 --env prod --resolved
+    pitwall config show -d s2 -o json`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return deploy.ConfigShow(deploy.Options{
+			Deployment: dep,
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+			Output:     output,
+		}, resolved)
+	},
+}
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff service dc1 dc2",
+	Short: "Shows differences of the service config between datacenters",
+	Long: `Shows field level differences of the service config.yml settings
+  between two datacenters, with defaults applied.
+
+  Examples:
+    pitwall config diff backend_api pg1 pg2 -d s2`,
+	Args:          cobra.ExactArgs(3),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return deploy.ConfigDiff(deploy.Options{
+			Deployment: dep,
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+		}, args[0], args[1], args[2])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configDiffCmd)
+
+	configShowCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment")
+	configShowCmd.Flags().BoolVar(&resolved, "resolved", false, "show effective config with overlay and defaults applied")
+	configShowCmd.MarkFlagRequired("dep")
+	configDiffCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment")
+	configDiffCmd.MarkFlagRequired("dep")
+}
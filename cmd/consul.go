@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var consulCmd = &cobra.Command{
+	Use:   "consul",
+	Short: "Browses Consul catalog",
+}
+
+var consulServicesCmd = &cobra.Command{
+	Use:   "services [pattern]",
+	Short: "Lists services registered in Consul",
+	Long: `Lists services registered in Consul in datacenter, with names containing pattern.
+  Shows number of instances, passing, warning and critical ones, and tags.
+
+  Examples:
+    pitwall consul services --dc pg1
+    pitwall consul services backend --dc pg1 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		pattern := ""
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+		exit(deploy.ConsulServices(interruptContext(), consulOptions(), pattern))
+	},
+}
+
+var consulInstancesCmd = &cobra.Command{
+	Use:   "instances <name>",
+	Short: "Lists instances of the service registered in Consul",
+	Long: `Lists instances of the service registered in Consul in datacenter with address,
+  node, tags and health. Checks which are not passing are shown with their output.
+
+  Examples:
+    pitwall consul instances backend_api --dc pg1`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		exit(deploy.ConsulInstances(interruptContext(), consulOptions(), args[0]))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(consulCmd)
+	for _, c := range []*cobra.Command{consulServicesCmd, consulInstancesCmd} {
+		consulCmd.AddCommand(c)
+		c.Flags().StringVar(&dc, "dc", "", "consul datacenter")
+		c.MarkFlagRequired("dc")
+	}
+}
+
+func consulOptions() deploy.Options {
+	return deploy.Options{
+		Consul: consul,
+		Dc:     dc,
+		Output: output,
+	}
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/minus5/pitwall/deploy"
 	_ "github.com/minus5/svckit/dcy/lazy"
 
@@ -9,29 +11,81 @@ import (
 )
 
 var deployCmd = &cobra.Command{
-	Use:   "deploy <service>",
+	Use:   "deploy <service>...",
 	Short: "Deploys service to a deployment",
-	Long:  ``,
+	Long: `Deploys service to a deployment.
+Multiple services are deployed one by one, ordered by depends_on from config.yml.
+
+Exit codes:
+  1 error, 2 invalid arguments or config, 3 plan conflict or service locked,
+  4 deployment failed, 5 deployment timed out, 6 connection error`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) > 1 {
-			cmd.Usage()
-			return
-		}
 		service := ""
+		var services []string
 		if len(args) == 1 {
 			service = args[0]
 		}
-
-		// temporary (hopefully) fix for consul address
-		if !rootCmd.Flags().Changed("consul") && dep != "s2" {
-			consul = fmt.Sprintf("http://%s-consul.dev.minus5.hr:8500", dep)
+		if len(args) > 1 {
+			services = args
 		}
 
-		deploy.Run(dep, service, path, registry, image, noGit, consul, dryRun)
+		if allDcs && dc != "" || len(services) > 0 && (image != "" || imageFromGit) {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Run(interruptContext(), deploy.Options{
+			Deployment:   dep,
+			Service:      service,
+			Services:     services,
+			Path:         path,
+			Config:       configSource,
+			Env:          envName,
+			Registry:     registry,
+			Image:        image,
+			Consul:       consul,
+			Nomad:        nomadConfig,
+			Namespace:    namespace,
+			NoGit:        noGit,
+			DryRun:       dryRun,
+			Canary:       canary,
+			Dc:           dc,
+			Parallel:     allDcs,
+			WaitTime:     waitTime,
+			AutoRevert:   autoRevert,
+			Timeout:      timeout,
+			Yes:          yes,
+			Output:       output,
+			NoLock:       noLock,
+			Src:          src,
+			ImageFromGit: imageFromGit,
+			Vars:         jobVars,
+			VarFiles:     jobVarFiles,
+		}))
 	},
 }
 
-var dryRun bool
+// setDeploymentConsul sets consul address for deployment
+// temporary (hopefully) fix for consul address
+func setDeploymentConsul() {
+	if !rootCmd.Flags().Changed("consul") && dep != "" && dep != "s2" {
+		consul = fmt.Sprintf("http://%s-consul.dev.minus5.hr:8500", dep)
+	}
+}
+
+var (
+	dryRun       bool
+	canary       bool
+	allDcs       bool
+	waitTime     time.Duration
+	autoRevert   bool
+	timeout      time.Duration
+	yes          bool
+	noLock       bool
+	src          string
+	imageFromGit bool
+	jobVars      []string
+	jobVarFiles  []string
+)
 
 func init() {
 	rootCmd.AddCommand(deployCmd)
@@ -39,8 +93,22 @@ func init() {
 	deployCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to deploy to")
 	deployCmd.MarkFlagRequired("dep")
 
+	deployCmd.Flags().StringVar(&dc, "dc", "", "deploy only to this datacenter (default all service datacenters)")
+	deployCmd.Flags().BoolVar(&allDcs, "all-dcs", false, "deploy to all service datacenters concurrently")
+
 	deployCmd.Flags().StringVar(&image, "image", "", "deploy this image instead of selecting from registry")
+	deployCmd.Flags().BoolVar(&imageFromGit, "image-from-git", false, "deploy image built from the service source repository head")
+	deployCmd.Flags().StringVar(&src, "src", "", "service source repository path (default from config.yml or current dir)")
 	deployCmd.Flags().StringVar(&registry, "registry", "registry.dev.minus5.hr", "docker images registry url")
 
 	deployCmd.Flags().BoolVar(&dryRun, "dry", false, "do not make changes, show what you will do")
+	deployCmd.Flags().BoolVar(&canary, "canary", false, "stop when canaries are healthy, promote with promote command")
+	deployCmd.Flags().BoolVar(&autoRevert, "auto-revert", false, "revert to previous stable job version if deployment fails")
+	deployCmd.Flags().DurationVar(&timeout, "timeout", 0, "fail deployment if it doesn't finish in timeout (default from config.yml)")
+	deployCmd.Flags().BoolVarP(&yes, "yes", "y", false, "apply job plan without asking for confirmation")
+	deployCmd.Flags().BoolVar(&yes, "ci", false, "non-interactive mode for CI, same as --yes")
+	deployCmd.Flags().BoolVar(&noLock, "no-lock", false, "don't acquire Consul lock preventing concurrent deploys of the service")
+	deployCmd.Flags().DurationVar(&waitTime, "wait-time", deploy.DefaultWaitTime, "max duration of Nomad blocking queries")
+	deployCmd.Flags().StringArrayVar(&jobVars, "var", nil, "job spec variable, name=value, replaces ${var.name} references")
+	deployCmd.Flags().StringArrayVar(&jobVarFiles, "var-file", nil, "file with job spec variables")
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var deployedImageCmd = &cobra.Command{
+	Use:   "deployed-image <service>",
+	Short: "Shows image and digest of the running service",
+	Long: `Shows image of the service job running in datacenter and its digest
+  recorded at deploy time. Pinned reference can be used to reproduce the deploy.
+
+  Examples:
+    pitwall deployed-image backend_api --dc pg1
+    pitwall deployed-image backend_api --dc pg1 -d s2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.DeployedImage(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deployedImageCmd)
+
+	deployedImageCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the service")
+	deployedImageCmd.MarkFlagRequired("dc")
+	deployedImageCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+}
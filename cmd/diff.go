@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <service>",
+	Short: "Diffs running service job against the repository",
+	Long: `Renders service job from repository .nomad file and config.yml and shows
+  unified diff from the job running in datacenter. Diff is made locally,
+  without submitting a plan, so it needs only read access to Nomad.
+  Image from config.yml is used unless --image is set.
+  Exits with error if jobs differ.
+
+  Examples:
+    pitwall diff backend_api --dc pg1 -d s2
+    pitwall diff backend_api --dc pg1 -d s2 --image registry/backend_api:2.1.0`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Diff(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+			Image:      image,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			NoGit:      noGit,
+			Dc:         dc,
+			Vars:       jobVars,
+			VarFiles:   jobVarFiles,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the service")
+	diffCmd.MarkFlagRequired("dc")
+	diffCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+	diffCmd.Flags().StringVar(&image, "image", "", "compare with this image instead of the one in config")
+	diffCmd.Flags().StringArrayVar(&jobVars, "var", nil, "job spec variable, name=value")
+	diffCmd.Flags().StringArrayVar(&jobVarFiles, "var-file", nil, "file with job spec variables")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"io/ioutil"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/minus5/svckit/log"
+	"github.com/spf13/cobra"
+)
+
+var dispatchCmd = &cobra.Command{
+	Use:   "dispatch <job>",
+	Short: "Registers and dispatches parameterized job and follows its logs",
+	Long: `Registers parameterized Nomad job in datacenter and dispatches it.
+  Job is rendered from the .nomad file and config.yml as in deploy, with the image from config.yml.
+  Streams logs of the dispatched allocations and waits for them to finish.
+
+  Examples:
+    pitwall dispatch db_migrate --dc pg1 --meta version=42
+    pitwall dispatch report --dc pg1 -d s2 --payload ./report.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		var payload []byte
+		if payloadFile != "" {
+			buf, err := ioutil.ReadFile(payloadFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			payload = buf
+		}
+		setDeploymentConsul()
+		exit(deploy.Dispatch(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+			Image:      image,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			Yes:        yes,
+			NoLock:     noLock,
+			Vars:       jobVars,
+			VarFiles:   jobVarFiles,
+		}, dispatchMeta, payload))
+	},
+}
+
+var (
+	dispatchMeta map[string]string
+	payloadFile  string
+)
+
+func init() {
+	rootCmd.AddCommand(dispatchCmd)
+
+	dispatchCmd.Flags().StringVar(&dc, "dc", "", "datacenter to dispatch job in")
+	dispatchCmd.MarkFlagRequired("dc")
+	dispatchCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+	dispatchCmd.Flags().StringVar(&image, "image", "", "register job with this image instead of the one from config.yml")
+	dispatchCmd.Flags().BoolVarP(&yes, "yes", "y", false, "apply job plan without asking for confirmation")
+	dispatchCmd.Flags().BoolVar(&noLock, "no-lock", false, "don't acquire Consul lock preventing concurrent deploys of the service")
+	dispatchCmd.Flags().StringArrayVar(&jobVars, "var", nil, "job spec variable, name=value, replaces ${var.name} references")
+	dispatchCmd.Flags().StringArrayVar(&jobVarFiles, "var-file", nil, "file with job spec variables")
+
+	dispatchCmd.Flags().StringToStringVar(&dispatchMeta, "meta", nil, "dispatch meta key=value pairs")
+	dispatchCmd.Flags().StringVar(&payloadFile, "payload", "", "file with dispatch payload")
+}
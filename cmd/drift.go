@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift [service]",
+	Short: "Shows differences between running jobs and repository config",
+	Long: `Renders jobs from repository .nomad files and config.yml and compares
+  them with the jobs running in Nomad, catching manual changes.
+  Exits with error if any service is drifted.
+
+  Examples:
+    pitwall drift --dc pg1
+    pitwall drift backend_api --dc pg1 -d s2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		setDeploymentConsul()
+		exit(deploy.Drift(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    service,
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			NoGit:      noGit,
+			Dc:         dc,
+			Vars:       jobVars,
+			VarFiles:   jobVarFiles,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringVar(&dc, "dc", "", "datacenter to check")
+	driftCmd.MarkFlagRequired("dc")
+	driftCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+	driftCmd.Flags().StringArrayVar(&jobVars, "var", nil, "job spec variable, name=value, replaces ${var.name} references")
+	driftCmd.Flags().StringArrayVar(&jobVarFiles, "var-file", nil, "file with job spec variables")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	eventTopics  []string
+	eventService string
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Streams Nomad cluster events",
+	Long: `Subscribes to Nomad event stream in datacenter and shows events until interrupted.
+  Topics are Job, Evaluation, Allocation, Deployment and Node, all by default.
+  With --service only events of the service job (and node events) are shown.
+  Use -o json for raw events, one per line. Requires Nomad >= 1.0.
+
+  Examples:
+    pitwall events --dc pg1
+    pitwall events --dc pg1 --topic Deployment,Allocation --service backend_api`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.EventStream(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    eventService,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			Output:     output,
+		}, eventTopics))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().StringVar(&dc, "dc", "", "datacenter to watch")
+	eventsCmd.MarkFlagRequired("dc")
+	eventsCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+	eventsCmd.Flags().StringSliceVar(&eventTopics, "topic", nil, "comma separated topics: Job, Evaluation, Allocation, Deployment, Node")
+	eventsCmd.Flags().StringVar(&eventService, "service", "", "only events of the service job")
+}
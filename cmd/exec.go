@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os/exec"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var execTask string
+
+var execCmd = &cobra.Command{
+	Use:   "exec <service> [-- command]",
+	Short: "Runs command in the running service allocation",
+	Long: `Runs command (default /bin/sh) in the service allocation in datacenter.
+  Allocation is selected interactively if there are more than one.
+  Session is interactive, with tty if stdin is terminal.
+  Requires nomad cli >= 0.9.2 in PATH.
+
+  Examples:
+    pitwall exec backend_api --dc pg1
+    pitwall exec backend_api --dc pg1 -- ls -l /data`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		err := deploy.Exec(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+		}, execTask, args[1:])
+		if ee, ok := err.(*exec.ExitError); ok {
+			osExit(ee.ExitCode())
+		}
+		exit(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	execCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the service")
+	execCmd.MarkFlagRequired("dc")
+	execCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+	execCmd.Flags().StringVar(&execTask, "task", "", "task in allocation (default service name)")
+}
@@ -25,8 +25,7 @@ Filter patern refernece: https://docs.aws.amazon.com/AmazonCloudWatch/latest/Dev
     monit grep backend_api -s "1 day ago" -f "{$.retry>100}"`, monit.TimePatterns()),
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) > 1 {
-			cmd.Usage()
-			return
+			exitUsage(cmd)
 		}
 		service := ""
 		if len(args) == 1 {
@@ -36,8 +35,7 @@ Filter patern refernece: https://docs.aws.amazon.com/AmazonCloudWatch/latest/Dev
 		st, errSt := monit.ParseTime(startTime)
 		et, errEt := monit.ParseTime(endTime)
 		if errSt != nil || errEt != nil {
-			cmd.Usage()
-			return
+			exitUsage(cmd)
 		}
 
 		monit.Grep(monit.GrepOptions{
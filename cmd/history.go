@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <service>",
+	Short: "Lists previous job versions of the service",
+	Long: `Lists previous Nomad job versions of the service in datacenter.
+  Shows version, submit time, user, deployment status and image tag.
+  Stable versions are marked with *.
+
+  Examples:
+    pitwall history backend_api --dc pg1
+    pitwall history backend_api --dc pg1 -d s2
+    pitwall history backend_api --dc pg1 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.History(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			Output:     output,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the service")
+	historyCmd.MarkFlagRequired("dc")
+	historyCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <service>",
+	Short: "Shows definition of the running service job",
+	Long: `Shows definition of the service job running in datacenter,
+  as json or yaml (-o yaml). Needs only read access to Nomad.
+
+  Examples:
+    pitwall inspect backend_api --dc pg1
+    pitwall inspect backend_api --dc pg1 -o yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Inspect(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			Output:     output,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the service")
+	inspectCmd.MarkFlagRequired("dc")
+	inspectCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+}
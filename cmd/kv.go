@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var kvReason string
+
+var kvCmd = &cobra.Command{
+	Use:   "kv",
+	Short: "Manages services runtime configuration in Consul KV",
+	Long: `Manages services feature flags and runtime configuration in Consul KV.
+  Keys of the service are under service/<service>/ prefix and are given as <service>/<key>.`,
+}
+
+var kvGetCmd = &cobra.Command{
+	Use:   "get <service>/<key>",
+	Short: "Shows value of the service key",
+	Long: `Writes value of the service key to stdout.
+
+  Examples:
+    pitwall kv get backend_api/features/new_odds`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		exit(deploy.KVGet(interruptContext(), kvOptions(), args[0]))
+	},
+}
+
+var kvTreeCmd = &cobra.Command{
+	Use:   "tree <service>[/<prefix>]",
+	Short: "Lists service keys with values",
+	Long: `Lists service keys under prefix with their values, first line of each value.
+
+  Examples:
+    pitwall kv tree backend_api
+    pitwall kv tree backend_api/features -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		exit(deploy.KVTree(interruptContext(), kvOptions(), args[0]))
+	},
+}
+
+var kvPutCmd = &cobra.Command{
+	Use:   "put <service>/<key> <value|->",
+	Short: "Sets value of the service key",
+	Long: `Sets value of the service key, value - reads it from stdin.
+  Change is recorded in the deployment audit log, like deploys.
+
+  Examples:
+    pitwall kv put backend_api/features/new_odds true -d s2 --reason "enable for all"
+    pitwall kv put backend_api/limits -d s2 - < limits.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			exitUsage(cmd)
+		}
+		value := []byte(args[1])
+		if args[1] == "-" {
+			var err error
+			if value, err = ioutil.ReadAll(os.Stdin); err != nil {
+				exit(err)
+			}
+		}
+		setDeploymentConsul()
+		o := kvOptions()
+		o.Deployment = dep
+		o.Path = path
+		o.Config = configSource
+		o.NoGit = noGit
+		exit(deploy.KVPut(interruptContext(), o, args[0], value, kvReason))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(kvCmd)
+	for _, c := range []*cobra.Command{kvGetCmd, kvTreeCmd, kvPutCmd} {
+		kvCmd.AddCommand(c)
+		c.Flags().StringVar(&dc, "dc", "", "consul datacenter (default of the consul agent)")
+	}
+	kvPutCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment whose audit log records the change")
+	kvPutCmd.MarkFlagRequired("dep")
+	kvPutCmd.Flags().StringVar(&kvReason, "reason", "", "reason recorded in audit log")
+}
+
+func kvOptions() deploy.Options {
+	return deploy.Options{
+		Consul: consul,
+		Dc:     dc,
+		Output: output,
+	}
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/manifoldco/promptui"
+	"github.com/minus5/svckit/env"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// invocationsFile keeps last invocations by repository
+const invocationsFile = "~/.config/pitwall/history.yml"
+
+// invocationsKeep is number of invocations kept for each repository
+const invocationsKeep = 20
+
+// commands which are not recorded, secret and kv put have secret values in arguments
+var notRecorded = map[string]bool{"last": true, "redo": true, "help": true, "completion": true, "__complete": true,
+	"secret": true, "kv put": true}
+
+// redacted replaces values of sensitive flags in recorded invocations
+const redacted = "<redacted>"
+
+// flags with secret values, besides the ones with token, password or secret in name
+var sensitiveFlags = map[string]bool{"var": true, "meta": true}
+
+var faint = promptui.Styler(promptui.FGFaint)
+var success = promptui.Styler(promptui.FGGreen)
+var warn = promptui.Styler(promptui.FGRed)
+
+// invocation is one recorded pitwall command
+type invocation struct {
+	Time     time.Time     `yaml:"time"`
+	Args     []string      `yaml:"args"`
+	Exit     int           `yaml:"exit"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+func (i invocation) String() string {
+	result := success("ok")
+	if i.Exit != 0 {
+		result = warn(fmt.Sprintf("exit %d", i.Exit))
+	}
+	return fmt.Sprintf("%-15s %-8s %s", units.HumanDuration(time.Since(i.Time))+" ago", result, strings.Join(i.Args, " "))
+}
+
+// current invocation, set by Execute
+var (
+	invocationArgs  []string
+	invocationCmd   string // command path without pitwall, like secret put
+	invocationStart time.Time
+)
+
+var lastCmd = &cobra.Command{
+	Use:   "last [n]",
+	Short: "Shows last pitwall invocations in this repository",
+	Long: `Shows last n (default 10) pitwall invocations in the current git repository,
+  with their results. Most recent is number 1, rerun it with pitwall redo <number>.`,
+	Args:          cobra.RangeArgs(0, 1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 10
+		if len(args) == 1 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid number %s", args[0])
+			}
+		}
+		is, err := repoInvocations()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n && i < len(is); i++ {
+			fmt.Printf("%3d  %s\n", i+1, is[i])
+		}
+		return nil
+	},
+}
+
+var redoCmd = &cobra.Command{
+	Use:   "redo [number]",
+	Short: "Reruns one of the last pitwall invocations",
+	Long: `Reruns invocation from pitwall last list, the most recent one by default.
+
+  Examples:
+    pitwall redo
+    pitwall redo 3`,
+	Args:          cobra.RangeArgs(0, 1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 1
+		if len(args) == 1 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid number %s", args[0])
+			}
+		}
+		is, err := repoInvocations()
+		if err != nil {
+			return err
+		}
+		if n < 1 || n > len(is) {
+			return fmt.Errorf("invocation %d not found, there are %d", n, len(is))
+		}
+		fn, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		i := is[n-1]
+		for _, a := range i.Args {
+			if strings.HasSuffix(a, redacted) {
+				return fmt.Errorf("invocation %d has redacted flag values, run it again with the values", n)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", faint("pitwall "+strings.Join(i.Args, " ")))
+		p := exec.Command(fn, i.Args...)
+		p.Stdin = os.Stdin
+		p.Stdout = os.Stdout
+		p.Stderr = os.Stderr
+		err = p.Run()
+		if ee, ok := err.(*exec.ExitError); ok {
+			osExit(ee.ExitCode())
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lastCmd)
+	rootCmd.AddCommand(redoCmd)
+}
+
+// repoKey is git repository of the current directory, or directory if it is not in repository
+func repoKey() string {
+	if out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+func loadInvocations() (map[string][]invocation, error) {
+	m := make(map[string][]invocation)
+	buf, err := ioutil.ReadFile(env.ExpandPath(invocationsFile))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(buf, &m); err != nil {
+		return nil, fmt.Errorf("invalid history file %s: %v", invocationsFile, err)
+	}
+	if m == nil {
+		m = make(map[string][]invocation)
+	}
+	return m, nil
+}
+
+// repoInvocations returns invocations in current repository, most recent first
+func repoInvocations() ([]invocation, error) {
+	m, err := loadInvocations()
+	if err != nil {
+		return nil, err
+	}
+	return m[repoKey()], nil
+}
+
+// isRecorded is current invocation command, or any of its parents, not in notRecorded
+func isRecorded() bool {
+	if notRecorded[invocationArgs[0]] {
+		return false
+	}
+	path := strings.Fields(invocationCmd)
+	for i := range path {
+		if notRecorded[strings.Join(path[:i+1], " ")] {
+			return false
+		}
+	}
+	return true
+}
+
+func isSensitiveFlag(name string) bool {
+	if sensitiveFlags[name] {
+		return true
+	}
+	for _, s := range []string{"token", "password", "secret"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactArgs returns copy of args with values of sensitive flags redacted
+func redactArgs(args []string) []string {
+	out := append([]string(nil), args...)
+	for i := 0; i < len(out); i++ {
+		a := out[i]
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "--") {
+			continue
+		}
+		name := a[2:]
+		if j := strings.Index(name, "="); j >= 0 {
+			if isSensitiveFlag(name[:j]) {
+				out[i] = a[:j+3] + redacted
+			}
+			continue
+		}
+		if isSensitiveFlag(name) && i+1 < len(out) {
+			out[i+1] = redacted
+			i++
+		}
+	}
+	return out
+}
+
+// recordInvocation saves current invocation with its exit code.
+// Errors are ignored, history is not worth failing the command.
+func recordInvocation(code int) {
+	if len(invocationArgs) == 0 || !isRecorded() {
+		return
+	}
+	m, err := loadInvocations()
+	if err != nil {
+		return
+	}
+	key := repoKey()
+	is := append([]invocation{{
+		Time:     invocationStart,
+		Args:     redactArgs(invocationArgs),
+		Exit:     code,
+		Duration: time.Since(invocationStart).Round(time.Millisecond),
+	}}, m[key]...)
+	if len(is) > invocationsKeep {
+		is = is[:invocationsKeep]
+	}
+	m[key] = is
+	buf, err := yaml.Marshal(m)
+	if err != nil {
+		return
+	}
+	fn := env.ExpandPath(invocationsFile)
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return
+	}
+	ioutil.WriteFile(fn, buf, 0600)
+	invocationArgs = nil
+}
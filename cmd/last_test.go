@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{"deploy", "api", "--nomad-token", "s3cr3t", "--var=tag=1", "--var-file", "prod.vars",
+		"--registry-password=pass", "-d", "s2", "--", "--var", "x"}
+	assert.Equal(t, []string{"deploy", "api", "--nomad-token", redacted, "--var=" + redacted, "--var-file", "prod.vars",
+		"--registry-password=" + redacted, "-d", "s2", "--", "--var", "x"}, redactArgs(args))
+	assert.Equal(t, "s3cr3t", args[3])
+	assert.Equal(t, []string{"deploy", "--nomad-token"}, redactArgs([]string{"deploy", "--nomad-token"}))
+}
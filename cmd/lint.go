@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Validates deployment config files",
+	Long: `Validates config.yml of the deployment, or of all deployments.
+  Reports unknown keys, wrong types, duplicate keys and services without .nomad file.
+  Exits with non zero status if any problem is found.
+
+  Examples:
+    pitwall lint
+    pitwall lint -d s2`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return deploy.Lint(deploy.Options{
+			Deployment: dep,
+			Path:       path,
+			Config:     configSource,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to lint (default all)")
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/minus5/svckit/log"
+)
+
+var (
+	verbose  bool
+	quiet    bool
+	logLevel string
+	logJSON  bool
+)
+
+func init() {
+	fs := rootCmd.PersistentFlags()
+	fs.BoolVarP(&verbose, "verbose", "v", false, "show debug log lines")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "show only error log lines")
+	fs.StringVar(&logLevel, "log-level", "", "least severe log level shown: debug, info, notice, event, error or fatal (default info)")
+	fs.BoolVar(&logJSON, "log-json", false, "show log lines as json")
+}
+
+// applyLogFlags sets log level and format of the internal logger.
+// --log-level takes precedence over -v and -q.
+func applyLogFlags() error {
+	level := logLevel
+	if level == "" {
+		switch {
+		case verbose:
+			level = "debug"
+		case quiet:
+			level = "error"
+		default:
+			level = "info"
+		}
+	}
+	if err := deploy.ValidLogLevel(level); err != nil {
+		return err
+	}
+	deploy.LogLevel = level
+	deploy.LogJSON = logJSON
+	log.SetOutput(deploy.NewLogWriter(os.Stderr))
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsTask   string
+	logsStderr bool
+	logsFollow bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <service>",
+	Short: "Shows stdout or stderr of the service allocation",
+	Long: `Shows task stdout (or stderr) of the service allocation in datacenter,
+  read directly from Nomad. Allocation is selected interactively if there are more than one.
+  Unlike tail it works for services whose output isn't shipped to NSQ.
+  With follow only the end of the log is shown and new output is streamed.
+
+  Examples:
+    pitwall logs backend_api --dc pg1
+    pitwall logs backend_api --dc pg1 --stderr -f`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Logs(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+		}, logsTask, logsStderr, logsFollow))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the service")
+	logsCmd.MarkFlagRequired("dc")
+	logsCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+	logsCmd.Flags().StringVar(&logsTask, "task", "", "task in allocation (default service name)")
+	logsCmd.Flags().BoolVar(&logsStderr, "stderr", false, "show stderr instead of stdout")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "stream new output")
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nodeFilter      deploy.NodeFilter
+	drainDisable    bool
+	drainDeadline   time.Duration
+	drainIgnoreSys  bool
+	drainDetach     bool
+	eligibleEnable  bool
+	eligibleDisable bool
+)
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Nomad client nodes maintenance",
+}
+
+var nodeLsCmd = &cobra.Command{
+	Use:   "ls [node...]",
+	Short: "Lists nodes with services running on them",
+	Long: `Lists Nomad client nodes in datacenter with status, scheduling eligibility,
+  meta attributes used in service constraints (hostgroup, node, dc_region)
+  and services running on each node.
+
+  Examples:
+    pitwall node ls --dc pg1
+    pitwall node ls --dc pg1 --hostgroup app -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		nodeFilter.Names = args
+		setDeploymentConsul()
+		exit(deploy.Nodes(interruptContext(), nodeOptions(), nodeFilter))
+	},
+}
+
+var nodeDrainCmd = &cobra.Command{
+	Use:   "drain [node...]",
+	Short: "Drains nodes moving their services",
+	Long: `Drains nodes selected by name or meta attributes. Services which will move
+  are shown and confirmation asked. Nodes are drained one by one, each drain
+  is watched until all allocations are moved, unless --detach is set.
+  Use --disable to stop draining and make nodes eligible again.
+
+  Examples:
+    pitwall node drain --dc pg1 --node app3
+    pitwall node drain --dc pg1 --hostgroup app --deadline 5m
+    pitwall node drain --dc pg1 --node app3 --disable`,
+	Run: func(cmd *cobra.Command, args []string) {
+		nodeFilter.Names = args
+		setDeploymentConsul()
+		exit(deploy.DrainNodes(interruptContext(), nodeOptions(), nodeFilter,
+			!drainDisable, drainDeadline, drainIgnoreSys, drainDetach))
+	},
+}
+
+var nodeEligibilityCmd = &cobra.Command{
+	Use:   "eligibility [node...]",
+	Short: "Marks nodes eligible or ineligible for scheduling",
+	Long: `Marks nodes selected by name or meta attributes as eligible (--enable)
+  or ineligible (--disable) for new allocations, one of them is required.
+  Running allocations stay on ineligible nodes.
+
+  Examples:
+    pitwall node eligibility --dc pg1 --hostgroup app --disable
+    pitwall node eligibility --dc pg1 --hostgroup app --enable`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if eligibleEnable == eligibleDisable {
+			exitUsage(cmd)
+		}
+		nodeFilter.Names = args
+		setDeploymentConsul()
+		exit(deploy.NodesEligibility(interruptContext(), nodeOptions(), nodeFilter, eligibleEnable))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	for _, c := range []*cobra.Command{nodeLsCmd, nodeDrainCmd, nodeEligibilityCmd} {
+		nodeCmd.AddCommand(c)
+		c.Flags().StringVar(&dc, "dc", "", "datacenter of the nodes")
+		c.MarkFlagRequired("dc")
+		c.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+		c.Flags().StringVar(&nodeFilter.HostGroup, "hostgroup", "", "nodes with hostgroup meta")
+		c.Flags().StringVar(&nodeFilter.Node, "node", "", "nodes with node meta")
+		c.Flags().StringVar(&nodeFilter.DcRegion, "dc-region", "", "nodes with dc_region meta")
+	}
+
+	nodeDrainCmd.Flags().BoolVar(&drainDisable, "disable", false, "stop draining and make nodes eligible")
+	nodeDrainCmd.Flags().DurationVar(&drainDeadline, "deadline", time.Hour, "max duration before remaining allocations are stopped")
+	nodeDrainCmd.Flags().BoolVar(&drainIgnoreSys, "ignore-system", false, "keep system jobs on the nodes")
+	nodeDrainCmd.Flags().BoolVar(&drainDetach, "detach", false, "don't wait for drain to finish")
+	nodeDrainCmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask for confirmation")
+
+	nodeEligibilityCmd.Flags().BoolVar(&eligibleEnable, "enable", false, "mark nodes eligible")
+	nodeEligibilityCmd.Flags().BoolVar(&eligibleDisable, "disable", false, "mark nodes ineligible")
+	nodeEligibilityCmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask for confirmation")
+}
+
+func nodeOptions() deploy.Options {
+	return deploy.Options{
+		Deployment: dep,
+		Consul:     consul,
+		Nomad:      nomadConfig,
+		Namespace:  namespace,
+		Dc:         dc,
+		Output:     output,
+		Yes:        yes,
+	}
+}
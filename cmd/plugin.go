@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/minus5/pitwall/monit"
+	"github.com/minus5/svckit/env"
+	"github.com/spf13/cobra"
+)
+
+// pluginPrefix of the executables on PATH exposed as pitwall subcommands
+const pluginPrefix = "pitwall-"
+
+// pluginEnv are environment variables passed to plugin, with the flags
+// (long and short name) from which value is taken
+var pluginEnv = []struct {
+	name  string
+	flags []string
+}{
+	{"PITWALL_DC", []string{"dc"}},
+	{"PITWALL_SERVICE", []string{"service", "s"}},
+	{"PITWALL_DEP", []string{"dep", "d"}},
+	{"PITWALL_ENV", []string{"env"}},
+	{"PITWALL_NAMESPACE", []string{"namespace"}},
+	{"PITWALL_CONSUL", []string{"consul"}},
+	{"PITWALL_PATH", []string{"path"}},
+	{"PITWALL_CONFIG", []string{"config"}},
+}
+
+// addPlugins adds subcommand for each pitwall-<name> executable found on PATH.
+// Built in commands take precedence, first plugin on PATH wins.
+func addPlugins() {
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := strings.TrimPrefix(f.Name(), pluginPrefix)
+			if name == f.Name() || name == "" || f.IsDir() || f.Mode()&0111 == 0 {
+				continue
+			}
+			if c, _, err := rootCmd.Find([]string{name}); err == nil && c != rootCmd {
+				continue
+			}
+			rootCmd.AddCommand(pluginCmd(name, filepath.Join(dir, f.Name())))
+		}
+	}
+}
+
+func pluginCmd(name, fn string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: fmt.Sprintf("Plugin %s", fn),
+		Long: fmt.Sprintf(`Runs plugin %s with all arguments.
+  Context is passed in environment variables: PITWALL_DC, PITWALL_SERVICE,
+  PITWALL_DEP, PITWALL_ENV, PITWALL_NAMESPACE, PITWALL_CONSUL, PITWALL_PATH,
+  PITWALL_CONFIG and PITWALL_CONFIG_FILE.
+  Values are taken from arguments (like --dc pg1), active context or config defaults.`, fn),
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		SilenceErrors:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := loadUserConfig()
+			if err != nil {
+				return err
+			}
+			p := exec.Command(fn, args...)
+			p.Stdin = os.Stdin
+			p.Stdout = os.Stdout
+			p.Stderr = os.Stderr
+			p.Env = append(os.Environ(), pluginContext(args, c)...)
+			err = p.Run()
+			if ee, ok := err.(*exec.ExitError); ok {
+				osExit(ee.ExitCode())
+			}
+			return err
+		},
+	}
+}
+
+// pluginContext returns plugin environment variables.
+// Arguments take precedence over active context, context over config defaults,
+// and defaults over global flags default values.
+func pluginContext(args []string, c *userConfig) []string {
+	vars := []string{"PITWALL_CONFIG_FILE=" + env.ExpandPath(monit.ConfigFile)}
+	for _, e := range pluginEnv {
+		v := argValue(args, e.flags...)
+		if v == "" {
+			v = c.Context[e.flags[0]]
+		}
+		if v == "" {
+			v = c.Defaults[e.flags[0]]
+		}
+		if v == "" {
+			if f := rootCmd.PersistentFlags().Lookup(e.flags[0]); f != nil {
+				v = f.DefValue
+			}
+		}
+		if e.flags[0] == "path" && v != "" {
+			v = env.ExpandPath(v)
+		}
+		if v != "" {
+			vars = append(vars, e.name+"="+v)
+		}
+	}
+	return vars
+}
+
+// argValue finds flag value in arguments, like --dep s2, --dep=s2 or -d s2
+func argValue(args []string, names ...string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		for _, n := range names {
+			prefix := "--" + n
+			if len(n) == 1 {
+				prefix = "-" + n
+			}
+			if a == prefix && i+1 < len(args) {
+				return args[i+1]
+			}
+			if strings.HasPrefix(a, prefix+"=") {
+				return a[len(prefix)+1:]
+			}
+		}
+	}
+	return ""
+}
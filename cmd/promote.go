@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote <service>",
+	Short: "Promotes canaries of the running deployment",
+	Long: `Promotes canaries of the running service deployment.
+  Deploy with --canary to stop after canaries are healthy.
+
+  Examples:
+    pitwall deploy backend_api -d s2 --canary
+    pitwall promote backend_api -d s2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		setDeploymentConsul()
+		exit(deploy.Promote(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    service,
+			Path:       path,
+			Config:     configSource,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			NoGit:      noGit,
+			WaitTime:   waitTime,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to promote in")
+	promoteCmd.MarkFlagRequired("dep")
+
+	promoteCmd.Flags().StringVar(&dc, "dc", "", "datacenter to promote in (default all service datacenters)")
+	promoteCmd.Flags().DurationVar(&waitTime, "wait-time", deploy.DefaultWaitTime, "max duration of Nomad blocking queries")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repoURL           string
+	reconcileInterval time.Duration
+)
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Deploys services drifted from the deployment repository",
+	Long: `Watches deployment repository and deploys services whose image
+  in config.yml differs from the one running in Nomad, or whose config is changed.
+  Runs until interrupted.
+
+  Examples:
+    pitwall reconcile -d s2
+    pitwall reconcile -d s2 --dc pg1 --interval 5m`,
+	Run: func(cmd *cobra.Command, args []string) {
+		setDeploymentConsul()
+		exit(deploy.Reconcile(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+			Repo:       repoURL,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+		}, reconcileInterval))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+
+	reconcileCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to reconcile")
+	reconcileCmd.MarkFlagRequired("dep")
+	reconcileCmd.Flags().StringVar(&dc, "dc", "", "reconcile only this datacenter (default all)")
+	reconcileCmd.Flags().StringVar(&repoURL, "repo", deploy.DefaultRepo, "deployment git repository url")
+	reconcileCmd.Flags().DurationVar(&reconcileInterval, "interval", time.Minute, "interval between repository checks")
+}
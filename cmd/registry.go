@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var gcKeep int
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Docker registry maintenance",
+}
+
+var registryGCCmd = &cobra.Command{
+	Use:   "gc <service>",
+	Short: "Deletes old service image tags from registry",
+	Long: `Deletes old service image tags from registry.
+  The newest tags and tags referenced by any Nomad job version
+  in service datacenters are kept. Mutable tags, like latest, are never deleted.
+
+  Examples:
+    pitwall registry gc backend_api -d s2 --dry
+    pitwall registry gc backend_api -d s2 --keep 50`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.RegistryGC(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Path:       path,
+			Config:     configSource,
+			Registry:   registry,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			NoGit:      noGit,
+			DryRun:     dryRun,
+		}, gcKeep))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(registryCmd)
+	registryCmd.AddCommand(registryGCCmd)
+
+	registryGCCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the service")
+	registryGCCmd.MarkFlagRequired("dep")
+	registryGCCmd.Flags().StringVar(&registry, "registry", "registry.dev.minus5.hr", "docker images registry url")
+	registryGCCmd.Flags().IntVar(&gcKeep, "keep", 20, "number of the newest tags to keep")
+	registryGCCmd.Flags().BoolVar(&dryRun, "dry", false, "show tags which would be deleted")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var releaseCmd = &cobra.Command{
+	Use:   "release <service>",
+	Short: "Builds, pushes and deploys service image",
+	Long: `Builds docker image from the service repository, tags it with build time
+  and git sha, pushes it to registry and deploys it.
+  Service repository path and Dockerfile are set in build section of config.yml,
+  or by --src flag.
+
+  Examples:
+    pitwall release backend_api -d s2
+    pitwall release backend_api -d s2 --dc pg1 --src ~/work/backend_api`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || allDcs && dc != "" {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Release(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Path:       path,
+			Config:     configSource,
+			Env:        envName,
+			Registry:   registry,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			NoGit:      noGit,
+			Canary:     canary,
+			Dc:         dc,
+			Parallel:   allDcs,
+			WaitTime:   waitTime,
+			AutoRevert: autoRevert,
+			Timeout:    timeout,
+			Yes:        yes,
+			NoLock:     noLock,
+			Src:        src,
+			Vars:       jobVars,
+			VarFiles:   jobVarFiles,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(releaseCmd)
+
+	releaseCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to deploy to")
+	releaseCmd.MarkFlagRequired("dep")
+	releaseCmd.Flags().StringVar(&dc, "dc", "", "deploy only to this datacenter (default all service datacenters)")
+	releaseCmd.Flags().BoolVar(&allDcs, "all-dcs", false, "deploy to all service datacenters concurrently")
+	releaseCmd.Flags().StringVar(&src, "src", "", "service repository path (default from config.yml or current dir)")
+	releaseCmd.Flags().StringVar(&registry, "registry", "registry.dev.minus5.hr", "docker images registry url")
+
+	releaseCmd.Flags().BoolVar(&canary, "canary", false, "stop when canaries are healthy, promote with promote command")
+	releaseCmd.Flags().BoolVar(&autoRevert, "auto-revert", false, "revert to previous stable job version if deployment fails")
+	releaseCmd.Flags().DurationVar(&timeout, "timeout", 0, "fail deployment if it doesn't finish in timeout (default from config.yml)")
+	releaseCmd.Flags().DurationVar(&waitTime, "wait-time", deploy.DefaultWaitTime, "max duration of Nomad blocking queries")
+	releaseCmd.Flags().BoolVarP(&yes, "yes", "y", false, "apply job plan without asking for confirmation")
+	releaseCmd.Flags().BoolVar(&noLock, "no-lock", false, "don't acquire Consul lock preventing concurrent deploys of the service")
+	releaseCmd.Flags().StringArrayVar(&jobVars, "var", nil, "job spec variable, name=value, replaces ${var.name} references")
+	releaseCmd.Flags().StringArrayVar(&jobVarFiles, "var-file", nil, "file with job spec variables")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var restartReason string
+
+var restartCmd = &cobra.Command{
+	Use:   "restart <service>",
+	Short: "Rolling restart of the service allocations",
+	Long: `Restarts service allocations one by one, waiting for health between them.
+  Running job is re-registered with changed restarted_at meta, so allocations are
+  replaced by the job update stanza. Reason is recorded in audit log.
+
+  Examples:
+    pitwall restart backend_api -d s2 --dc pg1
+    pitwall restart backend_api -d s2 --reason "stuck consumers"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		setDeploymentConsul()
+		exit(deploy.Restart(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    service,
+			Path:       path,
+			Config:     configSource,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			NoGit:      noGit,
+			WaitTime:   waitTime,
+		}, restartReason))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restartCmd)
+
+	restartCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to restart in")
+	restartCmd.MarkFlagRequired("dep")
+	restartCmd.Flags().StringVar(&dc, "dc", "", "datacenter to restart in (default all service datacenters)")
+	restartCmd.Flags().StringVar(&restartReason, "reason", "", "reason recorded in audit log")
+	restartCmd.Flags().DurationVar(&waitTime, "wait-time", deploy.DefaultWaitTime, "max duration of Nomad blocking queries")
+}
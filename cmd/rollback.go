@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <service>",
+	Short: "Rollbacks service to the previous job version",
+	Long: `Rollbacks service to the previous stable Nomad job version.
+  Use --to-version to select exact job version.
+
+  Examples:
+    pitwall rollback backend_api -d s2
+    pitwall rollback backend_api -d s2 --dc pg1 --to-version 12`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		setDeploymentConsul()
+		exit(deploy.Rollback(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    service,
+			Path:       path,
+			Config:     configSource,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			NoGit:      noGit,
+			WaitTime:   waitTime,
+		}, toVersion))
+	},
+}
+
+var toVersion int
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to rollback in")
+	rollbackCmd.MarkFlagRequired("dep")
+
+	rollbackCmd.Flags().StringVar(&dc, "dc", "", "datacenter to rollback in (default all service datacenters)")
+	rollbackCmd.Flags().IntVar(&toVersion, "to-version", -1, "job version to rollback to (default previous stable)")
+	rollbackCmd.Flags().DurationVar(&waitTime, "wait-time", deploy.DefaultWaitTime, "max duration of Nomad blocking queries")
+}
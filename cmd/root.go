@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	_ "github.com/minus5/svckit/dcy/lazy"
 
+	capi "github.com/hashicorp/consul/api"
+	"github.com/minus5/pitwall/deploy"
 	"github.com/minus5/svckit/dcy"
 	"github.com/minus5/svckit/log"
 	"github.com/spf13/cobra"
@@ -19,6 +26,14 @@ var (
 	noGit    bool
 	consul   string
 	image    string
+
+	nomadConfig deploy.NomadConfig
+	namespace   string
+	envName     string
+
+	configSource string
+	profile      string
+	output       string
 )
 
 //var cfgFile string
@@ -32,15 +47,49 @@ examples and usage of using your application.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return deploy.WithExitCode(deploy.ExitValidation, applyUserConfig(cmd, args))
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.Version = Version
+	invocationStart = time.Now()
+	invocationArgs = expandAlias(os.Args[1:])
+	rootCmd.SetArgs(invocationArgs)
+	addPlugins()
+	if c, _, err := rootCmd.Find(invocationArgs); err == nil {
+		invocationCmd = strings.TrimPrefix(c.CommandPath(), rootCmd.Name()+" ")
+	}
+	rootCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return deploy.WithExitCode(deploy.ExitValidation, err)
+	})
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		osExit(deploy.ExitCode(err))
 	}
+	recordInvocation(0)
+}
+
+// osExit records invocation result and exits
+func osExit(code int) {
+	recordInvocation(code)
+	os.Exit(code)
+}
+
+// exit exits with exit code of the err, if it is not nil
+func exit(err error) {
+	if err != nil {
+		osExit(deploy.ExitCode(err))
+	}
+}
+
+// exitUsage shows command usage and exits with validation exit code
+func exitUsage(cmd *cobra.Command) {
+	cmd.Usage()
+	osExit(deploy.ExitValidation)
 }
 
 func init() {
@@ -57,6 +106,18 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&path, "path", "~/work/pit/infrastructure", "infastructure project path")
 	rootCmd.PersistentFlags().StringVar(&consul, "consul", "http://consul.s2.minus5.hr", "consul url")
 	rootCmd.PersistentFlags().BoolVar(&noGit, "no-git", false, "don't pull/push to infrastructure repository")
+	rootCmd.PersistentFlags().StringVar(&configSource, "config", "", "remote deployment config source, like consul://deploy/pg1 (instead of --path repository)")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "output format: table, json or yaml; deploy writes json or yaml report to stdout and logs to stderr, implies --yes")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "use flags saved in profile of ~/.config/pitwall/config.yml")
+	rootCmd.PersistentFlags().StringVar(&envName, "env", "", "environment overlay applied to deployment config, like prod for config.prod.yml")
+
+	// Nomad connection, defaults from NOMAD_TOKEN, NOMAD_CACERT... environment variables
+	rootCmd.PersistentFlags().StringVar(&nomadConfig.Token, "nomad-token", "", "Nomad ACL token")
+	rootCmd.PersistentFlags().BoolVar(&nomadConfig.TLS, "nomad-tls", false, "use https to connect to Nomad")
+	rootCmd.PersistentFlags().StringVar(&nomadConfig.CACert, "nomad-ca-cert", "", "path to CA certificate to verify Nomad server")
+	rootCmd.PersistentFlags().StringVar(&nomadConfig.ClientCert, "nomad-client-cert", "", "path to client certificate for Nomad TLS authentication")
+	rootCmd.PersistentFlags().StringVar(&nomadConfig.ClientKey, "nomad-client-key", "", "path to client key for Nomad TLS authentication")
+	rootCmd.PersistentFlags().StringVar(&namespace, "namespace", "", "Nomad namespace (default from service or datacenter config)")
 	//rootCmd.PersistentFlags().StringVarP(&dc, "dc", "d", "", "datacenter to deploy to")
 }
 
@@ -86,10 +147,30 @@ func initConfig() {
 	// }
 }
 
+// interruptContext returns context which is canceled on first interrupt signal.
+// Second interrupt terminates the process.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		signal.Stop(c)
+		cancel()
+	}()
+	return ctx
+}
+
 // getServiceAddress returns adress of service
 func getServiceAddress(names ...string) string {
+	return getServiceAddressInDc(dc, names...)
+}
+
+// getServiceAddressInDc returns adress of service in datacenter
+func getServiceAddressInDc(dc string, names ...string) string {
 	if err := dcy.ConnectTo(consul); err != nil {
-		log.Fatal(err)
+		log.Error(err)
+		osExit(deploy.ExitConnection)
 	}
 	for _, n := range names {
 		addr, err := dcy.ServiceInDc(n, dc)
@@ -97,6 +178,16 @@ func getServiceAddress(names ...string) string {
 			return addr.String()
 		}
 	}
-	log.Fatal(fmt.Errorf("service %v not found in consul %s ", names, consul))
+	log.Error(fmt.Errorf("service %v not found in consul %s ", names, consul))
+	osExit(deploy.ExitConnection)
 	return ""
 }
+
+// consulDatacenters returns all datacenters known to Consul
+func consulDatacenters() ([]string, error) {
+	cli, err := capi.NewClient(&capi.Config{Address: consul})
+	if err != nil {
+		return nil, err
+	}
+	return cli.Catalog().Datacenters()
+}
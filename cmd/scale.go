@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scaleGroup  string
+	scaleReason string
+	scaleSave   bool
+)
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale <service> <count>",
+	Short: "Scales service task group to count",
+	Long: `Sets count of the service task group in datacenter and waits for allocations.
+  Running job is re-registered with the new count, reason is recorded in audit log.
+  Use --save to write count to the datacenter config.yml so it survives the next deploy.
+
+  Examples:
+    pitwall scale backend_api 5 -d s2 --dc pg1 --reason "match day"
+    pitwall scale backend_api 3 -d s2 --dc pg1 --save`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			exitUsage(cmd)
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil || count < 0 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Scale(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Path:       path,
+			Config:     configSource,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			NoGit:      noGit,
+			WaitTime:   waitTime,
+		}, scaleGroup, count, scaleReason, scaleSave))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scaleCmd)
+
+	scaleCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to scale in")
+	scaleCmd.MarkFlagRequired("dep")
+	scaleCmd.Flags().StringVar(&dc, "dc", "", "datacenter to scale in")
+	scaleCmd.MarkFlagRequired("dc")
+	scaleCmd.Flags().StringVar(&scaleGroup, "group", "", "task group to scale (default the only one or named as service)")
+	scaleCmd.Flags().StringVar(&scaleReason, "reason", "", "reason recorded in audit log")
+	scaleCmd.Flags().BoolVar(&scaleSave, "save", false, "write count to the datacenter config")
+	scaleCmd.Flags().DurationVar(&waitTime, "wait-time", deploy.DefaultWaitTime, "max duration of Nomad blocking queries")
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/minus5/pitwall/monit"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Search logs in the time window",
+	Long: fmt.Sprintf(`Search logs of the <service> in datacenter <dc> stored in the persistent log store.
+  Uses the same filters as tail.
+
+  Supported time patterns (for since and until):%s
+
+  Examples:
+    monit search backend_api -d pg1 --since "12 hours ago" --level error
+    monit search backend_api -d pg1 --since "14.04. 22:00" --until "15.04. 06:00" --grep msg=timeout
+    monit search backend_api -d pg1 --since "1 hour ago" --source loki --addr loki.example.com:3100`, monit.TimePatterns()),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		st, err := monit.ParseTime(since)
+		if err != nil {
+			return err
+		}
+		et, err := monit.ParseTime(until)
+		if err != nil {
+			return err
+		}
+		return monit.Search(monit.GrepOptions{
+			Source:    logSource,
+			Address:   logSourceAddress("nsq-to-cloudwatch", "nsq_to_cloudwatch"),
+			Dc:        dc,
+			Service:   service,
+			Json:      json,
+			Pretty:    pretty,
+			Exclude:   splitComma(exclude),
+			Include:   splitComma(include),
+			StartTime: st,
+			EndTime:   et,
+			Grep:      grep,
+			GrepV:     grepV,
+			Level:     level,
+		})
+	},
+}
+
+var (
+	since string
+	until string
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().StringVarP(&dc, "dc", "d", "", "datacenter to use")
+	searchCmd.MarkFlagRequired("dc")
+
+	searchCmd.Flags().BoolVarP(&json, "json", "j", false, "print unparsed json log line")
+	searchCmd.Flags().BoolVarP(&pretty, "pretty", "p", false, "pretrty print json log line")
+	searchCmd.Flags().StringVarP(&exclude, "exclude", "x", "", "list of attributes to EXCLUDE separated by ,")
+	searchCmd.Flags().StringVarP(&include, "include", "i", "", "list of attributes to INCLUDE separated by ,")
+	searchCmd.Flags().StringArrayVar(&grep, "grep", nil, "show only lines matching regexp, or attribute=regexp")
+	searchCmd.Flags().StringArrayVar(&grepV, "grep-v", nil, "drop lines matching regexp, or attribute=regexp")
+	searchCmd.Flags().StringVar(&level, "level", "", "minimal log level to show: debug, info, notice, warn, error, fatal")
+	searchCmd.Flags().StringVar(&logSource, "source", monit.SourceNSQ, "log source: nsq, loki, elasticsearch, opensearch or kafka")
+	searchCmd.Flags().StringVar(&logAddr, "addr", "", "log source address, with index path for elasticsearch like es:9200/logs-*, brokers separated by , for kafka (default nsq service from Consul)")
+
+	searchCmd.Flags().StringVar(&since, "since", "", "find logs from time")
+	searchCmd.Flags().StringVar(&until, "until", "", "find logs till time (default now)")
+	searchCmd.MarkFlagRequired("since")
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	secretEnvFile string
+	secretReason  string
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manages services secrets in Vault",
+	Long: `Manages services secrets in Vault. Each service has secret at the path
+  configured in deployment config.yml, secret/data/{service} by default:
+
+    secrets:
+      address: https://vault.example.com:8200
+      path: secret/data/{service}
+
+  Vault address is taken from VAULT_ADDR if not configured, token from
+  VAULT_TOKEN or ~/.vault-token (vault login).`,
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <service> [field]",
+	Short: "Shows service secret field value",
+	Long: `Shows value of the service secret field, or all fields in env file format.
+
+  Examples:
+    pitwall secret get backend_api db_password -d s2
+    pitwall secret get backend_api -d s2 > backend_api.env`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 || len(args) > 2 {
+			exitUsage(cmd)
+		}
+		field := ""
+		if len(args) == 2 {
+			field = args[1]
+		}
+		exit(deploy.SecretGet(interruptContext(), secretOptions(args[0]), field))
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list <service>",
+	Short: "Lists service secret fields",
+	Long: `Lists fields of the service secret, without values, with the references
+  to use in service environment in config.yml, like vault:secret/data/backend_api#db_password.
+
+  Examples:
+    pitwall secret list backend_api -d s2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		exit(deploy.SecretList(interruptContext(), secretOptions(args[0])))
+	},
+}
+
+var secretPutCmd = &cobra.Command{
+	Use:   "put <service> [NAME=value...]",
+	Short: "Sets service secret fields",
+	Long: `Sets fields of the service secret, other fields are kept.
+  Fields are imported from env file, - reads it from stdin, or given as arguments.
+  Prefer env file, arguments with values end up in shell history.
+  Secret commands are not recorded in pitwall history (last, redo).
+  Change is recorded in the deployment audit log, without values.
+
+  Examples:
+    pitwall secret put backend_api -d s2 --from-env-file prod.env
+    pass backend_api/prod | pitwall secret put backend_api -d s2 --from-env-file - --reason "rotated"
+    pitwall secret put backend_api db_user=api -d s2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			exitUsage(cmd)
+		}
+		fields := make(map[string]string)
+		if secretEnvFile != "" {
+			f := os.Stdin
+			if secretEnvFile != "-" {
+				var err error
+				if f, err = os.Open(secretEnvFile); err != nil {
+					exit(deploy.WithExitCode(deploy.ExitValidation, err))
+				}
+				defer f.Close()
+			}
+			var err error
+			fields, err = deploy.ReadEnvFile(f)
+			if err != nil {
+				exit(deploy.WithExitCode(deploy.ExitValidation, fmt.Errorf("%s: %v", secretEnvFile, err)))
+			}
+		}
+		for _, a := range args[1:] {
+			i := strings.Index(a, "=")
+			if i <= 0 {
+				exitUsage(cmd)
+			}
+			fields[a[:i]] = a[i+1:]
+		}
+		exit(deploy.SecretPut(interruptContext(), secretOptions(args[0]), fields, secretReason))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	for _, c := range []*cobra.Command{secretGetCmd, secretListCmd, secretPutCmd} {
+		secretCmd.AddCommand(c)
+		c.Flags().StringVarP(&dep, "dep", "d", "", "deployment with secrets config")
+		c.MarkFlagRequired("dep")
+	}
+	secretPutCmd.Flags().StringVar(&secretEnvFile, "from-env-file", "", "import fields from env file with NAME=value lines, - for stdin")
+	secretPutCmd.Flags().StringVar(&secretReason, "reason", "", "reason recorded in audit log")
+}
+
+func secretOptions(service string) deploy.Options {
+	setDeploymentConsul()
+	return deploy.Options{
+		Deployment: dep,
+		Service:    service,
+		Path:       path,
+		Config:     configSource,
+		Env:        envName,
+		Consul:     consul,
+		NoGit:      noGit,
+		Output:     output,
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/minus5/pitwall/selfupdate"
+	"github.com/spf13/cobra"
+)
+
+// Version of the pitwall, set by main
+var Version = "dev"
+
+var selfUpdateOptions selfupdate.Options
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Updates pitwall to the latest or pinned release",
+	Long: `Downloads pitwall release binary for this platform, verifies checksum
+  and replaces running binary.
+  Pin team version with defaults.version in ~/.config/pitwall/config.yml.
+  Set GITHUB_TOKEN for private releases.
+
+  Examples:
+    pitwall self-update
+    pitwall self-update --version 2.1.0
+    pitwall self-update --check`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		o := selfUpdateOptions
+		o.Current = Version
+		if o.Token == "" {
+			o.Token = os.Getenv("GITHUB_TOKEN")
+		}
+		return selfupdate.Run(o)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selfUpdateCmd)
+	fs := selfUpdateCmd.Flags()
+	fs.StringVar(&selfUpdateOptions.Version, "version", "", "pinned release version, latest if empty")
+	fs.StringVar(&selfUpdateOptions.URL, "url", selfupdate.DefaultURL, "releases endpoint")
+	fs.StringVar(&selfUpdateOptions.Key, "key", "", "cosign public key, requires signed release checksums")
+	fs.BoolVar(&selfUpdateOptions.Check, "check", false, "only show available version")
+	fs.BoolVar(&selfUpdateOptions.Force, "force", false, "update even if already at version")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/monit"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "live statistics of <service> logs in datacenter <dc>",
+	Long: `Live statistics of <service> logs in datacenter <dc>.
+  Instead of printing lines shows counts per level, per logger, top error messages
+  and rate of lines per second, refreshing in place.
+  Accepts the same filters and sources as tail.
+
+  Examples:
+    monit stats backend_api -d pg1
+    monit stats backend_api -d pg1 --grep-v url=/health
+    monit stats backend_api -d pg1 --source loki --addr loki.example.com:3100`,
+	Args:          cobra.MaximumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		if direct {
+			logSource = monit.SourceNomad
+		}
+		address := ""
+		if logSource == monit.SourceNomad {
+			address = logSourceAddress("nomad")
+		} else {
+			address = logSourceAddress("nsq_notifier", "nsq-notifier")
+		}
+		return monit.Stats(monit.TailOptions{
+			Source:  logSource,
+			Address: address,
+			Service: service,
+			Grep:    grep,
+			GrepV:   grepV,
+			Level:   level,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVarP(&dc, "dc", "d", "", "datacenter to find service")
+	statsCmd.MarkFlagRequired("dc")
+
+	statsCmd.Flags().StringArrayVar(&grep, "grep", nil, "count only lines matching regexp, or attribute=regexp")
+	statsCmd.Flags().StringArrayVar(&grepV, "grep-v", nil, "drop lines matching regexp, or attribute=regexp")
+	statsCmd.Flags().StringVar(&level, "level", "", "minimal log level to count: debug, info, notice, warn, error, fatal")
+	statsCmd.Flags().StringVar(&logSource, "source", monit.SourceNSQ, "log source: nsq, loki, elasticsearch, opensearch, kafka or nomad")
+	statsCmd.Flags().BoolVar(&direct, "direct", false, "stream allocation logs from Nomad, same as --source nomad")
+	statsCmd.Flags().StringVar(&logAddr, "addr", "", "log source address (default nsq service from Consul)")
+}
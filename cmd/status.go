@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status [service]",
+	Short: "Shows status of the service in datacenter",
+	Long: `Shows Nomad job status, running/desired allocations, job version,
+  last deployment time and status, image tag and health of the service.
+  Without service shows all jobs in datacenter.
+
+  Examples:
+    pitwall status backend_api --dc pg1
+    pitwall status --dc pg1
+    pitwall status backend_api --dc pg1 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exitUsage(cmd)
+		}
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		setDeploymentConsul()
+		exit(deploy.Status(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    service,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			Output:     output,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVar(&dc, "dc", "", "datacenter of the service")
+	statusCmd.MarkFlagRequired("dc")
+	statusCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment of the datacenter")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/minus5/pitwall/deploy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stopPurge  bool
+	stopReason string
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop <service>",
+	Short: "Stops service job",
+	Long: `Stops (deregisters) service Nomad job in datacenter, after confirmation.
+  Use --purge to remove job from Nomad immediately, without its history.
+  Stop is recorded in audit log.
+
+  Examples:
+    pitwall stop old_api -d s2 --dc pg1
+    pitwall stop old_api -d s2 --dc pg1 --purge --reason "replaced by backend_api"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitUsage(cmd)
+		}
+		setDeploymentConsul()
+		exit(deploy.Stop(interruptContext(), deploy.Options{
+			Deployment: dep,
+			Service:    args[0],
+			Path:       path,
+			Config:     configSource,
+			Consul:     consul,
+			Nomad:      nomadConfig,
+			Namespace:  namespace,
+			Dc:         dc,
+			NoGit:      noGit,
+			Yes:        yes,
+		}, stopPurge, stopReason))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+
+	stopCmd.Flags().StringVarP(&dep, "dep", "d", "", "deployment to stop in")
+	stopCmd.MarkFlagRequired("dep")
+	stopCmd.Flags().StringVar(&dc, "dc", "", "datacenter to stop in")
+	stopCmd.MarkFlagRequired("dc")
+	stopCmd.Flags().BoolVar(&stopPurge, "purge", false, "remove job from Nomad immediately")
+	stopCmd.Flags().StringVar(&stopReason, "reason", "", "reason recorded in audit log")
+	stopCmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask for confirmation")
+}
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"fmt"
 	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
 
 	"github.com/minus5/pitwall/monit"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var tailCmd = &cobra.Command{
@@ -12,35 +17,156 @@ var tailCmd = &cobra.Command{
 	Short: "tail logs in datacenter <dc> for <service>",
 	Long: `Tail logs in datacenter <dc> for <service>.
   If services is missing it will list all available services in <dc>.
+  Multiple services, separated by , or as separate arguments, are tailed at once.
 
   Examples:
     monit tail haproxy
     monit tail --dc pg1 haproxy
     monit tail backend_api -i request_logger -t url,method
     monit tail backend_api -i request_logger -a duration,status,code,lib
-    monit tail backend_api -a listic -e request_logger.go:30`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) > 1 {
-			cmd.Usage()
-			return
+    monit tail backend_api -a listic -e request_logger.go:30
+    monit tail backend_api --fields req.headers.x-request-id,resp.status,items[0].id
+    monit tail backend_api,haproxy nsq_notifier
+    monit tail --dc pg1,pg2 backend_api
+    monit tail --all-dcs backend_api
+    monit tail backend_api --grep 'timeout|refused' --grep-v level=debug
+    monit tail backend_api --level warn
+    monit tail backend_api --source loki --addr loki.example.com:3100
+    monit tail backend_api --source kafka --addr kafka1:9092,kafka2:9092
+    monit tail backend_api --direct
+    monit tail backend_api --where 'status >= 500 && duration > 200ms && method != "GET"'
+    monit tail backend_api --alert 'level == "error"' --alert-cmd 'notify-send "backend_api error"'
+    monit tail backend_api --throughput 10s --lag log/nsq_notifier
+    monit tail backend_api --sample 1/100
+    monit tail backend_api --highlight 49B69912-5537,timeout
+    monit tail backend_api --format logfmt
+    monit tail backend_api --format csv --columns time,level,msg,duration > backend_api.csv
+    monit tail backend_api --level error --grep-v url=/health --save-profile api-errors
+    monit tail --profile api-errors
+    monit tail backend_api --out backend_api.log --rotate-size 100MB --rotate-keep 5`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if profile != "" && len(args) == 0 {
+			p, err := monit.LoadProfile(profile)
+			if err != nil {
+				return err
+			}
+			args = p.Services
+		}
+		if saveProfile != "" {
+			p := &monit.Profile{Services: args, Flags: profileFlags(cmd.Flags())}
+			if err := monit.SaveProfile(saveProfile, p); err != nil {
+				return err
+			}
+			fmt.Printf("saved profile %s to %s\n", saveProfile, monit.ConfigFile)
+		}
+		size, err := units.FromHumanSize(rotateSize)
+		if err != nil {
+			return err
+		}
+		var services []string
+		for _, a := range args {
+			services = append(services, splitComma(a)...)
+		}
+		if direct {
+			logSource = monit.SourceNomad
 		}
-		service := ""
-		if len(args) == 1 {
-			service = args[0]
+		dcs := splitComma(dc)
+		if allDcs {
+			if dcs, err = consulDatacenters(); err != nil {
+				return err
+			}
+		}
+		if len(dcs) == 0 {
+			return fmt.Errorf(`required flag(s) "dc" not set`)
+		}
+		names := []string{"nsq_notifier", "nsq-notifier"}
+		if logSource == monit.SourceNomad {
+			names = []string{"nomad"}
+		}
+		address := ""
+		var dcAddresses map[string]string
+		if len(dcs) > 1 {
+			if logAddr != "" {
+				return fmt.Errorf("--addr can't be used with multiple datacenters")
+			}
+			dcAddresses = make(map[string]string)
+			for _, d := range dcs {
+				dcAddresses[d] = getServiceAddressInDc(d, names...)
+			}
+			address = dcAddresses[dcs[0]]
+		} else {
+			dc = dcs[0]
+			address = logSourceAddress(names...)
 		}
 
-		monit.Tail(monit.TailOptions{
-			Address: getServiceAddress("nsq_notifier", "nsq-notifier"),
-			Service: service,
-			Json:    json,
-			Pretty:  pretty,
-			Exclude: splitComma(exclude),
-			Include: splitComma(include),
-		})
+		nsqd := ""
+		if lag != "" {
+			nsqd = getServiceAddressInDc(dcs[0], "nsqd-http", "nsqd_http", "nsqd")
+		}
+
+		return monit.Tail(monit.TailOptions{
+			Source:      logSource,
+			Address:     address,
+			DcAddresses: dcAddresses,
+			Services:    services,
+			Json:        json,
+			Pretty:      pretty,
+			Exclude:     splitComma(exclude),
+			Include:     splitComma(include),
+			Fields:      splitComma(fields),
+			Grep:        grep,
+			GrepV:       grepV,
+			Level:       level,
+			Format:      format,
+			Columns:     splitComma(columns),
+			Dedup:       dedup,
+			Sample:      sample,
+			Highlight:   splitComma(highlight),
+			Where:       where,
+			Throughput:  throughput,
+			Nsqd:        nsqd,
+			Lag:         lag,
+			Alert:       alertExpr,
+			AlertCmd:    alertCmd,
 
+			Out:        outFile,
+			RotateSize: size,
+			RotateKeep: rotateKeep,
+		})
 	},
 }
 
+// logSourceAddress returns address from flag, or finds nsq service address in Consul
+func logSourceAddress(names ...string) string {
+	if logAddr != "" {
+		return logAddr
+	}
+	return getServiceAddress(names...)
+}
+
+// profileSkipFlags are not saved in tail profile
+var profileSkipFlags = []string{"dc", "profile", "save-profile"}
+
+// profileFlags returns flags set on command line
+func profileFlags(fs *pflag.FlagSet) map[string][]string {
+	flags := make(map[string][]string)
+	fs.Visit(func(f *pflag.Flag) {
+		for _, s := range profileSkipFlags {
+			if f.Name == s {
+				return
+			}
+		}
+		if f.Value.Type() == "stringArray" {
+			flags[f.Name], _ = fs.GetStringArray(f.Name)
+			return
+		}
+		flags[f.Name] = []string{f.Value.String()}
+	})
+	return flags
+}
+
 func splitComma(s string) []string {
 	parts := strings.Split(s, ",")
 	if len(parts) == 1 && parts[0] == "" {
@@ -50,22 +176,66 @@ func splitComma(s string) []string {
 }
 
 var (
-	json    bool
-	pretty  bool
-	exclude string
-	include string
+	json        bool
+	pretty      bool
+	exclude     string
+	include     string
+	fields      string
+	grep        []string
+	grepV       []string
+	level       string
+	format      string
+	columns     string
+	dedup       bool
+	sample      string
+	highlight   string
+	where       string
+	alertExpr   string
+	alertCmd    string
+	throughput  time.Duration
+	lag         string
+	saveProfile string
+
+	logSource string
+	logAddr   string
+	direct    bool
+
+	outFile    string
+	rotateSize string
+	rotateKeep int
 )
 
 func init() {
 	rootCmd.AddCommand(tailCmd)
 	//monitCmd.AddCommand(tailCmd)
 
-	tailCmd.Flags().StringVarP(&dc, "dc", "d", "", "datacenter to find service")
-	tailCmd.MarkFlagRequired("dc")
+	tailCmd.Flags().StringVarP(&dc, "dc", "d", "", "datacenter to find service, multiple separated by , are tailed at once")
+	tailCmd.Flags().BoolVar(&allDcs, "all-dcs", false, "tail service in all datacenters")
 
 	tailCmd.Flags().BoolVarP(&json, "json", "j", false, "print unparsed json log line")
 	tailCmd.Flags().BoolVarP(&pretty, "pretty", "p", false, "pretrty print json log line")
 	tailCmd.Flags().StringVarP(&exclude, "exclude", "x", "", "list of attributes to EXCLUDE separated by ,")
 	tailCmd.Flags().StringVarP(&include, "include", "i", "", "list of attributes to INCLUDE separated by ,")
+	tailCmd.Flags().StringVar(&fields, "fields", "", "list of attribute paths to show separated by , nested like req.headers.x-request-id or items[0].id")
+	tailCmd.Flags().StringArrayVar(&grep, "grep", nil, "show only lines matching regexp, or attribute=regexp")
+	tailCmd.Flags().StringArrayVar(&grepV, "grep-v", nil, "drop lines matching regexp, or attribute=regexp")
+	tailCmd.Flags().StringVar(&level, "level", "", "minimal log level to show: debug, info, notice, warn, error, fatal")
+	tailCmd.Flags().StringVar(&format, "format", monit.FormatText, "output format: text, logfmt or csv")
+	tailCmd.Flags().StringVar(&columns, "columns", "", "list of attributes to output in logfmt or csv format separated by , (default all for logfmt, common for csv)")
+	tailCmd.Flags().BoolVar(&dedup, "dedup", true, "collapse consecutive identical lines into \"last line repeated N times\"")
+	tailCmd.Flags().StringVar(&sample, "sample", "", "show only every Nth matching line, like 1/100")
+	tailCmd.Flags().StringVar(&highlight, "highlight", "", "list of regexps to highlight in lines separated by ,")
+	tailCmd.Flags().StringVar(&saveProfile, "save-profile", "", "save flags and services as named profile in ~/.config/pitwall/config.yml")
+	tailCmd.Flags().StringVar(&where, "where", "", "show only lines matching expression on attributes, like 'status >= 500 && method != \"GET\"'")
+	tailCmd.Flags().StringVar(&alertExpr, "alert", "", "ring terminal bell when line matching expression appears, same syntax as --where")
+	tailCmd.Flags().StringVar(&alertCmd, "alert-cmd", "", "shell command to run on alert, line is in PITWALL_LINE environment variable and stdin")
+	tailCmd.Flags().DurationVar(&throughput, "throughput", 0, "show received and filtered messages rate on stderr every interval, like 10s")
+	tailCmd.Flags().StringVar(&lag, "lag", "", "nsq topic/channel whose depth is shown with throughput, like log/nsq_notifier")
+	tailCmd.Flags().StringVar(&logSource, "source", monit.SourceNSQ, "log source: nsq, loki, elasticsearch, opensearch, kafka or nomad")
+	tailCmd.Flags().BoolVar(&direct, "direct", false, "stream allocation logs from Nomad, same as --source nomad")
+	tailCmd.Flags().StringVar(&outFile, "out", "", "also write lines to file")
+	tailCmd.Flags().StringVar(&rotateSize, "rotate-size", "100MB", "rotate out file when it reaches size")
+	tailCmd.Flags().IntVar(&rotateKeep, "rotate-keep", monit.DefaultRotateKeep, "number of rotated out files to keep")
+	tailCmd.Flags().StringVar(&logAddr, "addr", "", "log source address, with index path for elasticsearch like es:9200/logs-*, brokers separated by , for kafka (default nsq service from Consul)")
 
 }
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/minus5/pitwall/monit"
+	"github.com/spf13/cobra"
+)
+
+// contextKeys are flags which can be set in the active context
+var contextKeys = []string{"dc", "env", "dep", "namespace", "consul", "path"}
+
+var useCmd = &cobra.Command{
+	Use:   "use [<key> <value>]",
+	Short: "Sets active context",
+	Long: fmt.Sprintf(`Sets active context, default value of the flag for all commands.
+  Without arguments shows active context. Value - removes key from context.
+  Flags set on command line take precedence over context.
+
+  Supported keys: %v
+
+  Examples:
+    pitwall use dc pg2
+    pitwall use env staging
+    pitwall use dc -
+    pitwall use`, contextKeys),
+	Args:          cobra.RangeArgs(0, 2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadUserConfig()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			printContext(c.Context)
+			return nil
+		}
+		if len(args) == 1 {
+			exitUsage(cmd)
+		}
+		key, value := args[0], args[1]
+		if !isContextKey(key) {
+			return fmt.Errorf("unsupported context key %s, expecting one of %v", key, contextKeys)
+		}
+		if c.Context == nil {
+			c.Context = make(map[string]string)
+		}
+		if value == "-" {
+			delete(c.Context, key)
+		} else {
+			c.Context[key] = value
+		}
+		var ctx interface{}
+		if len(c.Context) > 0 {
+			ctx = c.Context
+		}
+		if err := monit.SetConfig("context", ctx); err != nil {
+			return err
+		}
+		printContext(c.Context)
+		return nil
+	},
+}
+
+func isContextKey(key string) bool {
+	for _, k := range contextKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func printContext(ctx map[string]string) {
+	if len(ctx) == 0 {
+		fmt.Println("no active context")
+		return
+	}
+	var keys []string
+	for k := range ctx {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%-10s %s\n", k, ctx[k])
+	}
+}
+
+// noteContext shows which flags are set from the active context,
+// so it is not forgotten
+func noteContext(key, value string) {
+	fmt.Fprintf(os.Stderr, "using %s %s from context (pitwall use)\n", key, value)
+}
+
+func init() {
+	rootCmd.AddCommand(useCmd)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/minus5/pitwall/deploy"
+	"github.com/minus5/pitwall/monit"
+	"github.com/minus5/svckit/env"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// userConfig is pitwall CLI config file, like:
+//
+//	defaults:            # default flag values
+//	  dc: pg1
+//	  path: ~/work/infrastructure
+//	  output: json
+//	  log-level: error
+//	  version: 2.1.0      # release pinned for self-update
+//	credentials_helper: pass  # docker-credential-pass for registry credentials
+//	context:             # active context, set with pitwall use
+//	  env: staging
+//	aliases:             # pitwall <alias> runs the command with its flags
+//	  dapi: deploy backend_api --dc pg1
+//	profiles:            # named flags combinations, selected with --profile
+//	  api-errors:
+//	    services: [backend_api]
+//	    flags:
+//	      level: [error]
+type userConfig struct {
+	Defaults          map[string]string `yaml:"defaults,omitempty"`
+	CredentialsHelper string            `yaml:"credentials_helper,omitempty"`
+	Context           map[string]string `yaml:"context,omitempty"`
+	Aliases           map[string]string `yaml:"aliases,omitempty"`
+}
+
+func loadUserConfig() (*userConfig, error) {
+	c := &userConfig{}
+	buf, err := ioutil.ReadFile(env.ExpandPath(monit.ConfigFile))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(buf, c); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", monit.ConfigFile, err)
+	}
+	return c, nil
+}
+
+// applyUserConfig sets command flags from profile and config defaults.
+// Flags set on command line take precedence over profile,
+// profile over active context and context over defaults.
+// Flags set from config are not marked as changed, Changed still reports
+// only flags passed on command line.
+func applyUserConfig(cmd *cobra.Command, args []string) error {
+	c, err := loadUserConfig()
+	if err != nil {
+		return err
+	}
+	fs := cmd.Flags()
+	applied := make(map[string]bool)
+	if profile != "" {
+		p, err := monit.LoadProfile(profile)
+		if err != nil {
+			return err
+		}
+		if err := applyProfile(fs, p, applied); err != nil {
+			return err
+		}
+	}
+	if cmd != useCmd {
+		for name, value := range c.Context {
+			if f := configFlag(fs, name, applied); f != nil {
+				if err := f.Value.Set(value); err != nil {
+					return fmt.Errorf("invalid context %s: %v", name, err)
+				}
+				applied[name] = true
+				noteContext(name, value)
+			}
+		}
+	}
+	for name, value := range c.Defaults {
+		if f := configFlag(fs, name, applied); f != nil {
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("invalid default %s in %s: %v", name, monit.ConfigFile, err)
+			}
+			applied[name] = true
+		}
+	}
+	deploy.CredentialsHelper = c.CredentialsHelper
+	if err := applyLogFlags(); err != nil {
+		return err
+	}
+	return deploy.ValidOutput(output)
+}
+
+// applyProfile sets flags from profile, flags set on command line take precedence.
+// Profile flags which command doesn't have are ignored.
+func applyProfile(fs *pflag.FlagSet, p *monit.Profile, applied map[string]bool) error {
+	for name, values := range p.Flags {
+		f := configFlag(fs, name, applied)
+		if f == nil {
+			continue
+		}
+		for _, v := range values {
+			if err := f.Value.Set(v); err != nil {
+				return err
+			}
+		}
+		applied[name] = true
+	}
+	return nil
+}
+
+// configFlag returns flag which can be set from config,
+// nil if command doesn't have it or it is already set.
+func configFlag(fs *pflag.FlagSet, name string, applied map[string]bool) *pflag.Flag {
+	f := fs.Lookup(name)
+	if f == nil || f.Changed || applied[name] {
+		return nil
+	}
+	return f
+}
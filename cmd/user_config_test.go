@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/minus5/pitwall/monit"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyProfileNotChanged(t *testing.T) {
+	var consul, dc string
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringVar(&consul, "consul", "", "")
+	fs.StringVar(&dc, "dc", "", "")
+	assert.Nil(t, fs.Parse([]string{"--dc", "pg1"}))
+
+	applied := make(map[string]bool)
+	p := &monit.Profile{Flags: map[string][]string{"consul": {"http://consul:8500"}, "dc": {"pg2"}}}
+	assert.Nil(t, applyProfile(fs, p, applied))
+	assert.Equal(t, "http://consul:8500", consul)
+	assert.False(t, fs.Changed("consul"))
+	assert.Equal(t, "pg1", dc)
+	assert.Nil(t, configFlag(fs, "consul", applied))
+}
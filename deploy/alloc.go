@@ -0,0 +1,191 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/hashicorp/nomad/api"
+)
+
+// AllocFilter selects allocations. Empty fields match all allocations.
+type AllocFilter struct {
+	Service string // job name
+	Status  string // client status: pending, running, complete, failed or lost
+	Node    string // node name or ID prefix
+}
+
+// AllocInfo is allocation with states of its tasks
+type AllocInfo struct {
+	ID      string      `json:"id" yaml:"id"`
+	Service string      `json:"service" yaml:"service"`
+	Group   string      `json:"group" yaml:"group"`
+	Node    string      `json:"node" yaml:"node"`
+	Version uint64      `json:"version" yaml:"version"`
+	Status  string      `json:"status" yaml:"status"`
+	Desired string      `json:"desired" yaml:"desired"`
+	Created time.Time   `json:"created" yaml:"created"`
+	Tasks   []*TaskInfo `json:"tasks" yaml:"tasks"`
+}
+
+// TaskInfo is state of the allocation task with its last events
+type TaskInfo struct {
+	Task      string `json:"task" yaml:"task"`
+	State     string `json:"state" yaml:"state"`
+	Failed    bool   `json:"failed" yaml:"failed"`
+	Restarts  uint64 `json:"restarts" yaml:"restarts"`
+	LastEvent string `json:"last_event" yaml:"last_event"`
+	LastError string `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+}
+
+func (a AllocInfo) String() string {
+	status := a.Status
+	if status == "failed" || status == "lost" {
+		status = warn(status)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-8s %-30s %-20s %-20s %-6s %-8s %-5s %s\n",
+		a.ID[:8],
+		a.Service,
+		a.Group,
+		a.Node,
+		fmt.Sprintf("v%d", a.Version),
+		status,
+		a.Desired,
+		faint(units.HumanDuration(time.Since(a.Created))+" ago"))
+	for _, t := range a.Tasks {
+		fmt.Fprintf(&b, "         %-30s %-8s restarts %-3d %s\n", t.Task, t.State, t.Restarts, t.LastEvent)
+		if t.LastError != "" {
+			fmt.Fprintf(&b, "         %s\n", warn(t.LastError))
+		}
+	}
+	return b.String()
+}
+
+// Allocs lists allocations in Dc matching filter
+func Allocs(ctx context.Context, o Options, f AllocFilter) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	if err := d.connect(ctx); err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	as, err := d.Allocs(f)
+	if err != nil {
+		return done(err)
+	}
+	err = render(o.Output, as, func(w io.Writer) {
+		for _, a := range as {
+			fmt.Fprintf(w, "%s", a)
+		}
+	})
+	if err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// Allocs returns allocations matching filter, by service and the newest first
+func (d *Deployer) Allocs(f AllocFilter) ([]*AllocInfo, error) {
+	var stubs []*api.AllocationListStub
+	var err error
+	if f.Service != "" {
+		stubs, _, err = d.cli.Jobs().Allocations(f.Service, false, nil)
+	} else {
+		stubs, _, err = d.cli.Allocations().List(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	nodes := d.nodeNames()
+	var as []*AllocInfo
+	for _, s := range stubs {
+		if f.Status != "" && s.ClientStatus != f.Status {
+			continue
+		}
+		node := nodes[s.NodeID]
+		if f.Node != "" && f.Node != node && !strings.HasPrefix(s.NodeID, f.Node) {
+			continue
+		}
+		as = append(as, allocInfo(s, node))
+	}
+	sort.Slice(as, func(i, j int) bool {
+		if as[i].Service != as[j].Service {
+			return as[i].Service < as[j].Service
+		}
+		return as[i].Created.After(as[j].Created)
+	})
+	return as, nil
+}
+
+func allocInfo(s *api.AllocationListStub, node string) *AllocInfo {
+	a := &AllocInfo{
+		ID:      s.ID,
+		Service: s.JobID,
+		Group:   s.TaskGroup,
+		Node:    node,
+		Version: s.JobVersion,
+		Status:  s.ClientStatus,
+		Desired: s.DesiredStatus,
+		Created: time.Unix(0, s.CreateTime),
+	}
+	for task, ts := range s.TaskStates {
+		t := &TaskInfo{
+			Task:     task,
+			State:    ts.State,
+			Failed:   ts.Failed,
+			Restarts: ts.Restarts,
+		}
+		if n := len(ts.Events); n > 0 {
+			t.LastEvent = eventMessage(ts.Events[n-1])
+		}
+		t.LastError = lastError(ts)
+		a.Tasks = append(a.Tasks, t)
+	}
+	sort.Slice(a.Tasks, func(i, j int) bool { return a.Tasks[i].Task < a.Tasks[j].Task })
+	return a
+}
+
+// eventMessage is event type with its message
+func eventMessage(e *api.TaskEvent) string {
+	msg := e.DisplayMessage
+	if msg == "" {
+		msg = e.Message
+	}
+	if msg == "" {
+		return e.Type
+	}
+	return e.Type + ": " + msg
+}
+
+// eventError returns error text of the event, empty if there is none
+func eventError(e *api.TaskEvent) string {
+	for _, err := range []string{e.DriverError, e.DownloadError, e.ValidationError, e.SetupError, e.VaultError, e.KillError} {
+		if err != "" {
+			return err
+		}
+	}
+	if e.Type == api.TaskTerminated && e.ExitCode != 0 {
+		return fmt.Sprintf("exit code %d", e.ExitCode)
+	}
+	return ""
+}
+
+// lastError returns error text of the last failed task event
+func lastError(s *api.TaskState) string {
+	for i := len(s.Events) - 1; i >= 0; i-- {
+		if err := eventError(s.Events[i]); err != "" {
+			return err
+		}
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocInfo(t *testing.T) {
+	s := &api.AllocationListStub{
+		ID:           "12345678-abcd",
+		JobID:        "backend_api",
+		ClientStatus: "failed",
+		TaskStates: map[string]*api.TaskState{
+			"backend_api": {
+				State:    "dead",
+				Failed:   true,
+				Restarts: 2,
+				Events: []*api.TaskEvent{
+					{Type: api.TaskDriverFailure, DriverError: "image not found"},
+					{Type: api.TaskTerminated, ExitCode: 1, DisplayMessage: "Exit Code: 1"},
+					{Type: api.TaskNotRestarting, DisplayMessage: "Exceeded allowed attempts"},
+				},
+			},
+		},
+	}
+	a := allocInfo(s, "app1")
+	assert.Equal(t, "app1", a.Node)
+	assert.Len(t, a.Tasks, 1)
+	task := a.Tasks[0]
+	assert.Equal(t, uint64(2), task.Restarts)
+	assert.Equal(t, "Not Restarting: Exceeded allowed attempts", task.LastEvent)
+	assert.Equal(t, "exit code 1", task.LastError)
+}
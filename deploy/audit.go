@@ -0,0 +1,237 @@
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// auditPrefix is Consul KV prefix of the deploy audit records
+const auditPrefix = "pitwall/audit"
+
+// AuditConfig selects audit log store, Consul KV is used if File is empty
+type AuditConfig struct {
+	File string `yaml:"file,omitempty"`
+}
+
+// AuditRecord is audit log entry of one deploy, or of other action on the service
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Sha        string    `json:"sha"`
+	Deployment string    `json:"deployment"`
+	Service    string    `json:"service"`
+	Dc         string    `json:"dc"`
+	Action     string    `json:"action,omitempty"` // empty for deploy
+	Reason     string    `json:"reason,omitempty"`
+	Image      string    `json:"image"`
+	Digest     string    `json:"digest,omitempty"`
+	Result     string    `json:"result"`
+	Error      string    `json:"error,omitempty"`
+	Duration   string    `json:"duration"`
+}
+
+func (r AuditRecord) String() string {
+	result := r.Result
+	if r.Action != "" {
+		result = r.Action + " " + r.Result
+	}
+	s := fmt.Sprintf("%s %-10s %-8s %-20s %-6s %-10s %s %s",
+		r.Time.Format("2006-01-02 15:04:05"), r.User, r.Sha, r.Service, r.Dc, result, r.Duration, r.Image)
+	if r.Reason != "" {
+		s += " " + info(r.Reason)
+	}
+	if r.Error != "" {
+		s += " " + warn(r.Error)
+	}
+	return s
+}
+
+// AuditStore is append-only store of the audit records.
+// Default store is Consul KV or file from audit config,
+// MemAuditStore keeps records in memory.
+type AuditStore interface {
+	Append(r AuditRecord) error
+	List() ([]AuditRecord, error)
+}
+
+// auditLog is audit store in Consul KV or file
+type auditLog struct {
+	file       string
+	consul     string
+	deployment string
+}
+
+func newAuditLog(c *DeploymentConfig, consul, deployment string) *auditLog {
+	a := &auditLog{consul: consul, deployment: deployment}
+	if c != nil && c.Audit != nil && c.Audit.File != "" {
+		a.file = env.ExpandPath(c.Audit.File)
+	}
+	return a
+}
+
+func (a *auditLog) Append(r AuditRecord) error {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if a.file != "" {
+		f, err := os.OpenFile(a.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.Write(append(buf, '\n'))
+		return err
+	}
+	cli, err := capi.NewClient(&capi.Config{Address: a.consul})
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s/%s/%d-%s-%s", auditPrefix, a.deployment, r.Time.UnixNano(), r.Service, r.Dc)
+	// cas with index 0 writes only if the key doesn't exist
+	ok, _, err := cli.KV().CAS(&capi.KVPair{Key: key, Value: buf}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("audit record %s already exists", key)
+	}
+	return nil
+}
+
+// List audit records sorted by time
+func (a *auditLog) List() ([]AuditRecord, error) {
+	var rs []AuditRecord
+	if a.file != "" {
+		f, err := os.Open(a.file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			var r AuditRecord
+			if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+				return nil, err
+			}
+			rs = append(rs, r)
+		}
+		return rs, s.Err()
+	}
+	cli, err := capi.NewClient(&capi.Config{Address: a.consul})
+	if err != nil {
+		return nil, err
+	}
+	kvs, _, err := cli.KV().List(fmt.Sprintf("%s/%s/", auditPrefix, a.deployment), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		var r AuditRecord
+		if err := json.Unmarshal(kv.Value, &r); err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Time.Before(rs[j].Time) })
+	return rs, nil
+}
+
+// audit records deploy result to the audit log
+func (w *Worker) audit(d *Deployer, started time.Time, derr error) {
+	w.auditAction("", "", d, started, derr)
+}
+
+// auditAction records result of the action on the service, like scale or stop,
+// to the audit log. Empty action is deploy.
+func (w *Worker) auditAction(action, reason string, d *Deployer, started time.Time, derr error) {
+	if w.dryRun {
+		return
+	}
+	r := AuditRecord{
+		Time:       started,
+		User:       currentUser(),
+		Sha:        repoSha(w.root),
+		Deployment: w.deployment,
+		Service:    w.service,
+		Dc:         d.dc,
+		Action:     action,
+		Reason:     reason,
+		Image:      d.image,
+		Digest:     w.digest,
+		Result:     "succeeded",
+		Error:      errString(derr),
+		Duration:   time.Since(started).Round(time.Second).String(),
+	}
+	if derr != nil {
+		r.Result = "failed"
+	}
+	if err := w.auditStore().Append(r); err != nil {
+		log.S("service", w.service).S("dc", d.dc).Error(fmt.Errorf("audit log failed: %v", err))
+	}
+}
+
+// auditStore returns audit store from options or from deployment config
+func (w *Worker) auditStore() AuditStore {
+	if w.auditLog != nil {
+		return w.auditLog
+	}
+	return newAuditLog(w.depConfig, w.consul, w.deployment)
+}
+
+// repoSha returns short sha of the deployment repository head
+func repoSha(root string) string {
+	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Audit shows deploy audit log records
+// filtered by service and datacenter options, newer than since.
+func Audit(ctx context.Context, o Options, since time.Duration) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	store := o.AuditStore
+	if store == nil {
+		root, err := configRoot(o)
+		if err != nil {
+			return done(err)
+		}
+		c, err := NewDeploymentEnvConfig(root, o.Deployment, o.Env)
+		if err != nil {
+			return done(err)
+		}
+		store = newAuditLog(c, o.Consul, o.Deployment)
+	}
+	rs, err := store.List()
+	if err != nil {
+		return done(err)
+	}
+	for _, r := range rs {
+		if o.Service != "" && r.Service != o.Service ||
+			o.Dc != "" && r.Dc != o.Dc ||
+			since > 0 && time.Since(r.Time) > since {
+			continue
+		}
+		fmt.Printf("%s\n", r)
+	}
+	return nil
+}
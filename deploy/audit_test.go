@@ -0,0 +1,32 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := &auditLog{file: dir + "/audit.log"}
+	rs, err := a.List()
+	assert.NoError(t, err)
+	assert.Len(t, rs, 0)
+
+	now := time.Now().Round(time.Second)
+	assert.NoError(t, a.Append(AuditRecord{Time: now, Service: "svc1", Result: "succeeded"}))
+	assert.NoError(t, a.Append(AuditRecord{Time: now, Service: "svc2", Result: "failed", Error: "boom"}))
+
+	rs, err = a.List()
+	assert.NoError(t, err)
+	assert.Len(t, rs, 2)
+	assert.Equal(t, "svc2", rs[1].Service)
+	assert.Equal(t, "boom", rs[1].Error)
+	assert.True(t, now.Equal(rs[0].Time))
+}
@@ -0,0 +1,132 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// allocation client statuses
+const (
+	allocClientStatusComplete = "complete"
+	allocClientStatusFailed   = "failed"
+	allocClientStatusLost     = "lost"
+)
+
+// evaluation statuses
+const (
+	evalStatusComplete = "complete"
+	evalStatusFailed   = "failed"
+	evalStatusCanceled = "canceled"
+)
+
+func (d *Deployer) isBatch() bool {
+	return d.job != nil && d.job.Type != nil && *d.job.Type == JobTypeBatch
+}
+
+// batchStatus waits for allocations of the registered batch job to finish.
+// Periodic and parameterized jobs are not run on register so there is nothing to wait for.
+func (d *Deployer) batchStatus(ctx context.Context) error {
+	if d.job.IsPeriodic() || d.job.IsParameterized() {
+		d.log().Info("job registered, it will be run by schedule or dispatch")
+		return nil
+	}
+	if d.jobEvalID == "" {
+		return nil
+	}
+
+	ev, _, err := d.cli.Evaluations().Info(d.jobEvalID, nil)
+	if err != nil {
+		return err
+	}
+	if err := d.placementError(ev); err != nil {
+		return err
+	}
+
+	q := d.blockingQuery()
+	for {
+		if err := ctx.Err(); err != nil {
+			d.log().S("evalID", d.jobEvalID).Info("stopped watching job, it is still running in Nomad")
+			return err
+		}
+		allocs, meta, err := d.cli.Evaluations().Allocations(d.jobEvalID, q)
+		if err != nil {
+			return err
+		}
+		if len(allocs) == 0 {
+			d.log().Info("no allocations placed")
+			return nil
+		}
+		if allocsTerminal(allocs) {
+			return d.batchResult(allocs)
+		}
+		d.log().I("allocs", len(allocs)).I("running", allocsRunning(allocs)).Debug("checking status")
+		q.WaitIndex = meta.LastIndex
+	}
+}
+
+// placementError shows task groups which evaluation failed to place
+func (d *Deployer) placementError(ev *api.Evaluation) error {
+	if len(ev.FailedTGAllocs) == 0 {
+		return nil
+	}
+	for tg := range ev.FailedTGAllocs {
+		d.log().S("group", tg).ErrorS("failed to place allocations")
+	}
+	return fmt.Errorf("evaluation %s failed to place allocations", ev.ID)
+}
+
+func allocsTerminal(allocs []*api.AllocationListStub) bool {
+	for _, a := range allocs {
+		switch a.ClientStatus {
+		case allocClientStatusComplete, allocClientStatusFailed, allocClientStatusLost:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func allocsRunning(allocs []*api.AllocationListStub) int {
+	n := 0
+	for _, a := range allocs {
+		if a.ClientStatus == "running" {
+			n++
+		}
+	}
+	return n
+}
+
+// batchResult shows task exit codes and fails if any allocation failed
+func (d *Deployer) batchResult(allocs []*api.AllocationListStub) error {
+	failed := 0
+	for _, a := range allocs {
+		if a.ClientStatus != allocClientStatusComplete {
+			failed++
+		}
+		for task, s := range a.TaskStates {
+			l := d.log().S("alloc", a.ID[:8]).S("task", task).I("exitCode", exitCode(s))
+			if s.Failed {
+				l.ErrorS("task failed")
+				continue
+			}
+			l.Info("task finished")
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d allocations failed", failed, len(allocs))
+	}
+	d.log().I("allocs", len(allocs)).Info("job finished")
+	return nil
+}
+
+// exitCode of the last terminated event
+func exitCode(s *api.TaskState) int {
+	for i := len(s.Events) - 1; i >= 0; i-- {
+		if e := s.Events[i]; e.Type == api.TaskTerminated {
+			return e.ExitCode
+		}
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package deploy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchResult(t *testing.T) {
+	allocs := []*api.AllocationListStub{
+		{
+			ID:           "11111111-aaaa",
+			ClientStatus: allocClientStatusComplete,
+			TaskStates: map[string]*api.TaskState{
+				"task": {Events: []*api.TaskEvent{{Type: api.TaskStarted}, {Type: api.TaskTerminated}}},
+			},
+		},
+		{
+			ID:           "22222222-bbbb",
+			ClientStatus: "running",
+		},
+	}
+	assert.False(t, allocsTerminal(allocs))
+	assert.Equal(t, 1, allocsRunning(allocs))
+
+	allocs[1].ClientStatus = allocClientStatusFailed
+	allocs[1].TaskStates = map[string]*api.TaskState{
+		"task": {Failed: true, Events: []*api.TaskEvent{{Type: api.TaskTerminated, ExitCode: 2}}},
+	}
+	assert.True(t, allocsTerminal(allocs))
+	assert.Equal(t, 2, exitCode(allocs[1].TaskStates["task"]))
+	var out bytes.Buffer
+	d := &Deployer{logOut: &out}
+	assert.Error(t, d.batchResult(allocs))
+	assert.NoError(t, d.batchResult(allocs[:1]))
+	assert.Contains(t, out.String(), "job finished")
+}
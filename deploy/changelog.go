@@ -0,0 +1,44 @@
+package deploy
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// imageSha returns git sha from the image tag, like 99a146a in 20160613151056.99a146a
+func imageSha(image string) string {
+	_, _, tag := splitImage(image)
+	t := NewTag(tag, false)
+	if t.created.IsZero() {
+		return ""
+	}
+	parts := strings.Split(tag, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// changelog returns commit subjects between running and new image,
+// empty if it can't be found from the source repository
+func (d *Deployer) changelog() string {
+	if d.src == "" {
+		return ""
+	}
+	running, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return ""
+	}
+	from, to := imageSha(jobImage(running, d.service)), imageSha(d.image)
+	if from == "" || to == "" || from == to {
+		return ""
+	}
+	cmd := exec.Command("git", "log", "--format=%h %an: %s", from+".."+to)
+	cmd.Dir = d.src
+	out, err := cmd.Output()
+	if err != nil {
+		d.log().S("src", d.src).S("range", from+".."+to).Debug("changelog not found")
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,48 @@
+package deploy
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Names returns service and datacenter names found in config.yml of the
+// deployment, or of all deployments if Deployment option is empty.
+// Used for shell completion.
+func Names(o Options) (services []string, dcs []string, err error) {
+	root, err := configRoot(o)
+	if err != nil {
+		return nil, nil, err
+	}
+	deployments := []string{o.Deployment}
+	if o.Deployment == "" {
+		fns, _ := filepath.Glob(filepath.Join(root, "deployments", "*", "config.yml"))
+		deployments = deployments[:0]
+		for _, fn := range fns {
+			deployments = append(deployments, filepath.Base(filepath.Dir(fn)))
+		}
+	}
+	ss := make(map[string]struct{})
+	ds := make(map[string]struct{})
+	for _, dep := range deployments {
+		c, err := NewDeploymentEnvConfig(root, dep, o.Env)
+		if err != nil {
+			continue
+		}
+		for dc, dcc := range c.Datacenters {
+			ds[dc] = struct{}{}
+			for s := range dcc.Services {
+				ss[s] = struct{}{}
+			}
+		}
+	}
+	return sortedKeys(ss), sortedKeys(ds), nil
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
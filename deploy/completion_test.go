@@ -0,0 +1,32 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNames(t *testing.T) {
+	root, err := ioutil.TempDir("", "names")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	for dep, config := range map[string]string{
+		"d1": "datacenters:\n  pg1:\n    services:\n      api: {image: api:1}\n      worker: {image: worker:1}\n",
+		"d2": "datacenters:\n  pg2:\n    services:\n      api: {image: api:1}\n",
+	} {
+		assert.NoError(t, os.MkdirAll(root+"/deployments/"+dep, 0755))
+		assert.NoError(t, ioutil.WriteFile(root+"/deployments/"+dep+"/config.yml", []byte(config), 0644))
+	}
+
+	services, dcs, err := Names(Options{Path: root})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api", "worker"}, services)
+	assert.Equal(t, []string{"pg1", "pg2"}, dcs)
+
+	services, dcs, err = Names(Options{Path: root, Deployment: "d2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api"}, services)
+	assert.Equal(t, []string{"pg2"}, dcs)
+}
@@ -0,0 +1,110 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConfigDiff prints field level differences of the service config
+// between two datacenters, with defaults applied.
+func ConfigDiff(o Options, service, dc1, dc2 string) error {
+	root, err := configRoot(o)
+	if err != nil {
+		return err
+	}
+	c, err := NewDeploymentEnvConfig(root, o.Deployment, o.Env)
+	if err != nil {
+		return err
+	}
+	s1 := c.FindForDc(service, dc1)
+	if s1 == nil {
+		return fmt.Errorf("service %s not found in datacenter %s", service, dc1)
+	}
+	s2 := c.FindForDc(service, dc2)
+	if s2 == nil {
+		return fmt.Errorf("service %s not found in datacenter %s", service, dc2)
+	}
+	lines, err := serviceDiff(s1, s2)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s => %s\n", service, dc1, dc2)
+	if len(lines) == 0 {
+		fmt.Println(success("no differences"))
+	}
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	return nil
+}
+
+// serviceDiff returns changed fields between service configs, sorted by field name
+func serviceDiff(s1, s2 *ServiceConfig) ([]string, error) {
+	f1, err := flattenConfig(s1)
+	if err != nil {
+		return nil, err
+	}
+	f2, err := flattenConfig(s2)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for k := range f1 {
+		keys[k] = true
+	}
+	for k := range f2 {
+		keys[k] = true
+	}
+	var names []string
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, k := range names {
+		v1, ok1 := f1[k]
+		v2, ok2 := f2[k]
+		switch {
+		case !ok1:
+			lines = append(lines, fmt.Sprintf("%s %s: %q", diffMarker(diffTypeAdded), k, v2))
+		case !ok2:
+			lines = append(lines, fmt.Sprintf("%s %s: %q", diffMarker(diffTypeDeleted), k, v1))
+		case v1 != v2:
+			lines = append(lines, fmt.Sprintf("%s %s: %q => %q", diffMarker(diffTypeEdited), k, v1, v2))
+		}
+	}
+	return lines, nil
+}
+
+// flattenConfig converts config to map of dotted field names to values
+func flattenConfig(v interface{}) (map[string]string, error) {
+	buf, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[interface{}]interface{}
+	if err := yaml.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+	f := make(map[string]string)
+	flatten(f, "", m)
+	return f, nil
+}
+
+func flatten(f map[string]string, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		for k, e := range t {
+			flatten(f, strings.TrimPrefix(fmt.Sprintf("%s.%v", prefix, k), "."), e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			flatten(f, fmt.Sprintf("%s[%d]", prefix, i), e)
+		}
+	default:
+		f[prefix] = fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,33 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceDiff(t *testing.T) {
+	s1 := &ServiceConfig{
+		Image:       "api:1",
+		Count:       2,
+		Environment: map[string]string{"LOG_LEVEL": "info", "REGION": "eu"},
+		Volumes:     []string{"/data:/data"},
+	}
+	s2 := &ServiceConfig{
+		Image:       "api:1",
+		Count:       3,
+		Environment: map[string]string{"LOG_LEVEL": "debug"},
+		Volumes:     []string{"/data:/data", "/tmp:/tmp"},
+	}
+	lines, err := serviceDiff(s1, s2)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `count: "2" => "3"`)
+	assert.Contains(t, lines[1], `env.LOG_LEVEL: "info" => "debug"`)
+	assert.Contains(t, lines[2], `env.REGION: "eu"`)
+	assert.Contains(t, lines[3], `vol[1]: "/tmp:/tmp"`)
+
+	lines, err = serviceDiff(s1, s1)
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+}
@@ -0,0 +1,197 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+// ConsulService is service registered in Consul catalog with health of its instances
+type ConsulService struct {
+	Name      string   `json:"name" yaml:"name"`
+	Tags      []string `json:"tags" yaml:"tags"`
+	Instances int      `json:"instances" yaml:"instances"`
+	Passing   int      `json:"passing" yaml:"passing"`
+	Warning   int      `json:"warning" yaml:"warning"`
+	Critical  int      `json:"critical" yaml:"critical"`
+}
+
+func (s ConsulService) String() string {
+	return fmt.Sprintf("%-40s %3d %s %s %s  %s",
+		s.Name,
+		s.Instances,
+		success(fmt.Sprintf("%3d", s.Passing)),
+		fmt.Sprintf("%3d", s.Warning),
+		warn(fmt.Sprintf("%3d", s.Critical)),
+		faint(strings.Join(s.Tags, ",")))
+}
+
+// ConsulInstance is service instance registered in Consul
+type ConsulInstance struct {
+	ID      string        `json:"id" yaml:"id"`
+	Node    string        `json:"node" yaml:"node"`
+	Address string        `json:"address" yaml:"address"`
+	Port    int           `json:"port" yaml:"port"`
+	Tags    []string      `json:"tags" yaml:"tags"`
+	Health  string        `json:"health" yaml:"health"` // passing, warning or critical
+	Checks  []ConsulCheck `json:"checks" yaml:"checks"`
+}
+
+// ConsulCheck is health check of the service instance or its node
+type ConsulCheck struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+	Output string `json:"output" yaml:"output"`
+}
+
+func (i ConsulInstance) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-25s %-20s %-8s %s\n",
+		fmt.Sprintf("%s:%d", i.Address, i.Port),
+		i.Node,
+		consulHealth(i.Health),
+		faint(strings.Join(i.Tags, ",")))
+	for _, c := range i.Checks {
+		if c.Status == capi.HealthPassing {
+			continue
+		}
+		fmt.Fprintf(&b, "    %s %s %s\n", consulHealth(c.Status), c.Name, strings.TrimSpace(c.Output))
+	}
+	return b.String()
+}
+
+func consulHealth(status string) string {
+	switch status {
+	case capi.HealthPassing:
+		return success(status)
+	case capi.HealthCritical:
+		return warn(status)
+	}
+	return status
+}
+
+// ConsulServices lists services registered in Consul in Dc,
+// with names containing pattern.
+func ConsulServices(ctx context.Context, o Options, pattern string) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
+	l := newTerminalLogger()
+	defer l.Close()
+	ss, err := consulServices(ctx, o.Consul, o.Dc, pattern)
+	if err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	err = render(o.Output, ss, func(w io.Writer) {
+		for _, s := range ss {
+			fmt.Fprintf(w, "%s\n", s)
+		}
+	})
+	if err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// ConsulInstances lists instances of the service registered in Consul in Dc
+// with their addresses, tags and health checks.
+func ConsulInstances(ctx context.Context, o Options, name string) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
+	l := newTerminalLogger()
+	defer l.Close()
+	is, err := consulInstances(ctx, o.Consul, o.Dc, name)
+	if err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	if len(is) == 0 {
+		return done(WithExitCode(ExitValidation, fmt.Errorf("service %s not found in consul %s", name, o.Dc)))
+	}
+	err = render(o.Output, is, func(w io.Writer) {
+		for _, i := range is {
+			fmt.Fprintf(w, "%s", i)
+		}
+	})
+	if err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+func consulServices(ctx context.Context, addr, dc, pattern string) ([]*ConsulService, error) {
+	cli, err := capi.NewClient(&capi.Config{Address: addr})
+	if err != nil {
+		return nil, err
+	}
+	q := (&capi.QueryOptions{Datacenter: dc}).WithContext(ctx)
+	names, _, err := cli.Catalog().Services(q)
+	if err != nil {
+		return nil, err
+	}
+	var ss []*ConsulService
+	for name, tags := range names {
+		if !strings.Contains(name, pattern) {
+			continue
+		}
+		ses, _, err := cli.Health().Service(name, "", false, q)
+		if err != nil {
+			return nil, err
+		}
+		s := &ConsulService{Name: name, Tags: tags, Instances: len(ses)}
+		for _, se := range ses {
+			switch se.Checks.AggregatedStatus() {
+			case capi.HealthPassing:
+				s.Passing++
+			case capi.HealthWarning:
+				s.Warning++
+			default:
+				s.Critical++
+			}
+		}
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Name < ss[j].Name })
+	return ss, nil
+}
+
+func consulInstances(ctx context.Context, addr, dc, name string) ([]*ConsulInstance, error) {
+	cli, err := capi.NewClient(&capi.Config{Address: addr})
+	if err != nil {
+		return nil, err
+	}
+	q := (&capi.QueryOptions{Datacenter: dc}).WithContext(ctx)
+	ses, _, err := cli.Health().Service(name, "", false, q)
+	if err != nil {
+		return nil, err
+	}
+	var is []*ConsulInstance
+	for _, se := range ses {
+		is = append(is, consulInstance(se))
+	}
+	sort.Slice(is, func(i, j int) bool { return is[i].Node < is[j].Node })
+	return is, nil
+}
+
+func consulInstance(se *capi.ServiceEntry) *ConsulInstance {
+	i := &ConsulInstance{
+		ID:      se.Service.ID,
+		Node:    se.Node.Node,
+		Address: se.Service.Address,
+		Port:    se.Service.Port,
+		Tags:    se.Service.Tags,
+		Health:  se.Checks.AggregatedStatus(),
+	}
+	if i.Address == "" {
+		i.Address = se.Node.Address
+	}
+	for _, c := range se.Checks {
+		i.Checks = append(i.Checks, ConsulCheck{Name: c.Name, Status: c.Status, Output: c.Output})
+	}
+	return i
+}
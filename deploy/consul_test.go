@@ -0,0 +1,24 @@
+package deploy
+
+import (
+	"testing"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsulInstance(t *testing.T) {
+	se := &capi.ServiceEntry{
+		Node:    &capi.Node{Node: "app1", Address: "10.0.0.1"},
+		Service: &capi.AgentService{ID: "api-1", Port: 8080, Tags: []string{"v1"}},
+		Checks: capi.HealthChecks{
+			{Name: "serf", Status: capi.HealthPassing},
+			{Name: "http", Status: capi.HealthCritical, Output: "connection refused"},
+		},
+	}
+	i := consulInstance(se)
+	assert.Equal(t, "10.0.0.1", i.Address)
+	assert.Equal(t, capi.HealthCritical, i.Health)
+	assert.Len(t, i.Checks, 2)
+	assert.Contains(t, i.String(), "connection refused")
+}
@@ -0,0 +1,119 @@
+package deploy
+
+import (
+	"reflect"
+	"strings"
+)
+
+// applyDefaults merges defaults into each service config.
+// Service values override hostgroup defaults, which override global defaults.
+// Original service configs are kept for saving.
+func (c *DeploymentConfig) applyDefaults() {
+	if c.Defaults == nil && len(c.HostGroupDefaults) == 0 {
+		return
+	}
+	c.own = make(map[*ServiceConfig]*ServiceConfig)
+	for _, dc := range c.Datacenters {
+		if dc == nil {
+			continue
+		}
+		for name, s := range dc.Services {
+			if s == nil {
+				s = &ServiceConfig{}
+			}
+			m := c.merged(s)
+			c.own[m] = s
+			dc.Services[name] = m
+		}
+	}
+}
+
+// merged returns service config with defaults applied
+func (c *DeploymentConfig) merged(s *ServiceConfig) *ServiceConfig {
+	m := *s
+	hg := s.HostGroup
+	if hg == "" && c.Defaults != nil {
+		hg = c.Defaults.HostGroup
+	}
+	if d, ok := c.HostGroupDefaults[hg]; ok && d != nil {
+		mergeDefaults(&m, d)
+	}
+	if c.Defaults != nil {
+		mergeDefaults(&m, c.Defaults)
+	}
+	return &m
+}
+
+// mergeDefaults sets zero fields of s to the default values, maps are merged by keys.
+// Fields set in service config file keep their value, even if it is zero.
+func mergeDefaults(s, d *ServiceConfig) {
+	sv := reflect.ValueOf(s).Elem()
+	dv := reflect.ValueOf(d).Elem()
+	for i := 0; i < sv.NumField(); i++ {
+		f, df := sv.Field(i), dv.Field(i)
+		if !f.CanSet() || isZero(df) {
+			continue
+		}
+		if f.Kind() == reflect.Map && !f.IsNil() {
+			m := reflect.MakeMap(f.Type())
+			for _, k := range df.MapKeys() {
+				m.SetMapIndex(k, df.MapIndex(k))
+			}
+			for _, k := range f.MapKeys() {
+				m.SetMapIndex(k, f.MapIndex(k))
+			}
+			f.Set(m)
+			continue
+		}
+		if isZero(f) && !s.set[yamlKey(sv.Type().Field(i))] {
+			f.Set(df)
+		}
+	}
+}
+
+// yamlKey is the config file key of the struct field
+func yamlKey(f reflect.StructField) string {
+	if k := strings.Split(f.Tag.Get("yaml"), ",")[0]; k != "" {
+		return k
+	}
+	return strings.ToLower(f.Name)
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.Interface() == reflect.Zero(v.Type()).Interface()
+}
+
+// withoutDefaults returns config with original service configs, as written in file.
+// Service image is the only value changed by deployments, it is copied to the original.
+func (c *DeploymentConfig) withoutDefaults() *DeploymentConfig {
+	if c.own == nil {
+		return c
+	}
+	out := *c
+	out.Datacenters = make(map[string]*DcConfig)
+	for name, dc := range c.Datacenters {
+		if dc == nil {
+			out.Datacenters[name] = dc
+			continue
+		}
+		odc := *dc
+		odc.Services = make(map[string]*ServiceConfig)
+		for sn, m := range dc.Services {
+			s, ok := c.own[m]
+			if !ok {
+				odc.Services[sn] = m
+				continue
+			}
+			if m.Image != c.merged(s).Image {
+				s.Image = m.Image
+			}
+			odc.Services[sn] = s
+		}
+		out.Datacenters[name] = &odc
+	}
+	return &out
+}
@@ -0,0 +1,36 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeployedImage shows image and its digest of the service job running in datacenter
+func DeployedImage(ctx context.Context, o Options) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(w.dc)
+	return done(d.deployedImage(ctx))
+}
+
+func (d *Deployer) deployedImage(ctx context.Context) error {
+	if err := d.connect(ctx); err != nil {
+		return err
+	}
+	job, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return err
+	}
+	image := jobImage(job, d.service)
+	digest := job.Meta[ImageDigestMeta]
+	fmt.Printf("image:  %s\n", image)
+	fmt.Printf("digest: %s\n", digest)
+	if digest != "" {
+		registry, repo, _ := splitImage(image)
+		if registry != "" {
+			fmt.Printf("pinned: %s/%s@%s\n", registry, repo, digest)
+		}
+	}
+	return nil
+}
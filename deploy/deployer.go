@@ -1,12 +1,16 @@
 package deploy
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"time"
 
 	"github.com/hashicorp/nomad/api"
-	"github.com/hashicorp/nomad/jobspec"
+	"github.com/manifoldco/promptui"
 	"github.com/minus5/svckit/log"
 )
 
@@ -14,16 +18,33 @@ import (
 // i had a problem with including github.com/hashicorp/nomad/nomad/structs
 const (
 	JobTypeService             = "service"
+	JobTypeBatch               = "batch"
+	JobTypeSystem              = "system"
 	DeploymentStatusRunning    = "running"
 	DeploymentStatusSuccessful = "successful"
+	DeploymentStatusFailed     = "failed"
 
 	// FederatedDcsEnv is name of the environment variable containing datacenter names
 	FederatedDcsEnv = "SVCKIT_FEDERATED_DCS"
 	// DeploymentEnv is name of the environment variable containing deployment name
 	DeploymentEnv = "deployment"
+
+	// DeployedByMeta is job meta key containing user who deployed the job
+	DeployedByMeta = "deployed_by"
+	// ImageDigestMeta is job meta key containing digest of the deployed image
+	ImageDigestMeta = "image_digest"
+
+	// DefaultWaitTime is max duration of Nomad blocking queries
+	DefaultWaitTime = 5 * time.Second
 )
 
-//Deployer has all deployment related objects
+// ErrDeploymentTimeout is returned when deployment doesn't finish in timeout
+var ErrDeploymentTimeout = errors.New("deployment timed out")
+
+// ErrAborted is returned when user doesn't confirm job plan
+var ErrAborted = errors.New("aborted")
+
+// Deployer has all deployment related objects
 type Deployer struct {
 	root            string
 	service         string
@@ -31,7 +52,7 @@ type Deployer struct {
 	address         string
 	config          *DeploymentConfig
 	job             *api.Job
-	cli             *api.Client
+	cli             NomadClient
 	jobModifyIndex  uint64
 	jobEvalID       string
 	jobDeploymentID string
@@ -39,6 +60,28 @@ type Deployer struct {
 	dc              string
 	cdc             string // datacenter set in config file for service
 	deployment      string
+	manualPromote   bool          // don't promote canaries, stop when they are healthy
+	waitTime        time.Duration // max duration of blocking queries
+	autoRevert      bool          // revert to previous job version on failed deployment
+	timeout         time.Duration // max duration of the whole deployment
+	events          chan DeployEvent
+	eventsDone      <-chan struct{} // Run context done, stops sending events
+	nomad           NomadConfig     // Nomad connection parameters from flags
+	namespace       string          // Nomad namespace from flags
+	confirm         bool            // ask for confirmation after showing job plan
+	consul          string          // Consul address for deploy locks and smoke tests
+	noLock          bool            // don't acquire deploy lock
+	vars            []string        // job spec variables, name=value
+	varFiles        []string        // job spec variable files
+	digest          string          // image digest recorded in job meta
+	src             string          // service source repository, for changelog
+	unlock          func()          // releases deploy lock
+	trafficServices []string        // Consul services with canary tags for traffic shift
+	registered      time.Time       // when job was registered
+	timeToHealthy   time.Duration   // from job registration to successful deployment
+	trace           *trace          // deployment trace, if tracing is configured
+	loader          JobLoader       // loads job instead of .nomad file in root
+	logOut          io.Writer       // svckit log output, default output if nil
 }
 
 // NewDeployer is used to create new deployer
@@ -51,39 +94,84 @@ func NewDeployer(root, service, image string, config *DeploymentConfig, address,
 		address:    address,
 		cdc:        cdc,
 		deployment: deployment,
+		waitTime:   DefaultWaitTime,
 	}
 }
 
+// NewDeployerWithClient creates deployer which uses cli instead of connecting to Nomad
+// and loader instead of loading job from .nomad file in root.
+// Nil loader loads job from root.
+func NewDeployerWithClient(cli NomadClient, loader JobLoader, root, service, image string, config *DeploymentConfig, cdc, deployment string) *Deployer {
+	d := NewDeployer(root, service, image, config, "", cdc, deployment)
+	d.cli = cli
+	d.loader = loader
+	return d
+}
+
 // Go function executes all needed steps for a new deployment
-// loadServiceConfig - loads Nomad job configuration from file *.nomad
 // connect - connects to a Nomad server (from Consul)
+// loadServiceConfig - loads Nomad job configuration from file *.nomad
 // validate - job check is it syntactically correct
 // plan - dry-run a job update to determine its effects
 // register - register a job to scheduler
 // status - status of the submited job
-func (d *Deployer) Go(dryRun bool) error {
-	steps := []func() error{
-		d.loadServiceConfig,
-		d.connect,
-		d.validate,
+// If timeout is set and deployment doesn't finish in time Nomad deployment is failed.
+func (d *Deployer) Go(ctx context.Context, dryRun bool) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	defer func() {
+		if d.unlock != nil {
+			d.unlock()
+			d.unlock = nil
+		}
+	}()
+	d.startTrace()
+	steps := []func(context.Context) error{
+		exitStep(ExitConnection, d.traced("connect", d.connect)),
+		exitStep(ExitValidation, d.traced("load_service_config", d.loadServiceConfig)),
+		exitStep(ExitValidation, d.traced("validate", d.validate)),
 	}
 	if dryRun {
-		steps = append(steps, d.show)
+		steps = append(steps, exitStep(ExitValidation, d.show))
 	} else {
 		steps = append(steps,
-			[]func() error{
-				d.plan,
-				d.register,
-				d.status,
+			[]func(context.Context) error{
+				exitStep(ExitValidation, d.traced("verify_signature", d.verifySignature)),
+				exitStep(ExitConflict, d.traced("lock", d.lockService)),
+				exitStep(ExitValidation, d.traced("plan", d.plan)),
+				exitStep(ExitFailed, d.traced("register", d.register)),
+				exitStep(ExitFailed, d.traced("status", d.statusOrRevert)),
 			}...)
 	}
-	return runSteps(steps)
+	err := runContextSteps(ctx, steps)
+	if err == context.DeadlineExceeded && d.jobDeploymentID != "" {
+		// timed out between register and status steps
+		err = d.failTimedOut(d.jobDeploymentID)
+	}
+	err = WithExitCode(ExitFailed, err)
+	d.endTrace(err)
+	return err
+}
+
+// exitStep sets exit code of the step error
+func exitStep(code int, step func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return WithExitCode(code, step(ctx))
+	}
+}
+
+// withTimeout bounds ctx with deployer timeout, if set
+func (d *Deployer) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.timeout > 0 {
+		return context.WithTimeout(ctx, d.timeout)
+	}
+	return context.WithCancel(ctx)
 }
 
-func (d *Deployer) show() error {
-	log.Info("show")
+func (d *Deployer) show(_ context.Context) error {
+	d.log().Info("show")
 	buf, _ := json.MarshalIndent(d.job, "  ", "  ")
-	fmt.Printf("%s\n", buf)
+	fmt.Fprintf(termOut, "%s\n", buf)
 	return nil
 }
 
@@ -96,13 +184,42 @@ func (d *Deployer) checkServiceConfig() error {
 }
 
 // plan envoke the scheduler in a dry-run mode with new jobs or when updating existing jobs to determine what would happen if the job is submitted
-func (d *Deployer) plan() error {
-	jp, _, err := d.cli.Jobs().Plan(d.job, false, nil)
+func (d *Deployer) plan(_ context.Context) error {
+	jp, _, err := d.cli.Jobs().Plan(d.job, true, nil)
 	if err != nil {
 		return err
 	}
 	d.jobModifyIndex = jp.JobModifyIndex
-	log.I("modifyIndex", int(jp.JobModifyIndex)).Info("job planned")
+	if d.events == nil {
+		if err := d.showPlan(jp); err != nil {
+			return err
+		}
+	}
+	d.log().I("modifyIndex", int(jp.JobModifyIndex)).Info("job planned")
+	d.emit(DeployEvent{Type: EventPlanned, Plan: jp})
+	return nil
+}
+
+// showPlan prints job plan and asks for confirmation to apply it.
+// Terminal is locked so plans and prompts of the concurrent deployers don't interleave.
+func (d *Deployer) showPlan(jp *api.JobPlanResponse) error {
+	changes := d.changelog()
+	termMu.Lock()
+	defer termMu.Unlock()
+	fmt.Fprintf(termOut, "%s\n", formatPlan(jp))
+	if changes != "" {
+		fmt.Fprintf(termOut, "Changes:\n%s\n\n", changes)
+	}
+	if !d.confirm {
+		return nil
+	}
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Apply these changes in %s", d.dc),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return ErrAborted
+	}
 	return nil
 }
 
@@ -110,9 +227,12 @@ func (d *Deployer) plan() error {
 // If EnforceRegister is set then the job will only be registered if the passed
 // JobModifyIndex matches the current Jobs index. If the index is zero, the
 // register only occurs if the job is new
-func (d *Deployer) register() error {
+func (d *Deployer) register(ctx context.Context) error {
 	jr, _, err := d.cli.Jobs().EnforceRegister(d.job, d.jobModifyIndex, nil)
 	if err != nil {
+		if isConflict(err) {
+			return WithExitCode(ExitConflict, err)
+		}
 		return err
 	}
 	// EvalID is the eval ID of the plan being applied. The modify index of the
@@ -122,17 +242,29 @@ func (d *Deployer) register() error {
 	// processed many times, potentially making state updates, without the state of
 	// the evaluation itself being updated.
 	d.jobEvalID = jr.EvalID
-	if err := d.getDeploymentID(); err != nil {
-		return err
+	d.registered = time.Now()
+	// periodic and parameterized jobs are registered without evaluation
+	if jr.EvalID != "" {
+		if err := d.getDeploymentID(ctx); err != nil {
+			return err
+		}
 	}
-	log.S("evalID", jr.EvalID).S("deploymentID", d.jobDeploymentID).Info("job registered")
+	d.log().S("evalID", jr.EvalID).S("deploymentID", d.jobDeploymentID).Info("job registered")
+	d.emit(DeployEvent{Type: EventRegistered, EvalID: jr.EvalID, DeploymentID: d.jobDeploymentID})
 	return nil
 }
 
 // DeploymentID is the ID of the deployment to update
-func (d *Deployer) getDeploymentID() error {
+func (d *Deployer) getDeploymentID(ctx context.Context) error {
+	q := d.blockingQuery()
 	for {
-		ev, _, err := d.cli.Evaluations().Info(d.jobEvalID, nil)
+		if err := ctx.Err(); err != nil {
+			if err == context.DeadlineExceeded {
+				return d.evalTimedOut()
+			}
+			return err
+		}
+		ev, meta, err := d.cli.Evaluations().Info(d.jobEvalID, q)
 		if err != nil {
 			return err
 		}
@@ -140,15 +272,44 @@ func (d *Deployer) getDeploymentID() error {
 			d.jobDeploymentID = ev.DeploymentID
 			return nil
 		}
-		if ev.Status == "complete" && ev.Type != JobTypeService {
+		if ev.Status == evalStatusComplete && ev.Type != JobTypeService {
 			return nil
 		}
-		time.Sleep(time.Second)
+		q.WaitIndex = meta.LastIndex
 	}
 }
 
+// evalTimedOut fails deployment of the job evaluation if it is created by now,
+// when deploy times out before deployment ID is known
+func (d *Deployer) evalTimedOut() error {
+	ev, _, err := d.cli.Evaluations().Info(d.jobEvalID, nil)
+	if err == nil && ev.DeploymentID != "" {
+		d.jobDeploymentID = ev.DeploymentID
+		return d.failTimedOut(ev.DeploymentID)
+	}
+	d.log().S("evalID", d.jobEvalID).Info("deploy timed out before deployment was created, job is registered in Nomad")
+	return ErrDeploymentTimeout
+}
+
+// log starts svckit log line written to the deployer log output
+func (d *Deployer) log() *log.Agregator {
+	return log.NewAgregator(d.logOut, 3)
+}
+
+// blockingQuery returns options for Nomad blocking query
+// WaitIndex should be set to the LastIndex of the previous response.
+func (d *Deployer) blockingQuery() *api.QueryOptions {
+	return &api.QueryOptions{WaitIndex: 1, AllowStale: true, WaitTime: d.waitTime}
+}
+
 // status of the submited job
-func (d *Deployer) status() error {
+func (d *Deployer) status(ctx context.Context) error {
+	if d.isBatch() {
+		return d.batchStatus(ctx)
+	}
+	if d.isSystem() {
+		return d.systemStatus(ctx)
+	}
 	depID := d.jobDeploymentID
 	if depID == "" {
 		return nil
@@ -157,13 +318,14 @@ func (d *Deployer) status() error {
 	var canaryChan chan interface{}
 	deploymentChan := make(chan interface{})
 
-	if d.job.Update != nil && d.job.Update.Canary != nil && *d.job.Update.Canary != 0 {
+	if d.hasCanaries() && !d.manualPromote {
 		canaryChan = make(chan interface{})
 		go d.canaryPromote(depID, canaryChan, deploymentChan)
 	}
 
 	t := time.Now()
-	q := &api.QueryOptions{WaitIndex: 1, AllowStale: true, WaitTime: time.Duration(5 * time.Second)}
+	q := d.blockingQuery()
+	healthy := make(map[string]int) // healthy allocs by task group
 
 	// signal canaryPromote goroutine to exit if it's still runing on return
 	defer func() {
@@ -173,6 +335,13 @@ func (d *Deployer) status() error {
 	}()
 
 	for {
+		if err := ctx.Err(); err != nil {
+			if err == context.DeadlineExceeded {
+				return d.failTimedOut(depID)
+			}
+			d.log().S("deploymentID", depID).Info("stopped watching deployment, it is still running in Nomad")
+			return err
+		}
 		dep, meta, err := d.cli.Deployments().Info(depID, q)
 
 		if err != nil {
@@ -183,7 +352,7 @@ func (d *Deployer) status() error {
 		case <-deploymentChan:
 			// if promotion didn't succeed, and deployment is still running, fail it
 			if dep.Status == DeploymentStatusRunning {
-				log.Info("failing deployment")
+				d.log().Info("failing deployment")
 				_, _, err := d.cli.Deployments().Fail(depID, nil)
 				if err != nil {
 					return fmt.Errorf("error while manually failing deployment: %v", err)
@@ -198,19 +367,30 @@ func (d *Deployer) status() error {
 
 		q.WaitIndex = meta.LastIndex
 		du := fmt.Sprintf("%.2fs", time.Since(t).Seconds())
+		for g, v := range dep.TaskGroups {
+			if v.HealthyAllocs > healthy[g] {
+				healthy[g] = v.HealthyAllocs
+				d.emit(DeployEvent{Type: EventAllocHealthy, DeploymentID: depID,
+					Group: g, Healthy: v.HealthyAllocs, Desired: v.DesiredTotal})
+			}
+		}
 		if dep.Status == DeploymentStatusRunning {
 			for _, v := range dep.TaskGroups {
-				log.S("running", du).
+				d.log().S("running", du).
 					//S("group", k).
 					I("desired", v.DesiredTotal).
 					I("placed", v.PlacedAllocs).
 					I("healthy", v.HealthyAllocs).
 					Debug("checking status")
 			}
+			if d.manualPromote && canariesHealthy(dep) && !canariesPromoted(dep) {
+				d.log().S("deploymentID", depID).Info("canaries healthy, waiting for promote")
+				return nil
+			}
 			continue
 		}
 		if dep.Status == DeploymentStatusSuccessful {
-			log.S("after", du).Info("deployment successful")
+			d.log().S("after", du).Info("deployment successful")
 			break
 		}
 
@@ -223,6 +403,17 @@ func (d *Deployer) status() error {
 	return nil
 }
 
+// failTimedOut fails Nomad deployment which didn't finish in time
+func (d *Deployer) failTimedOut(depID string) error {
+	d.log().S("deploymentID", depID).S("timeout", d.timeout.String()).Info("failing timed out deployment")
+	if _, _, err := d.cli.Deployments().Fail(depID, nil); err != nil {
+		d.log().S("deploymentID", depID).Info("deployment is still running in Nomad")
+		return fmt.Errorf("%v, error while failing deployment: %v", ErrDeploymentTimeout, err)
+	}
+	d.checkFailedDeployment(depID)
+	return ErrDeploymentTimeout
+}
+
 // find and show deployment error
 func (d *Deployer) checkFailedDeployment(depID string) {
 	al, _, err := d.cli.Deployments().Allocations(depID, nil)
@@ -235,7 +426,7 @@ func (d *Deployer) checkFailedDeployment(depID string) {
 						e.ValidationError != "" ||
 						e.SetupError != "" ||
 						e.VaultError != "" {
-						fmt.Printf("%s%s%s%s%s",
+						fmt.Fprintf(termOut, "%s%s%s%s%s",
 							warn(e.DriverError),
 							warn(e.DownloadError),
 							warn(e.ValidationError),
@@ -250,7 +441,7 @@ func (d *Deployer) checkFailedDeployment(depID string) {
 
 // promote canary allocations when all are healthy
 func (d *Deployer) canaryPromote(depID string, shutdownChan, deploymentChan chan interface{}) {
-	log.S("deploymentID", depID).Info("promoting deployment")
+	d.log().S("deploymentID", depID).Info("promoting deployment")
 
 	autoPromote := time.Tick(5 * time.Second)
 
@@ -261,10 +452,24 @@ func (d *Deployer) canaryPromote(depID string, shutdownChan, deploymentChan chan
 			if healthy := d.checkCanaryHealth(depID); !healthy {
 				continue
 			}
+			if len(d.trafficServices) > 0 {
+				err := d.shiftTraffic(depID, shutdownChan)
+				if err != nil {
+					d.resetTraffic()
+					if err != errTrafficStopped {
+						d.log().Error(fmt.Errorf("error while shifting traffic: %v", err))
+						close(deploymentChan)
+					}
+					return
+				}
+			}
 
 			_, _, err := d.cli.Deployments().PromoteAll(depID, nil)
+			if len(d.trafficServices) > 0 {
+				d.resetTraffic()
+			}
 			if err != nil {
-				log.Errorf("error while promoting: %v", err)
+				d.log().Error(fmt.Errorf("error while promoting: %v", err))
 				close(deploymentChan)
 			}
 			return
@@ -278,13 +483,16 @@ func (d *Deployer) canaryPromote(depID string, shutdownChan, deploymentChan chan
 
 // check if all canary allocations are healthy
 func (d *Deployer) checkCanaryHealth(depID string) bool {
-	var unhealthy int
-
 	dep, _, err := d.cli.Deployments().Info(depID, &api.QueryOptions{AllowStale: true})
 	if err != nil {
-		log.Errorf("unable to query deployment %s for health: %v", depID, err)
+		d.log().Error(fmt.Errorf("unable to query deployment %s for health: %v", depID, err))
 		return false
 	}
+	return canariesHealthy(dep)
+}
+
+func canariesHealthy(dep *api.Deployment) bool {
+	var unhealthy int
 
 	for _, taskInfo := range dep.TaskGroups {
 		if taskInfo.DesiredCanaries == 0 {
@@ -300,34 +508,84 @@ func (d *Deployer) checkCanaryHealth(depID string) bool {
 
 }
 
-// loadServiceConfig from dc config.yml
-func (d *Deployer) loadServiceConfig() error {
-	fn := fmt.Sprintf("%s/nomad/service/%s.nomad", d.root, d.service)
-	job, err := jobspec.ParseFile(fn)
-	if err != nil {
-		fn = fmt.Sprintf("%s/nomad/system/%s.nomad", d.root, d.service)
-		job, err = jobspec.ParseFile(fn)
+func canariesPromoted(dep *api.Deployment) bool {
+	for _, taskInfo := range dep.TaskGroups {
+		if taskInfo.DesiredCanaries > 0 && !taskInfo.Promoted {
+			return false
+		}
+	}
+	return true
+}
+
+// hasCanaries checks does job update strategy places canaries
+func (d *Deployer) hasCanaries() bool {
+	if u := d.job.Update; u != nil && u.Canary != nil && *u.Canary != 0 {
+		return true
 	}
+	for _, tg := range d.job.TaskGroups {
+		if u := tg.Update; u != nil && u.Canary != nil && *u.Canary != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadServiceConfig loads service job with job loader, or from .nomad file
+func (d *Deployer) loadServiceConfig(_ context.Context) error {
+	load := d.loadJobFile
+	if d.loader != nil {
+		load = func() (*api.Job, error) { return d.loader.LoadJob(d.service, d.cdc) }
+	}
+	job, err := load()
 	if err != nil {
 		return err
 	}
-
-	log.S("from", fn).Debug("loaded config")
 	d.job = job
 	return d.checkServiceConfig()
 }
 
-// connect to Nomad server (from Consul)
-func (d *Deployer) connect() error {
-	c := &api.Config{}
-	addr := d.address
-	c = c.ClientConfig("", addr, false)
-	cli, err := api.NewClient(c)
+// loadJobFile loads job from .nomad file in root
+// .nomad file is rendered as template before parsing
+func (d *Deployer) loadJobFile() (*api.Job, error) {
+	var buf []byte
+	var err error
+	var fn string
+	for _, dir := range []string{"service", "system", "batch"} {
+		fn = fmt.Sprintf("%s/nomad/%s/%s.nomad", d.root, dir, d.service)
+		if buf, err = ioutil.ReadFile(fn); err == nil {
+			break
+		}
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if buf, err = d.renderJob(fn, buf); err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	job, err := parseJob(fn, buf, d.vars, d.varFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	d.log().S("from", fn).Debug("loaded config")
+	return job, nil
+}
+
+// connect to Nomad server (from Consul)
+func (d *Deployer) connect(_ context.Context) error {
+	if d.cli == nil {
+		c := d.nomadConfig().clientConfig(d.address)
+		cli, err := api.NewClient(c)
+		if err != nil {
+			return err
+		}
+		if ns := d.jobNamespace(); ns != "" {
+			cli.SetNamespace(ns)
+			d.log().S("namespace", ns).Debug("setting")
+		}
+		d.log().S("nomad", c.Address).Info("connected")
+		d.cli = NewNomadClient(cli)
 	}
-	log.S("nomad", addr).Info("connected")
-	d.cli = cli
 	// server default dc and region
 	dc, err := d.cli.Agent().Datacenter()
 	if err != nil {
@@ -342,13 +600,65 @@ func (d *Deployer) connect() error {
 	return nil
 }
 
+// nomadConfig combines datacenter Nomad config with the one from flags
+func (d *Deployer) nomadConfig() NomadConfig {
+	var n NomadConfig
+	if d.config != nil {
+		if dc, ok := d.config.Datacenters[d.cdc]; ok && dc != nil && dc.Nomad != nil {
+			n = *dc.Nomad
+		}
+	}
+	n.merge(d.nomad)
+	return n
+}
+
+// jobNamespace returns Nomad namespace set by flag, in service or datacenter config
+func (d *Deployer) jobNamespace() string {
+	if d.namespace != "" {
+		return d.namespace
+	}
+	if d.config == nil {
+		return ""
+	}
+	if s := d.config.FindForDc(d.service, d.cdc); s != nil && s.Namespace != "" {
+		return s.Namespace
+	}
+	if dc, ok := d.config.Datacenters[d.cdc]; ok && dc != nil {
+		return dc.Namespace
+	}
+	return ""
+}
+
+// jobArch is cpu architecture from service or datacenter config
+func (d *Deployer) jobArch() string {
+	if d.config == nil {
+		return ""
+	}
+	if s := d.config.FindForDc(d.service, d.cdc); s != nil && s.Arch != "" {
+		return s.Arch
+	}
+	if dc, ok := d.config.Datacenters[d.cdc]; ok && dc != nil {
+		return dc.Arch
+	}
+	return ""
+}
+
 // validate the job to check is it syntactically correct
 // combines Nomad job file and config.yml for specific datacenter
-func (d *Deployer) validate() error {
+func (d *Deployer) validate(_ context.Context) error {
 
 	d.job.Region = &d.region
 	d.job.Datacenters = []string{}
 	d.job.AddDatacenter(d.dc)
+	if ns := d.jobNamespace(); ns != "" {
+		d.job.Namespace = &ns
+	}
+	if u := currentUser(); u != "" {
+		d.job.SetMeta(DeployedByMeta, u)
+	}
+	if d.digest != "" {
+		d.job.SetMeta(ImageDigestMeta, d.digest)
+	}
 
 	s := d.config.FindForDc(d.service, d.cdc)
 	if s.HostGroup != "" {
@@ -358,9 +668,23 @@ func (d *Deployer) validate() error {
 		d.job.Constrain(api.NewConstraint("${meta.node}", "=", s.Node))
 	}
 
+	taskImage := d.image
+	if arch := d.jobArch(); arch != "" {
+		d.job.Constrain(api.NewConstraint("${attr.cpu.arch}", "=", arch))
+		// pin platform image from multi-arch manifest list
+		pi, err := platformImage(d.image, arch)
+		if err != nil {
+			return err
+		}
+		if pi != "" {
+			taskImage = pi
+			d.log().S("arch", arch).S("image", pi).Debug("setting platform image")
+		}
+	}
+
 	if len(s.Constraints) > 0 {
 		for _, v := range s.Constraints {
-			log.Debug("setting constraint - att: %s, op: %s, v: %s", v.Attribute, v.Operator, v.Value)
+			d.log().Debug(fmt.Sprintf("setting constraint - att: %s, op: %s, v: %s", v.Attribute, v.Operator, v.Value))
 			d.job.Constrain(api.NewConstraint(v.Attribute, v.Operator, v.Value))
 		}
 	}
@@ -369,9 +693,19 @@ func (d *Deployer) validate() error {
 		if !(*tg.Name == d.service || *tg.Name == "services") {
 			continue
 		}
-		if s.Count > 0 {
+		if s.Count > 0 && !d.isSystem() {
 			tg.Count = &s.Count
-			log.I("count", s.Count).Debug("setting")
+			d.log().I("count", s.Count).Debug("setting")
+		}
+		if s.Canary > 0 && !d.isSystem() {
+			if tg.Update == nil {
+				tg.Update = &api.UpdateStrategy{}
+			}
+			tg.Update.Canary = &s.Canary
+			d.log().I("canary", s.Canary).Debug("setting")
+		}
+		if err := d.setCanaryTags(tg); err != nil {
+			return err
 		}
 
 		for _, ta := range tg.Tasks {
@@ -380,23 +714,16 @@ func (d *Deployer) validate() error {
 			}
 
 			// set image
-			ta.Config["image"] = d.image
+			ta.Config["image"] = taskImage
 			s.Image = d.image
-			log.S("image", s.Image).Debug("setting")
+			d.log().S("image", s.Image).Debug("setting")
 
 			// set resources
-			if s.CPU != 0 {
-				ta.Resources.CPU = &s.CPU
-				log.I("cpu", s.CPU).Debug("setting")
-			}
-			if s.Memory != 0 {
-				ta.Resources.MemoryMB = &s.Memory
-				log.I("memory", s.Memory).Debug("setting")
-			}
+			setResources(ta, s)
 
 			// replace arguments
 			if len(s.Arguments) > 0 {
-				log.I("args_len", len(s.Arguments)).Debug("setting")
+				d.log().I("args_len", len(s.Arguments)).Debug("setting")
 				ta.Config["args"] = s.Arguments
 			}
 
@@ -408,17 +735,39 @@ func (d *Deployer) validate() error {
 				ta.Env[DeploymentEnv] = d.deployment
 			}
 			for k, v := range s.Environment {
-				if v != "" {
+				if v != "" && !isVaultRef(v) {
 					ta.Env[k] = v
-					log.S(k, v).Debug("setting env")
+					d.log().S(k, v).Debug("setting env")
 				}
 			}
 
+			// set private registry credentials, service overrides datacenter
+			if s.RegistryAuth != nil {
+				setRegistryAuth(ta, s.RegistryAuth)
+			} else if dcc, ok := d.config.Datacenters[d.cdc]; ok {
+				setRegistryAuth(ta, dcc.RegistryAuth)
+			}
+
+			// set templates
+			if err := checkVaultRefs(ta, s); err != nil {
+				return err
+			}
+			setTemplates(ta, s)
+
 			// replace volumes
 			if len(s.Volumes) > 0 {
-				log.I("vol_len", len(s.Volumes)).Debug("setting")
+				d.log().I("vol_len", len(s.Volumes)).Debug("setting")
 				ta.Config["volumes"] = s.Volumes
 			}
+
+			// set vault policies
+			if len(s.Vault) > 0 {
+				if ta.Vault == nil {
+					ta.Vault = &api.Vault{}
+				}
+				ta.Vault.Policies = s.Vault
+				d.log().I("vault_policies_len", len(s.Vault)).Debug("setting")
+			}
 		}
 	}
 
@@ -426,6 +775,7 @@ func (d *Deployer) validate() error {
 	if err != nil {
 		return err
 	}
-	log.Info("job validated")
+	d.log().Info("job validated")
+	d.emit(DeployEvent{Type: EventValidated})
 	return nil
 }
@@ -3,47 +3,91 @@ package deploy
 import (
 	"fmt"
 	"io/ioutil"
+	"time"
 
 	"github.com/manifoldco/promptui"
+	"github.com/minus5/pitwall/fuzzy"
 	"github.com/minus5/svckit/log"
 	yaml "gopkg.in/yaml.v2"
 )
 
 // DeploymentConfig containes parameters for specific deployment
 type DeploymentConfig struct {
-	root         string
-	deployment   string
-	FederatedDcs string `yaml:"federated_dcs"`
-	Datacenters  map[string]*DcConfig
+	root              string
+	deployment        string
+	FederatedDcs      string                    `yaml:"federated_dcs"`
+	Timeout           string                    `yaml:"timeout,omitempty"` // default deploy timeout, like 10m
+	Notify            *NotifyConfig             `yaml:"notify,omitempty"`
+	Grafana           *GrafanaConfig            `yaml:"grafana,omitempty"`
+	Sentry            *SentryConfig             `yaml:"sentry,omitempty"`
+	Metrics           *MetricsConfig            `yaml:"metrics,omitempty"`
+	Tracing           *TracingConfig            `yaml:"tracing,omitempty"`
+	Audit             *AuditConfig              `yaml:"audit,omitempty"`
+	Cosign            *CosignConfig             `yaml:"cosign,omitempty"`
+	Secrets           *SecretsConfig            `yaml:"secrets,omitempty"`
+	Defaults          *ServiceConfig            `yaml:"defaults,omitempty"`
+	HostGroupDefaults map[string]*ServiceConfig `yaml:"hostgroup_defaults,omitempty"`
+	Vars              map[string]string         `yaml:"vars,omitempty"` // .nomad template variables
+	Datacenters       map[string]*DcConfig
+
+	timeout time.Duration
+	raw     []byte                            // config file content, if it has environment variables interpolations
+	own     map[*ServiceConfig]*ServiceConfig // service configs without defaults
+	env     string                            // environment overlay name
+	images  map[string]string                 // loaded images by dc/service
 }
 
 // DcConfig contains parameters for specific datacenter
 type DcConfig struct {
-	Services map[string]*ServiceConfig `yaml:"services,omitempty"`
+	Namespace    string                    `yaml:"namespace,omitempty"`
+	Nomad        *NomadConfig              `yaml:"nomad,omitempty"`
+	RegistryAuth *RegistryAuth             `yaml:"registry_auth,omitempty"`
+	Arch         string                    `yaml:"arch,omitempty"` // cpu architecture, like amd64 or arm64
+	Vars         map[string]string         `yaml:"vars,omitempty"` // .nomad template variables, override deployment vars
+	Services     map[string]*ServiceConfig `yaml:"services,omitempty"`
 }
 
 // NewDeploymentConfig creates new config for specific deployment
 func NewDeploymentConfig(root, deployment string) (*DeploymentConfig, error) {
+	return NewDeploymentEnvConfig(root, deployment, "")
+}
+
+// NewDeploymentEnvConfig creates new config for specific deployment
+// with environment overlay applied, if env is not empty
+func NewDeploymentEnvConfig(root, deployment, env string) (*DeploymentConfig, error) {
 	c := &DeploymentConfig{
 		root:       root,
 		deployment: deployment,
+		env:        env,
 	}
 	return c, c.load()
 }
 
-func (c *DeploymentConfig) serviceNames() []string {
-	var names []string
-	for _, s := range c.Datacenters {
+// serviceNames returns sorted unique service names in dc, or in all datacenters if dc is empty
+func (c *DeploymentConfig) serviceNames(dc string) []string {
+	m := make(map[string]struct{})
+	for d, s := range c.Datacenters {
+		if dc != "" && d != dc {
+			continue
+		}
 		for k := range s.Services {
-			names = append(names, k)
+			m[k] = struct{}{}
 		}
 	}
-	return names
+	return sortedKeys(m)
 }
 
 // Select service
 func (c *DeploymentConfig) Select() (string, error) {
-	names := c.serviceNames()
+	return c.SelectInDc("")
+}
+
+// SelectInDc selects service from the services in datacenter
+func (c *DeploymentConfig) SelectInDc(dc string) (string, error) {
+	names := c.serviceNames(dc)
+	if len(names) == 0 {
+		return "", fmt.Errorf("no services found in datacenter %s", dc)
+	}
 	prompt := promptui.Select{
 		Label: "Select service",
 		Items: names,
@@ -51,6 +95,8 @@ func (c *DeploymentConfig) Select() (string, error) {
 		Templates: &promptui.SelectTemplates{
 			Selected: string([]byte("\033[" + "1A")),
 		},
+		Searcher:          fuzzy.Searcher(names),
+		StartInSearchMode: true,
 	}
 	idx, _, err := prompt.Run()
 	return names[idx], err
@@ -94,6 +140,11 @@ func (c *DeploymentConfig) FindDatacenters(service string) []string {
 	return dcs
 }
 
+// DeployTimeout returns default deploy timeout, zero if not set
+func (c *DeploymentConfig) DeployTimeout() time.Duration {
+	return c.timeout
+}
+
 // FileName returns config.yml for dc
 func (c *DeploymentConfig) FileName() string {
 	return fmt.Sprintf("%s/deployments/%s/config.yml", c.root, c.deployment)
@@ -101,31 +152,86 @@ func (c *DeploymentConfig) FileName() string {
 
 func (c *DeploymentConfig) load() error {
 	fn := c.FileName()
-	data, err := ioutil.ReadFile(fn)
+	data, err := readConfigFile(fn)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
+	if c.env != "" {
+		if data, err = c.applyOverlay(data); err != nil {
+			return err
+		}
+	}
+	if hasInterpolation(string(data)) {
+		c.raw = data
+		data = []byte(interpolate(string(data)))
+	}
 	if err := yaml.Unmarshal([]byte(data), c); err != nil {
 		log.Error(err)
 		return err
 	}
-	log.S("from", fn).Debug("deployment config")
+	if c.Timeout != "" {
+		t, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %s in %s: %v", c.Timeout, fn, err)
+		}
+		c.timeout = t
+	}
+	c.applyDefaults()
+	c.snapshotImages()
+	log.S("from", fn).S("env", c.env).Debug("deployment config")
 	return nil
 }
 
 // ServiceConfig represent structure for config.yml
 type ServiceConfig struct {
-	Image       string
-	Count       int                    `yaml:"count,omitempty"`
-	HostGroup   string                 `yaml:"hostgroup,omitempty"`
-	Node        string                 `yaml:"node,omitempty"`
-	CPU         int                    `yaml:"cpu,omitempty"`
-	Memory      int                    `yaml:"mem,omitempty"`
-	Environment map[string]string      `yaml:"env,omitempty"`
-	Arguments   []string               `yaml:"arg,omitempty"`
-	Volumes     []string               `yaml:"vol,omitempty"`
-	Constraints map[string]*Constraint `yaml:"constraints,omitempty"`
+	Image            string
+	Namespace        string                 `yaml:"namespace,omitempty"`
+	Count            int                    `yaml:"count,omitempty"`
+	Canary           int                    `yaml:"canary,omitempty"`
+	HostGroup        string                 `yaml:"hostgroup,omitempty"`
+	Node             string                 `yaml:"node,omitempty"`
+	CPU              int                    `yaml:"cpu,omitempty"`
+	Memory           int                    `yaml:"mem,omitempty"`
+	NetworkMode      string                 `yaml:"network_mode,omitempty"`
+	Ports            map[string]int         `yaml:"ports,omitempty"`
+	Environment      map[string]string      `yaml:"env,omitempty"`
+	EnvKV            map[string]string      `yaml:"env_kv,omitempty"`
+	Templates        []*Template            `yaml:"templates,omitempty"`
+	Arguments        []string               `yaml:"arg,omitempty"`
+	Volumes          []string               `yaml:"vol,omitempty"`
+	Constraints      map[string]*Constraint `yaml:"constraints,omitempty"`
+	AutoRevert       bool                   `yaml:"auto_revert,omitempty"`
+	Vault            []string               `yaml:"vault_policies,omitempty"`
+	Hooks            *HooksConfig           `yaml:"hooks,omitempty"`
+	SmokeTest        *SmokeTest             `yaml:"smoke_test,omitempty"`
+	Traffic          *TrafficConfig         `yaml:"traffic,omitempty"`
+	DNS              *DNSConfig             `yaml:"dns,omitempty"`
+	DependsOn        []string               `yaml:"depends_on,omitempty"`
+	Build            *BuildConfig           `yaml:"build,omitempty"`
+	RequireSignature bool                   `yaml:"require_signature,omitempty"`
+	RegistryAuth     *RegistryAuth          `yaml:"registry_auth,omitempty"`
+	Arch             string                 `yaml:"arch,omitempty"`
+
+	set map[string]bool // keys set in config file, they are not overridden by defaults
+}
+
+// UnmarshalYAML records keys set in config file,
+// so explicit zero values (false, 0, "") are not replaced by defaults
+func (s *ServiceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ServiceConfig
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	var keys map[string]interface{}
+	if err := unmarshal(&keys); err != nil {
+		return err
+	}
+	s.set = make(map[string]bool)
+	for k := range keys {
+		s.set[k] = true
+	}
+	return nil
 }
 
 type Constraint struct {
@@ -136,8 +242,19 @@ type Constraint struct {
 
 // Save changes to config.yml
 func (c *DeploymentConfig) Save() error {
-	fn := c.FileName()
-	buf, err := yaml.Marshal(c)
+	fn := c.SavedFileName()
+	if data, err := ioutil.ReadFile(fn); err == nil && isSopsEncrypted(data) {
+		return c.saveEncrypted(fn)
+	}
+	var buf []byte
+	var err error
+	if c.env != "" {
+		buf, err = c.marshalOverlay()
+	} else if c.raw != nil {
+		buf, err = c.marshalWithInterpolations()
+	} else {
+		buf, err = yaml.Marshal(c.withoutDefaults())
+	}
 	if err != nil {
 		log.S("fn", fn).Error(err)
 		return err
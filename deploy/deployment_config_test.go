@@ -1,7 +1,10 @@
 package deploy
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,6 +22,7 @@ func TestLoadConfig(t *testing.T) {
 	assert.NotNil(t, cfg)
 	assert.Equal(t, "datacenter1 datacenter2", cfg.FederatedDcs) // fedrated dscs
 	assert.Len(t, cfg.Datacenters, 3)                            // number of datacenters
+	assert.Equal(t, 10*time.Minute, cfg.DeployTimeout())         // default deploy timeout
 
 	// find one service
 	svc := cfg.Find("service_test1")
@@ -32,9 +36,10 @@ func TestLoadConfig(t *testing.T) {
 	svc = cfg.FindForDc("service_test1", "datacenter2")
 	assert.Nil(t, svc)
 
-	// all avaliable service names
-	allsvc := cfg.serviceNames()
-	assert.Len(t, allsvc, 3)
+	// all avaliable service names, service in two datacenters is listed once
+	allsvc := cfg.serviceNames("")
+	assert.Equal(t, []string{"service_test1", "service_test2"}, allsvc)
+	assert.Equal(t, []string{"service_test2"}, cfg.serviceNames("datacenter3"))
 
 	// all datacenters for service
 	alldc := cfg.FindDatacenters("service_test2")
@@ -54,6 +59,7 @@ func TestLoadServiceParams(t *testing.T) {
 	// check values
 	assert.Equal(t, "service_test1_image", svc.Image)
 	assert.Equal(t, 1, svc.Count)
+	assert.Equal(t, 1, svc.Canary)
 	assert.Equal(t, "app", svc.HostGroup)
 	assert.Equal(t, "app1", svc.Node)
 	assert.Equal(t, 64, svc.CPU)
@@ -77,6 +83,9 @@ func TestLoadServiceParams(t *testing.T) {
 	assert.Equal(t, "name-of-the-volume1:/path/in/container1", svc.Volumes[0])
 	assert.Equal(t, "name-of-the-volume2:/path/in/container2", svc.Volumes[1])
 
+	// check vault policies
+	assert.Equal(t, []string{"service_test1-read"}, svc.Vault)
+
 	assert.Len(t, svc.Constraints, 1)
 	c, exists := svc.Constraints["constraint"]
 	assert.True(t, exists)
@@ -84,3 +93,162 @@ func TestLoadServiceParams(t *testing.T) {
 	assert.Equal(t, "op", c.Operator)
 	assert.Equal(t, "val", c.Value)
 }
+
+func TestInterpolate(t *testing.T) {
+	os.Setenv("TEST_IMAGE_TAG", "20190410120000.1a2b3c4")
+	defer os.Unsetenv("TEST_IMAGE_TAG")
+	assert.Equal(t, "img:20190410120000.1a2b3c4", interpolate("img:${TEST_IMAGE_TAG}"))
+	assert.Equal(t, "img:20190410120000.1a2b3c4", interpolate("img:${env:TEST_IMAGE_TAG:-latest}"))
+	assert.Equal(t, "count: 3", interpolate("count: ${env:TEST_NOT_SET:-3}"))
+	assert.Equal(t, "count: ", interpolate("count: ${TEST_NOT_SET}"))
+}
+
+func TestSaveKeepsInterpolations(t *testing.T) {
+	os.Setenv("TEST_COUNT", "3")
+	defer os.Unsetenv("TEST_COUNT")
+	root, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(root+"/deployments/test", 0755))
+	fn := root + "/deployments/test/config.yml"
+	assert.NoError(t, ioutil.WriteFile(fn, []byte(`
+datacenters:
+    dc1:
+        services:
+            api:
+                image: ${env:TEST_API_IMAGE:-api:1}
+                count: ${TEST_COUNT}
+            worker:
+                image: worker:1
+`), 0644))
+
+	c, err := NewDeploymentConfig(root, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, c.FindForDc("api", "dc1").Count)
+	assert.Equal(t, "api:1", c.FindForDc("api", "dc1").Image)
+	c.FindForDc("worker", "dc1").Image = "worker:2"
+	assert.NoError(t, c.Save())
+
+	buf, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), "${env:TEST_API_IMAGE:-api:1}")
+	assert.Contains(t, string(buf), "${TEST_COUNT}")
+	assert.Contains(t, string(buf), "worker:2")
+}
+
+func TestDefaults(t *testing.T) {
+	root, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(root+"/deployments/test", 0755))
+	fn := root + "/deployments/test/config.yml"
+	assert.NoError(t, ioutil.WriteFile(fn, []byte(`
+defaults:
+    cpu: 100
+    mem: 128
+    env:
+        LOG_LEVEL: info
+        REGION: eu
+    auto_revert: true
+hostgroup_defaults:
+    worker:
+        mem: 512
+datacenters:
+    dc1:
+        services:
+            api:
+                image: api:1
+                hostgroup: app
+                env:
+                    LOG_LEVEL: debug
+                auto_revert: false
+            worker:
+                image: worker:1
+                hostgroup: worker
+                cpu: 200
+`), 0644))
+
+	c, err := NewDeploymentConfig(root, "test")
+	assert.NoError(t, err)
+	api := c.FindForDc("api", "dc1")
+	assert.Equal(t, 100, api.CPU)
+	assert.Equal(t, 128, api.Memory)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "debug", "REGION": "eu"}, api.Environment)
+	worker := c.FindForDc("worker", "dc1")
+	assert.Equal(t, 200, worker.CPU)
+	assert.Equal(t, 512, worker.Memory)
+	// explicit false is not overridden by default
+	assert.False(t, api.AutoRevert)
+	assert.True(t, worker.AutoRevert)
+
+	// defaults are not written to services
+	worker.Image = "worker:2"
+	assert.NoError(t, c.Save())
+	c, err = NewDeploymentConfig(root, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "worker:2", c.FindForDc("worker", "dc1").Image)
+	assert.Equal(t, 0, c.own[c.FindForDc("api", "dc1")].CPU)
+	assert.Equal(t, 0, c.own[c.FindForDc("worker", "dc1")].Memory)
+}
+
+func TestEnvOverlay(t *testing.T) {
+	root, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(root+"/deployments/test", 0755))
+	fn := root + "/deployments/test/config.yml"
+	base := `
+datacenters:
+    dc1:
+        services:
+            api:
+                image: api:1
+                count: 1
+                env:
+                    LOG_LEVEL: debug
+                    REGION: eu
+                arg: ["-a"]
+`
+	assert.NoError(t, ioutil.WriteFile(fn, []byte(base), 0644))
+	ofn := root + "/deployments/test/config.prod.yml"
+	assert.NoError(t, ioutil.WriteFile(ofn, []byte(`
+datacenters:
+    dc1:
+        services:
+            api:
+                count: 3
+                env:
+                    LOG_LEVEL: info
+                arg: ["-b"]
+`), 0644))
+
+	c, err := NewDeploymentEnvConfig(root, "test", "prod")
+	assert.NoError(t, err)
+	api := c.FindForDc("api", "dc1")
+	assert.Equal(t, "api:1", api.Image)
+	assert.Equal(t, 3, api.Count)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "info", "REGION": "eu"}, api.Environment)
+	assert.Equal(t, []string{"-b"}, api.Arguments)
+
+	// deployed image is written to overlay
+	api.Image = "api:2"
+	assert.NoError(t, c.Save())
+	buf, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, base, string(buf))
+	c, err = NewDeploymentEnvConfig(root, "test", "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "api:2", c.FindForDc("api", "dc1").Image)
+	assert.Equal(t, 3, c.FindForDc("api", "dc1").Count)
+}
+
+func TestIsSopsEncrypted(t *testing.T) {
+	assert.False(t, isSopsEncrypted([]byte("datacenters: {}\n")))
+	assert.True(t, isSopsEncrypted([]byte(`
+federated_dcs: ENC[AES256_GCM,data:abc,type:str]
+sops:
+    age:
+        - recipient: age1xyz
+    version: 3.7.3
+`)))
+}
@@ -0,0 +1,229 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// Dispatch registers parameterized job in Dc, dispatches it and follows
+// dispatched allocations until they finish.
+// Job is rendered from repository .nomad file and config.yml as in deploy.
+func Dispatch(ctx context.Context, o Options, meta map[string]string, payload []byte) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.loadDepConfig,
+		func() error { return w.dispatch(ctx, meta, payload) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) dispatch(ctx context.Context, meta map[string]string, payload []byte) error {
+	s := w.depConfig.FindForDc(w.service, w.dc)
+	if s == nil {
+		return WithExitCode(ExitValidation, fmt.Errorf("service %s not found in datacenter %s", w.service, w.dc))
+	}
+	if w.image == "" {
+		w.image = s.Image
+	}
+	d := w.newDeployer(w.dc)
+	return d.Dispatch(ctx, meta, payload)
+}
+
+// Dispatch registers parameterized job and dispatches it
+// connect - connects to a Nomad server
+// loadServiceConfig - loads Nomad job configuration from file *.nomad
+// validate - sets job configuration from config.yml
+// lock, plan, register - updates registered job as in deploy
+// dispatch - dispatches job with meta and payload
+// follow - streams dispatched allocations logs and waits for them to finish
+func (d *Deployer) Dispatch(ctx context.Context, meta map[string]string, payload []byte) error {
+	defer func() {
+		if d.unlock != nil {
+			d.unlock()
+			d.unlock = nil
+		}
+	}()
+	steps := []func(context.Context) error{
+		exitStep(ExitConnection, d.connect),
+		exitStep(ExitValidation, d.loadServiceConfig),
+		exitStep(ExitValidation, d.validate),
+		exitStep(ExitValidation, d.checkParameterized),
+		exitStep(ExitConflict, d.lockService),
+		exitStep(ExitValidation, d.plan),
+		exitStep(ExitFailed, d.register),
+		func(ctx context.Context) error { return d.dispatch(meta, payload) },
+		d.follow,
+	}
+	return runContextSteps(ctx, steps)
+}
+
+func (d *Deployer) checkParameterized(_ context.Context) error {
+	if !d.job.IsParameterized() {
+		return fmt.Errorf("job %s is not parameterized", d.service)
+	}
+	return nil
+}
+
+func (d *Deployer) dispatch(meta map[string]string, payload []byte) error {
+	rsp, _, err := d.cli.Jobs().Dispatch(d.service, meta, payload, nil)
+	if err != nil {
+		return err
+	}
+	d.jobEvalID = rsp.EvalID
+	d.log().S("job", rsp.DispatchedJobID).S("evalID", rsp.EvalID).Info("job dispatched")
+	return nil
+}
+
+// follow allocations of the dispatched job evaluation
+func (d *Deployer) follow(ctx context.Context) error {
+	if err := d.waitEval(ctx); err != nil {
+		return err
+	}
+	q := d.blockingQuery()
+	// wait for allocations to start
+	var allocs []*api.AllocationListStub
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var meta *api.QueryMeta
+		var err error
+		allocs, meta, err = d.cli.Evaluations().Allocations(d.jobEvalID, q)
+		if err != nil {
+			return err
+		}
+		if len(allocs) == 0 {
+			return fmt.Errorf("evaluation %s placed no allocations", d.jobEvalID)
+		}
+		if !allocsPending(allocs) {
+			break
+		}
+		q.WaitIndex = meta.LastIndex
+	}
+
+	var wg sync.WaitGroup
+	for _, stub := range allocs {
+		alloc, _, err := d.cli.Allocations().Info(stub.ID, nil)
+		if err != nil {
+			return err
+		}
+		d.log().S("alloc", alloc.ID[:8]).S("node", alloc.NodeID[:8]).Info("following allocation")
+		for _, task := range allocTasks(alloc) {
+			for _, lt := range []struct {
+				typ string
+				out io.Writer
+			}{{"stdout", os.Stdout}, {"stderr", os.Stderr}} {
+				wg.Add(1)
+				go func(task, typ string, out io.Writer) {
+					defer wg.Done()
+					if err := streamLogs(ctx, d.cli, alloc, task, typ, out); err != nil {
+						d.log().S("task", task).S("type", typ).Error(err)
+					}
+				}(task, lt.typ, lt.out)
+			}
+		}
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// logs streams are finished, collect allocations results
+	for {
+		allocs, meta, err := d.cli.Evaluations().Allocations(d.jobEvalID, q)
+		if err != nil {
+			return err
+		}
+		if allocsTerminal(allocs) {
+			return d.batchResult(allocs)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q.WaitIndex = meta.LastIndex
+	}
+}
+
+// waitEval waits until scheduler processes the job evaluation,
+// fails if allocations are not placed
+func (d *Deployer) waitEval(ctx context.Context) error {
+	q := d.blockingQuery()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ev, meta, err := d.cli.Evaluations().Info(d.jobEvalID, q)
+		if err != nil {
+			return err
+		}
+		switch ev.Status {
+		case evalStatusComplete:
+			return d.placementError(ev)
+		case evalStatusFailed, evalStatusCanceled:
+			return fmt.Errorf("evaluation %s %s: %s", ev.ID, ev.Status, ev.StatusDescription)
+		}
+		q.WaitIndex = meta.LastIndex
+	}
+}
+
+func allocsPending(allocs []*api.AllocationListStub) bool {
+	for _, a := range allocs {
+		if a.ClientStatus == "pending" {
+			return true
+		}
+	}
+	return false
+}
+
+// allocTasks returns names of the tasks in allocation task group
+func allocTasks(alloc *api.Allocation) []string {
+	var tasks []string
+	if alloc.Job == nil {
+		return tasks
+	}
+	for _, tg := range alloc.Job.TaskGroups {
+		if tg.Name == nil || *tg.Name != alloc.TaskGroup {
+			continue
+		}
+		for _, ta := range tg.Tasks {
+			tasks = append(tasks, ta.Name)
+		}
+	}
+	return tasks
+}
+
+// streamLogs copies task logs of logType (stdout or stderr) to out
+// until the task is finished or ctx is done.
+func streamLogs(ctx context.Context, cli NomadClient, alloc *api.Allocation, task, logType string, out io.Writer) error {
+	cancel := make(chan struct{})
+	defer close(cancel)
+	frames, errs := cli.AllocFS().Logs(alloc, true, task, logType, "start", 0, cancel, nil)
+	for {
+		select {
+		case f, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			if f.IsHeartbeat() {
+				continue
+			}
+			out.Write(f.Data)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
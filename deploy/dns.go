@@ -0,0 +1,259 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/minus5/svckit/log"
+)
+
+// DNS record types set by pitwall
+const (
+	DNSTypeA     = "A"
+	DNSTypeCNAME = "CNAME"
+)
+
+const (
+	defaultDNSProvider = "route53"
+	defaultDNSTTL      = 60
+)
+
+// DNSConfig points DNS record to the service after successful deployment,
+// for services exposed outside of the Consul mesh.
+type DNSConfig struct {
+	Provider string `yaml:"provider,omitempty"` // DNS provider from DNSProviders, default route53
+	Zone     string `yaml:"zone"`               // provider zone, Route53 hosted zone id
+	Name     string `yaml:"name"`               // record name, like api.example.com
+	TTL      int    `yaml:"ttl,omitempty"`      // default 60
+	Target   string `yaml:"target,omitempty"`   // load balancer host name, CNAME record is set to it
+	Service  string `yaml:"service,omitempty"`  // Consul service name for A records, defaults to the service
+}
+
+func (c *DNSConfig) provider() string {
+	if c.Provider == "" {
+		return defaultDNSProvider
+	}
+	return c.Provider
+}
+
+func (c *DNSConfig) ttl() int {
+	if c.TTL == 0 {
+		return defaultDNSTTL
+	}
+	return c.TTL
+}
+
+// DNSRecord is record set with all values of the name and type
+type DNSRecord struct {
+	Name   string
+	Type   string
+	TTL    int
+	Values []string
+}
+
+func (r *DNSRecord) equal(o *DNSRecord) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+	return strings.TrimSuffix(r.Name, ".") == strings.TrimSuffix(o.Name, ".") &&
+		r.Type == o.Type &&
+		r.TTL == o.TTL &&
+		strings.Join(r.Values, ",") == strings.Join(o.Values, ",")
+}
+
+func (r *DNSRecord) lines() []string {
+	var ls []string
+	for _, v := range r.Values {
+		ls = append(ls, fmt.Sprintf("%s %d %s %s", r.Name, r.TTL, r.Type, v))
+	}
+	return ls
+}
+
+// DNSProvider reads and updates DNS records in a zone
+type DNSProvider interface {
+	// Record returns record set of the name and type, nil if not found
+	Record(zone, name, typ string) (*DNSRecord, error)
+	// Upsert creates or replaces record set
+	Upsert(zone string, r *DNSRecord) error
+}
+
+// DNSProviders are DNS providers by name used in service dns config.
+// Other providers can be added before running deploy.
+var DNSProviders = map[string]DNSProvider{
+	defaultDNSProvider: route53{},
+}
+
+// updateDNS sets service DNS record, if configured, to the deployed service.
+// In dry run record changes are only shown.
+func (w *Worker) updateDNS(d *Deployer) error {
+	c := w.dnsConfig(d.dc)
+	if c == nil {
+		return nil
+	}
+	p, ok := DNSProviders[c.provider()]
+	if !ok {
+		return fmt.Errorf("unknown dns provider %s", c.provider())
+	}
+	want, err := d.dnsRecord(c)
+	if err != nil {
+		return err
+	}
+	have, err := p.Record(c.Zone, want.Name, want.Type)
+	if err != nil {
+		return err
+	}
+	if have.equal(want) {
+		log.S("name", want.Name).S("type", want.Type).Info("dns record up to date")
+		return nil
+	}
+	fmt.Fprint(termOut, dnsChange(have, want))
+	if w.dryRun {
+		return nil
+	}
+	if err := p.Upsert(c.Zone, want); err != nil {
+		return err
+	}
+	log.S("name", want.Name).S("type", want.Type).S("values", strings.Join(want.Values, ",")).Info("dns record updated")
+	return nil
+}
+
+func (w *Worker) dnsConfig(dc string) *DNSConfig {
+	if w.depConfig == nil {
+		return nil
+	}
+	s := w.depConfig.FindForDc(w.service, dc)
+	if s == nil {
+		return nil
+	}
+	return s.DNS
+}
+
+// dnsRecord is CNAME to the load balancer, or A records
+// of the service instances passing Consul health checks
+func (d *Deployer) dnsRecord(c *DNSConfig) (*DNSRecord, error) {
+	r := &DNSRecord{Name: c.Name, TTL: c.ttl()}
+	if c.Target != "" {
+		r.Type = DNSTypeCNAME
+		r.Values = []string{c.Target}
+		return r, nil
+	}
+	name := c.Service
+	if name == "" {
+		name = d.service
+	}
+	addrs, err := d.healthyInstances(name)
+	if err != nil {
+		return nil, err
+	}
+	r.Type = DNSTypeA
+	r.Values = instanceHosts(addrs)
+	if len(r.Values) == 0 {
+		return nil, fmt.Errorf("dns: no healthy instances of %s in %s", name, d.dc)
+	}
+	return r, nil
+}
+
+// instanceHosts returns unique sorted hosts of host:port addresses
+func instanceHosts(addrs []string) []string {
+	m := make(map[string]struct{})
+	for _, a := range addrs {
+		host, _, err := net.SplitHostPort(a)
+		if err != nil {
+			host = a
+		}
+		m[host] = struct{}{}
+	}
+	return sortedKeys(m)
+}
+
+// dnsChange shows record values removed and added
+func dnsChange(have, want *DNSRecord) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dns record %s %s:\n", want.Name, want.Type)
+	if have != nil {
+		for _, l := range have.lines() {
+			fmt.Fprintf(&b, "  %s\n", warn("- "+l))
+		}
+	}
+	for _, l := range want.lines() {
+		fmt.Fprintf(&b, "  %s\n", success("+ "+l))
+	}
+	return b.String()
+}
+
+// route53 provider uses aws cli, credentials are found by aws from environment
+type route53 struct{}
+
+type route53RecordSet struct {
+	Name            string
+	Type            string
+	TTL             int
+	ResourceRecords []struct{ Value string }
+}
+
+func (route53) Record(zone, name, typ string) (*DNSRecord, error) {
+	out, err := awsCli("route53", "list-resource-record-sets",
+		"--hosted-zone-id", zone,
+		"--start-record-name", name,
+		"--start-record-type", typ,
+		"--max-items", "1",
+		"--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	var rsp struct {
+		ResourceRecordSets []route53RecordSet
+	}
+	if err := json.Unmarshal(out, &rsp); err != nil {
+		return nil, err
+	}
+	for _, rs := range rsp.ResourceRecordSets {
+		if strings.TrimSuffix(rs.Name, ".") != strings.TrimSuffix(name, ".") || rs.Type != typ {
+			continue
+		}
+		r := &DNSRecord{Name: name, Type: typ, TTL: rs.TTL}
+		for _, rr := range rs.ResourceRecords {
+			r.Values = append(r.Values, rr.Value)
+		}
+		sort.Strings(r.Values)
+		return r, nil
+	}
+	return nil, nil
+}
+
+func (route53) Upsert(zone string, r *DNSRecord) error {
+	rs := route53RecordSet{Name: r.Name, Type: r.Type, TTL: r.TTL}
+	for _, v := range r.Values {
+		rs.ResourceRecords = append(rs.ResourceRecords, struct{ Value string }{v})
+	}
+	batch := map[string]interface{}{
+		"Comment": "pitwall deploy",
+		"Changes": []interface{}{
+			map[string]interface{}{"Action": "UPSERT", "ResourceRecordSet": rs},
+		},
+	}
+	buf, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	_, err = awsCli("route53", "change-resource-record-sets",
+		"--hosted-zone-id", zone,
+		"--change-batch", string(buf))
+	return err
+}
+
+func awsCli(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("aws", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("aws %s %s failed: %v %s", args[0], args[1], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
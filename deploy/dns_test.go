@@ -0,0 +1,31 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceHosts(t *testing.T) {
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, instanceHosts([]string{"10.0.0.2:8080", "10.0.0.1:8080", "10.0.0.2:8081"}))
+	assert.Len(t, instanceHosts(nil), 0)
+}
+
+func TestDNSRecordEqual(t *testing.T) {
+	r := &DNSRecord{Name: "api.example.com", Type: DNSTypeA, TTL: 60, Values: []string{"10.0.0.1"}}
+	assert.True(t, r.equal(&DNSRecord{Name: "api.example.com.", Type: DNSTypeA, TTL: 60, Values: []string{"10.0.0.1"}}))
+	assert.False(t, r.equal(&DNSRecord{Name: "api.example.com", Type: DNSTypeA, TTL: 300, Values: []string{"10.0.0.1"}}))
+	assert.False(t, r.equal(nil))
+	var n *DNSRecord
+	assert.True(t, n.equal(nil))
+}
+
+func TestDNSConfigDefaults(t *testing.T) {
+	c := &DNSConfig{}
+	assert.Equal(t, "route53", c.provider())
+	assert.Equal(t, 60, c.ttl())
+	r, err := (&Deployer{}).dnsRecord(&DNSConfig{Name: "api.example.com", Target: "lb.example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, DNSTypeCNAME, r.Type)
+	assert.Equal(t, []string{"lb.example.com"}, r.Values)
+}
@@ -0,0 +1,100 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// Drift compares jobs running in Nomad with the ones rendered from
+// repository .nomad files and config.yml, and shows differences.
+// Services in Dc option datacenter are checked, or just Service if set.
+func Drift(ctx context.Context, o Options) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.loadDepConfig,
+		func() error { return w.drift(ctx) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) loadDepConfig() error {
+	c, err := w.configSource().Load(w.deployment, w.env)
+	if err != nil {
+		return err
+	}
+	w.depConfig = c
+	return nil
+}
+
+func (w *Worker) drift(ctx context.Context) error {
+	dcc, ok := w.depConfig.Datacenters[w.dc]
+	if !ok {
+		return fmt.Errorf("datacenter %s not found", w.dc)
+	}
+	var services []string
+	for name := range dcc.Services {
+		if w.service == "" || name == w.service {
+			services = append(services, name)
+		}
+	}
+	sort.Strings(services)
+
+	drifted := 0
+	for _, name := range services {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.service = name
+		w.image = dcc.Services[name].Image
+		d := w.newDeployer(w.dc)
+		jd, err := d.drift(ctx)
+		if err != nil {
+			drifted++
+			fmt.Printf("%s %s\n", warn(name), warn(err.Error()))
+			continue
+		}
+		if jd == nil || jd.Type == diffTypeNone {
+			fmt.Printf("%s %s\n", name, success("in sync"))
+			continue
+		}
+		drifted++
+		var b strings.Builder
+		formatJobDiff(&b, jd)
+		fmt.Printf("%s %s\n%s\n", name, warn("drifted"), b.String())
+	}
+	if drifted > 0 {
+		return fmt.Errorf("%d of %d services drifted in %s", drifted, len(services), w.dc)
+	}
+	return nil
+}
+
+// drift renders job from repository and plans it against the running job.
+// Returned diff shows changes from the running job to the rendered one.
+func (d *Deployer) drift(ctx context.Context) (*api.JobDiff, error) {
+	steps := []func(context.Context) error{
+		d.connect,
+		d.loadServiceConfig,
+		d.validate,
+	}
+	if err := runContextSteps(ctx, steps); err != nil {
+		return nil, err
+	}
+	running, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return nil, err
+	}
+	copyDeployMeta(d.job, running)
+	jp, _, err := d.cli.Jobs().Plan(d.job, true, nil)
+	if err != nil {
+		return nil, err
+	}
+	d.log().S("service", d.service).Debug("planned")
+	return jp.Diff, nil
+}
@@ -0,0 +1,238 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// event stream topics
+const (
+	TopicJob        = "Job"
+	TopicEvaluation = "Evaluation"
+	TopicAllocation = "Allocation"
+	TopicDeployment = "Deployment"
+	TopicNode       = "Node"
+)
+
+// serviceTopics are subscribed to when events are filtered by service
+var serviceTopics = []string{TopicJob, TopicEvaluation, TopicAllocation, TopicDeployment}
+
+// NomadEvent is Nomad event stream event.
+// Nomad api used here (0.8) has no event stream client so events are decoded here.
+type NomadEvent struct {
+	Topic      string          `json:"Topic"`
+	Type       string          `json:"Type"`
+	Key        string          `json:"Key"`
+	Namespace  string          `json:"Namespace"`
+	FilterKeys []string        `json:"FilterKeys"`
+	Index      uint64          `json:"Index"`
+	Payload    json.RawMessage `json:"Payload"`
+}
+
+// eventsFrame is one line of the event stream, heartbeat frame has no events
+type eventsFrame struct {
+	Index  uint64        `json:"Index"`
+	Events []*NomadEvent `json:"Events"`
+}
+
+// eventPayload has fields of all topics payloads shown in event summary
+type eventPayload struct {
+	Job *struct {
+		ID      string
+		Version uint64
+		Status  string
+	}
+	Evaluation *struct {
+		ID          string
+		JobID       string
+		TriggeredBy string
+		Status      string
+	}
+	Allocation *struct {
+		ID               string
+		Name             string
+		NodeName         string
+		ClientStatus     string
+		DesiredStatus    string
+		DeploymentStatus *struct {
+			Healthy *bool
+		}
+	}
+	Deployment *struct {
+		ID                string
+		JobID             string
+		JobVersion        uint64
+		Status            string
+		StatusDescription string
+	}
+	Node *struct {
+		Name                  string
+		Status                string
+		SchedulingEligibility string
+		Drain                 bool
+	}
+}
+
+// EventStream subscribes to Nomad event stream in Dc and writes events to stdout
+// until interrupted. Topics limit subscription, Service filters events of the service job.
+// Json output writes each event as json line.
+// Event stream is available in Nomad >= 1.0.
+func EventStream(ctx context.Context, o Options, topics []string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	if err := d.connect(ctx); err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	if err := d.EventStream(ctx, os.Stdout, topics, o.Output == OutputJSON); err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// EventStream writes events to out until ctx is done or stream is closed.
+func (d *Deployer) EventStream(ctx context.Context, out io.Writer, topics []string, jsonOut bool) error {
+	body, err := d.cli.Raw().Response(eventsEndpoint(topics, d.service), nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "404") {
+			return fmt.Errorf("event stream not found, it requires Nomad >= 1.0: %v", err)
+		}
+		return err
+	}
+	defer body.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			body.Close()
+		case <-stop:
+		}
+	}()
+	d.log().S("topics", strings.Join(topics, ",")).S("service", d.service).Info("subscribed to events")
+
+	enc := json.NewEncoder(out)
+	dec := json.NewDecoder(body)
+	for {
+		var f eventsFrame
+		if err := dec.Decode(&f); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, e := range f.Events {
+			if !e.matchService(d.service) {
+				continue
+			}
+			if jsonOut {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Fprintf(out, "%s\n", e)
+		}
+	}
+}
+
+// eventsEndpoint is event stream url subscribed to topics,
+// with service job as topic key if set
+func eventsEndpoint(topics []string, service string) string {
+	if len(topics) == 0 && service != "" {
+		topics = serviceTopics
+	}
+	v := url.Values{}
+	for _, t := range topics {
+		key := "*"
+		if service != "" && t != TopicNode {
+			key = service
+		}
+		v.Add("topic", t+":"+key)
+	}
+	if len(v) == 0 {
+		return "/v1/event/stream"
+	}
+	return "/v1/event/stream?" + v.Encode()
+}
+
+// matchService checks if event belongs to the service job
+func (e *NomadEvent) matchService(service string) bool {
+	if service == "" || e.Topic == TopicNode || e.Key == service {
+		return true
+	}
+	for _, k := range e.FilterKeys {
+		if k == service {
+			return true
+		}
+	}
+	return false
+}
+
+func (e NomadEvent) String() string {
+	return fmt.Sprintf("%s %-11s %-28s %s",
+		faint(time.Now().Format("15:04:05")),
+		e.Topic,
+		e.Type,
+		e.summary())
+}
+
+// summary of the event payload
+func (e *NomadEvent) summary() string {
+	var p eventPayload
+	if err := json.Unmarshal(e.Payload, &p); err != nil {
+		return e.Key
+	}
+	switch {
+	case p.Deployment != nil:
+		s := p.Deployment
+		status := s.Status
+		if status == DeploymentStatusFailed {
+			status = warn(status)
+		}
+		return strings.TrimSpace(fmt.Sprintf("%s %s v%d %s %s", short(s.ID), s.JobID, s.JobVersion, status, s.StatusDescription))
+	case p.Allocation != nil:
+		s := p.Allocation
+		status := s.ClientStatus
+		if status == "failed" || status == "lost" {
+			status = warn(status)
+		}
+		health := ""
+		if ds := s.DeploymentStatus; ds != nil && ds.Healthy != nil {
+			health = success("healthy")
+			if !*ds.Healthy {
+				health = warn("unhealthy")
+			}
+		}
+		return strings.TrimSpace(fmt.Sprintf("%s %s %s %s/%s %s", short(s.ID), s.Name, s.NodeName, status, s.DesiredStatus, health))
+	case p.Evaluation != nil:
+		s := p.Evaluation
+		return fmt.Sprintf("%s %s %s %s", short(s.ID), s.JobID, s.TriggeredBy, s.Status)
+	case p.Job != nil:
+		s := p.Job
+		return fmt.Sprintf("%s v%d %s", s.ID, s.Version, s.Status)
+	case p.Node != nil:
+		s := p.Node
+		eligibility := s.SchedulingEligibility
+		if s.Drain {
+			eligibility = warn("draining")
+		}
+		return fmt.Sprintf("%s %s %s", s.Name, s.Status, eligibility)
+	}
+	return e.Key
+}
+
+// short returns first 8 characters of the Nomad ID
+func short(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
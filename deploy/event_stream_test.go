@@ -0,0 +1,26 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsEndpoint(t *testing.T) {
+	assert.Equal(t, "/v1/event/stream", eventsEndpoint(nil, ""))
+	assert.Equal(t, "/v1/event/stream?topic=Deployment%3A%2A", eventsEndpoint([]string{"Deployment"}, ""))
+	assert.Equal(t, "/v1/event/stream?topic=Allocation%3Aapi&topic=Node%3A%2A", eventsEndpoint([]string{"Allocation", "Node"}, "api"))
+	assert.Contains(t, eventsEndpoint(nil, "api"), "topic=Job%3Aapi")
+}
+
+func TestNomadEvent(t *testing.T) {
+	e := &NomadEvent{
+		Topic:      TopicDeployment,
+		Key:        "1234567890",
+		FilterKeys: []string{"api"},
+		Payload:    []byte(`{"Deployment":{"ID":"1234567890","JobID":"api","JobVersion":3,"Status":"running"}}`),
+	}
+	assert.True(t, e.matchService("api"))
+	assert.False(t, e.matchService("web"))
+	assert.Equal(t, "12345678 api v3 running", e.summary())
+}
@@ -0,0 +1,91 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// EventType is type of the deploy event
+type EventType string
+
+// deploy event types
+const (
+	EventValidated    EventType = "validated"
+	EventPlanned      EventType = "planned"
+	EventRegistered   EventType = "registered"
+	EventAllocHealthy EventType = "alloc_healthy"
+	EventSucceeded    EventType = "succeeded"
+	EventFailed       EventType = "failed"
+)
+
+// DeployEvent is emitted by Deployer.Run on each deployment step
+type DeployEvent struct {
+	Type         EventType
+	Time         time.Time
+	Service      string
+	Dc           string
+	EvalID       string
+	DeploymentID string
+	// task group allocations, set on EventAllocHealthy
+	Group   string
+	Healthy int
+	Desired int
+	// job plan, set on EventPlanned
+	Plan *api.JobPlanResponse
+	// set on EventFailed
+	Err error
+}
+
+// Run deploys service in the background and returns channel of deploy events.
+// Service config is loaded and Nomad connected before returning.
+// Channel is closed after EventSucceeded or EventFailed,
+// caller must read all events until then.
+// Plan is not printed to stdout, it is in the EventPlanned.
+// Progress is not logged, events are dropped after ctx is done.
+func (d *Deployer) Run(ctx context.Context) (<-chan DeployEvent, error) {
+	d.logOut = ioutil.Discard
+	steps := []func(context.Context) error{
+		d.connect,
+		d.loadServiceConfig,
+	}
+	if err := runContextSteps(ctx, steps); err != nil {
+		return nil, err
+	}
+
+	d.events = make(chan DeployEvent, 16)
+	d.eventsDone = ctx.Done()
+	go func() {
+		defer close(d.events)
+		ctx, cancel := d.withTimeout(ctx)
+		defer cancel()
+		steps := []func(context.Context) error{
+			d.validate,
+			d.plan,
+			d.register,
+			d.statusOrRevert,
+		}
+		if err := runContextSteps(ctx, steps); err != nil {
+			d.emit(DeployEvent{Type: EventFailed, DeploymentID: d.jobDeploymentID, Err: err})
+			return
+		}
+		d.emit(DeployEvent{Type: EventSucceeded, DeploymentID: d.jobDeploymentID})
+	}()
+	return d.events, nil
+}
+
+// emit deploy event if deployer is started with Run
+func (d *Deployer) emit(e DeployEvent) {
+	if d.events == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Service = d.service
+	e.Dc = d.cdc
+	select {
+	case d.events <- e:
+	case <-d.eventsDone:
+	}
+}
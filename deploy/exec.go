@@ -0,0 +1,126 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/hashicorp/nomad/api"
+	"github.com/manifoldco/promptui"
+	"github.com/minus5/svckit/log"
+)
+
+// allocItem is allocation shown in select prompt
+type allocItem struct {
+	*api.AllocationListStub
+}
+
+func (a allocItem) String() string {
+	return fmt.Sprintf("%s  %-30s v%-5d %s",
+		a.ID[:8],
+		a.Name,
+		a.JobVersion,
+		faint(units.HumanDuration(time.Since(time.Unix(0, a.CreateTime)))+" ago"))
+}
+
+// runningAllocations returns running allocations, the newest first
+func runningAllocations(allocs []*api.AllocationListStub) []*api.AllocationListStub {
+	var running []*api.AllocationListStub
+	for _, a := range allocs {
+		if a.ClientStatus == "running" {
+			running = append(running, a)
+		}
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].CreateTime > running[j].CreateTime })
+	return running
+}
+
+// selectAlloc selects running allocation of the service job,
+// asks if there are more than one
+func (d *Deployer) selectAlloc() (*api.AllocationListStub, error) {
+	allocs, _, err := d.cli.Jobs().Allocations(d.service, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	running := runningAllocations(allocs)
+	switch len(running) {
+	case 0:
+		return nil, fmt.Errorf("no running allocations of %s in %s", d.service, d.dc)
+	case 1:
+		return running[0], nil
+	}
+	items := make([]allocItem, len(running))
+	for i, a := range running {
+		items[i] = allocItem{a}
+	}
+	prompt := promptui.Select{
+		Label: "Select allocation",
+		Items: items,
+		Size:  10,
+		Templates: &promptui.SelectTemplates{
+			Selected: string([]byte("\033[" + "1A")),
+		},
+	}
+	idx, _, err := prompt.Run()
+	if err != nil {
+		return nil, ErrAborted
+	}
+	return running[idx], nil
+}
+
+// Exec runs command in the service allocation in Dc,
+// interactive session with tty if stdin is terminal.
+// Nomad api 0.8 has no alloc exec endpoint so nomad cli (>= 0.9.2) is used
+// with connection parameters from options.
+func Exec(ctx context.Context, o Options, task string, command []string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	if err := d.connect(ctx); err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	a, err := d.selectAlloc()
+	if err != nil {
+		return done(err)
+	}
+	if task == "" {
+		task = d.service
+	}
+	if len(command) == 0 {
+		command = []string{"/bin/sh"}
+	}
+	log.S("alloc", a.ID[:8]).S("task", task).Info("exec")
+	args := append([]string{"alloc", "exec", "-task", task, a.ID}, command...)
+	cmd := exec.CommandContext(ctx, "nomad", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), d.nomadEnv()...)
+	return cmd.Run()
+}
+
+// nomadEnv returns environment variables for nomad cli connected to deployer Nomad
+func (d *Deployer) nomadEnv() []string {
+	c := d.nomadConfig().clientConfig(d.address)
+	vars := []string{"NOMAD_ADDR=" + c.Address}
+	add := func(name, value string) {
+		if value != "" {
+			vars = append(vars, name+"="+value)
+		}
+	}
+	add("NOMAD_TOKEN", c.SecretID)
+	add("NOMAD_NAMESPACE", d.jobNamespace())
+	add("NOMAD_CACERT", c.TLSConfig.CACert)
+	add("NOMAD_CLIENT_CERT", c.TLSConfig.ClientCert)
+	add("NOMAD_CLIENT_KEY", c.TLSConfig.ClientKey)
+	add("NOMAD_TLS_SERVER_NAME", c.TLSConfig.TLSServerName)
+	if c.TLSConfig.Insecure {
+		add("NOMAD_SKIP_VERIFY", "true")
+	}
+	return vars
+}
@@ -0,0 +1,26 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunningAllocations(t *testing.T) {
+	allocs := []*api.AllocationListStub{
+		{ID: "1", ClientStatus: "running", CreateTime: 1},
+		{ID: "2", ClientStatus: "complete", CreateTime: 2},
+		{ID: "3", ClientStatus: "running", CreateTime: 3},
+	}
+	running := runningAllocations(allocs)
+	assert.Len(t, running, 2)
+	assert.Equal(t, "3", running[0].ID)
+}
+
+func TestNomadEnv(t *testing.T) {
+	d := &Deployer{address: "10.0.0.1:4646", nomad: NomadConfig{Token: "secret", TLS: true}}
+	env := d.nomadEnv()
+	assert.Contains(t, env, "NOMAD_ADDR=https://10.0.0.1:4646")
+	assert.Contains(t, env, "NOMAD_TOKEN=secret")
+}
@@ -0,0 +1,104 @@
+package deploy
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Process exit codes, so automation can branch on the kind of failure
+const (
+	ExitError      = 1 // unclassified error
+	ExitValidation = 2 // invalid arguments, config or job
+	ExitConflict   = 3 // job changed since plan or service locked by another deploy
+	ExitFailed     = 4 // deployment failed
+	ExitTimeout    = 5 // deployment timed out
+	ExitConnection = 6 // Nomad, Consul, registry or repository not reachable
+)
+
+// exitError is error with process exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// WithExitCode sets exit code of the error.
+// Error which already has code keeps it, connection errors and
+// timed out deployments get their own codes regardless of the code.
+func WithExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*exitError); ok {
+		return err
+	}
+	switch {
+	case err == ErrAborted || err == context.Canceled:
+		code = ExitError
+	case isTimeout(err):
+		code = ExitTimeout
+	case isConnectionError(err):
+		code = ExitConnection
+	case code == 0:
+		code = ExitError
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns process exit code for the error
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := err.(*exitError); ok {
+		return e.code
+	}
+	return WithExitCode(ExitError, err).(*exitError).code
+}
+
+// wrapExit keeps exit code of the err in wrapped error
+func wrapExit(err, wrapped error) error {
+	return WithExitCode(ExitCode(err), wrapped)
+}
+
+// stepExitCodes are exit codes for errors of the worker steps
+var stepExitCodes = map[string]int{
+	"pull":           ExitConnection,
+	"select_service": ExitValidation,
+	"build_image":    ExitFailed,
+	"select_image":   ExitValidation,
+	"pin_image":      ExitValidation,
+	"verify_image":   ExitValidation,
+	"deploy":         ExitFailed,
+	"pull_changes":   ExitConnection,
+	"push":           ExitConnection,
+}
+
+func isTimeout(err error) bool {
+	return err == context.DeadlineExceeded ||
+		strings.HasPrefix(err.Error(), ErrDeploymentTimeout.Error())
+}
+
+// isConflict is job register refused because job changed since plan
+func isConflict(err error) bool {
+	return strings.Contains(err.Error(), "conflicting job modify index")
+}
+
+func isConnectionError(err error) bool {
+	switch err.(type) {
+	case *url.Error, *net.OpError, *net.DNSError:
+		return true
+	}
+	msg := err.Error()
+	for _, s := range []string{"connection refused", "no such host", "i/o timeout", "network is unreachable"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
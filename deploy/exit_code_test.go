@@ -0,0 +1,27 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, ExitError, ExitCode(errors.New("unknown")))
+	assert.Equal(t, ExitValidation, ExitCode(WithExitCode(ExitValidation, errors.New("invalid job"))))
+	assert.Equal(t, ExitError, ExitCode(WithExitCode(ExitValidation, ErrAborted)))
+
+	conn := &url.Error{Op: "Get", URL: "http://nomad", Err: errors.New("connection refused")}
+	assert.Equal(t, ExitConnection, ExitCode(WithExitCode(ExitFailed, conn)))
+
+	timedOut := fmt.Errorf("%v, reverted to version 3", ErrDeploymentTimeout)
+	assert.Equal(t, ExitTimeout, ExitCode(WithExitCode(ExitFailed, timedOut)))
+
+	err := WithExitCode(ExitConflict, errors.New("service locked"))
+	assert.Equal(t, ExitConflict, ExitCode(WithExitCode(ExitFailed, err)))
+	assert.Equal(t, ExitConflict, ExitCode(wrapExit(err, fmt.Errorf("api: %v", err))))
+}
@@ -0,0 +1,465 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// errFakeNotSupported is returned by FakeNomad for APIs it doesn't implement
+var errFakeNotSupported = errors.New("not supported by fake nomad")
+
+// StaticConfigSource is ConfigSource with config in memory, saved configs are kept in it
+type StaticConfigSource struct {
+	Config *DeploymentConfig
+}
+
+// Load returns source config
+func (s *StaticConfigSource) Load(deployment, env string) (*DeploymentConfig, error) {
+	if s.Config == nil {
+		return nil, fmt.Errorf("config of deployment %s not found", deployment)
+	}
+	s.Config.deployment = deployment
+	return s.Config, nil
+}
+
+// Save replaces source config
+func (s *StaticConfigSource) Save(c *DeploymentConfig) error {
+	s.Config = c
+	return nil
+}
+
+// MemAuditStore is AuditStore with records in memory
+type MemAuditStore struct {
+	mu      sync.Mutex
+	Records []AuditRecord
+}
+
+// Append adds record to the store
+func (s *MemAuditStore) Append(r AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Records = append(s.Records, r)
+	return nil
+}
+
+// List returns records sorted by time
+func (s *MemAuditStore) List() ([]AuditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs := append([]AuditRecord(nil), s.Records...)
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Time.Before(rs[j].Time) })
+	return rs, nil
+}
+
+// StaticJobLoader is JobLoader with jobs by service name, same in all datacenters.
+// Loaded job is a copy, deployer changes don't modify it.
+type StaticJobLoader map[string]*api.Job
+
+// LoadJob returns copy of the service job
+func (l StaticJobLoader) LoadJob(service, dc string) (*api.Job, error) {
+	job, ok := l[service]
+	if !ok {
+		return nil, fmt.Errorf("job of service %s not found", service)
+	}
+	return copyJob(job)
+}
+
+// FakeNomad is in memory NomadClient for deployments without Nomad.
+// Registered service jobs get deployment with DeploymentStatus, successful by default.
+// Nodes, allocations, logs and raw API are not supported.
+type FakeNomad struct {
+	Dc               string
+	Region           string
+	DeploymentStatus string
+
+	mu          sync.Mutex
+	index       uint64
+	jobs        map[string][]*api.Job // versions by job id
+	evals       map[string]*api.Evaluation
+	deployments map[string]*api.Deployment
+}
+
+// NewFakeNomad creates fake Nomad of the datacenter
+func NewFakeNomad(dc string) *FakeNomad {
+	return &FakeNomad{
+		Dc:               dc,
+		Region:           "global",
+		DeploymentStatus: DeploymentStatusSuccessful,
+		jobs:             make(map[string][]*api.Job),
+		evals:            make(map[string]*api.Evaluation),
+		deployments:      make(map[string]*api.Deployment),
+	}
+}
+
+// Job returns the latest registered version of the job, nil if not registered
+func (n *FakeNomad) Job(id string) *api.Job {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	vs := n.jobs[id]
+	if len(vs) == 0 {
+		return nil
+	}
+	return vs[len(vs)-1]
+}
+
+// Agent of the fake
+func (n *FakeNomad) Agent() NomadAgent { return fakeAgent{n} }
+
+// Allocations of the fake
+func (n *FakeNomad) Allocations() NomadAllocations { return fakeAllocations{n} }
+
+// AllocFS of the fake
+func (n *FakeNomad) AllocFS() NomadAllocFS { return fakeAllocFS{n} }
+
+// Deployments of the fake
+func (n *FakeNomad) Deployments() NomadDeployments { return fakeDeployments{n} }
+
+// Evaluations of the fake
+func (n *FakeNomad) Evaluations() NomadEvaluations { return fakeEvaluations{n} }
+
+// Jobs of the fake
+func (n *FakeNomad) Jobs() NomadJobs { return fakeJobs{n} }
+
+// Nodes of the fake
+func (n *FakeNomad) Nodes() NomadNodes { return fakeNodes{n} }
+
+// Raw of the fake
+func (n *FakeNomad) Raw() NomadRaw { return fakeRaw{n} }
+
+func (n *FakeNomad) queryMeta() *api.QueryMeta {
+	return &api.QueryMeta{LastIndex: n.index}
+}
+
+func (n *FakeNomad) nextID(prefix string) string {
+	n.index++
+	return fmt.Sprintf("%s-%08d-0000-0000-0000-000000000000", prefix, n.index)
+}
+
+// register stores new version of the job with its evaluation and deployment
+func (n *FakeNomad) register(job *api.Job, modifyIndex uint64, enforce bool) (*api.JobRegisterResponse, error) {
+	job, err := copyJob(job)
+	if err != nil {
+		return nil, err
+	}
+	job.Canonicalize()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id := *job.ID
+	vs := n.jobs[id]
+	var current uint64
+	version := uint64(0)
+	if len(vs) > 0 {
+		prev := vs[len(vs)-1]
+		current, version = *prev.JobModifyIndex, *prev.Version+1
+	}
+	if enforce && modifyIndex != current {
+		return nil, fmt.Errorf("Enforcing job modify index %d: job exists with conflicting job modify index: %d", modifyIndex, current)
+	}
+	ev := &api.Evaluation{ID: n.nextID("eval"), JobID: id, Type: *job.Type, Status: "complete"}
+	job.Version = &version
+	job.Stable = boolPtr(false)
+	if *job.Type == JobTypeService {
+		dep := &api.Deployment{
+			ID:             n.nextID("dep"),
+			JobID:          id,
+			JobVersion:     version,
+			Status:         n.DeploymentStatus,
+			TaskGroups:     make(map[string]*api.DeploymentState),
+			CreateIndex:    n.index,
+			JobCreateIndex: *job.CreateIndex,
+		}
+		for _, tg := range job.TaskGroups {
+			s := &api.DeploymentState{DesiredTotal: *tg.Count}
+			if dep.Status == DeploymentStatusSuccessful {
+				s.PlacedAllocs, s.HealthyAllocs = *tg.Count, *tg.Count
+			}
+			dep.TaskGroups[*tg.Name] = s
+		}
+		job.Stable = boolPtr(dep.Status == DeploymentStatusSuccessful)
+		n.deployments[dep.ID] = dep
+		ev.DeploymentID = dep.ID
+	}
+	index := n.index
+	job.JobModifyIndex = &index
+	n.jobs[id] = append(vs, job)
+	if job.IsPeriodic() || job.IsParameterized() {
+		// Nomad doesn't create evaluation for them
+		return &api.JobRegisterResponse{JobModifyIndex: index}, nil
+	}
+	n.evals[ev.ID] = ev
+	return &api.JobRegisterResponse{EvalID: ev.ID, JobModifyIndex: index}, nil
+}
+
+// copyJob makes deep copy of the job
+func copyJob(job *api.Job) (*api.Job, error) {
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	var j api.Job
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+type fakeAgent struct{ n *FakeNomad }
+
+func (a fakeAgent) Datacenter() (string, error) { return a.n.Dc, nil }
+func (a fakeAgent) Region() (string, error)     { return a.n.Region, nil }
+
+type fakeAllocations struct{ n *FakeNomad }
+
+func (a fakeAllocations) Info(allocID string, q *api.QueryOptions) (*api.Allocation, *api.QueryMeta, error) {
+	return nil, nil, errFakeNotSupported
+}
+
+func (a fakeAllocations) List(q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error) {
+	return nil, a.n.queryMeta(), nil
+}
+
+type fakeAllocFS struct{ n *FakeNomad }
+
+func (a fakeAllocFS) Logs(alloc *api.Allocation, follow bool, task, logType, origin string,
+	offset int64, cancel <-chan struct{}, q *api.QueryOptions) (<-chan *api.StreamFrame, <-chan error) {
+	errs := make(chan error, 1)
+	errs <- errFakeNotSupported
+	return nil, errs
+}
+
+type fakeDeployments struct{ n *FakeNomad }
+
+func (d fakeDeployments) Allocations(deploymentID string, q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error) {
+	return nil, d.n.queryMeta(), nil
+}
+
+func (d fakeDeployments) Fail(deploymentID string, q *api.WriteOptions) (*api.DeploymentUpdateResponse, *api.WriteMeta, error) {
+	return d.setStatus(deploymentID, DeploymentStatusFailed)
+}
+
+func (d fakeDeployments) Info(deploymentID string, q *api.QueryOptions) (*api.Deployment, *api.QueryMeta, error) {
+	d.n.mu.Lock()
+	defer d.n.mu.Unlock()
+	dep, ok := d.n.deployments[deploymentID]
+	if !ok {
+		return nil, nil, fmt.Errorf("deployment %s not found", deploymentID)
+	}
+	return dep, d.n.queryMeta(), nil
+}
+
+func (d fakeDeployments) PromoteAll(deploymentID string, q *api.WriteOptions) (*api.DeploymentUpdateResponse, *api.WriteMeta, error) {
+	return d.setStatus(deploymentID, DeploymentStatusSuccessful)
+}
+
+func (d fakeDeployments) setStatus(deploymentID, status string) (*api.DeploymentUpdateResponse, *api.WriteMeta, error) {
+	d.n.mu.Lock()
+	defer d.n.mu.Unlock()
+	dep, ok := d.n.deployments[deploymentID]
+	if !ok {
+		return nil, nil, fmt.Errorf("deployment %s not found", deploymentID)
+	}
+	dep.Status = status
+	return &api.DeploymentUpdateResponse{}, &api.WriteMeta{}, nil
+}
+
+type fakeEvaluations struct{ n *FakeNomad }
+
+func (e fakeEvaluations) Allocations(evalID string, q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error) {
+	return nil, e.n.queryMeta(), nil
+}
+
+func (e fakeEvaluations) Info(evalID string, q *api.QueryOptions) (*api.Evaluation, *api.QueryMeta, error) {
+	e.n.mu.Lock()
+	defer e.n.mu.Unlock()
+	ev, ok := e.n.evals[evalID]
+	if !ok {
+		return nil, nil, fmt.Errorf("evaluation %s not found", evalID)
+	}
+	return ev, e.n.queryMeta(), nil
+}
+
+type fakeJobs struct{ n *FakeNomad }
+
+func (j fakeJobs) Allocations(jobID string, allAllocs bool, q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error) {
+	return nil, j.n.queryMeta(), nil
+}
+
+func (j fakeJobs) Deployments(jobID string, q *api.QueryOptions) ([]*api.Deployment, *api.QueryMeta, error) {
+	j.n.mu.Lock()
+	defer j.n.mu.Unlock()
+	var ds []*api.Deployment
+	for _, d := range j.n.deployments {
+		if d.JobID == jobID {
+			ds = append(ds, d)
+		}
+	}
+	sort.Slice(ds, func(a, b int) bool { return ds[a].CreateIndex > ds[b].CreateIndex })
+	return ds, j.n.queryMeta(), nil
+}
+
+func (j fakeJobs) Deregister(jobID string, purge bool, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	j.n.mu.Lock()
+	defer j.n.mu.Unlock()
+	if _, ok := j.n.jobs[jobID]; !ok {
+		return "", nil, fmt.Errorf("job %s not found", jobID)
+	}
+	delete(j.n.jobs, jobID)
+	return j.n.nextID("eval"), &api.WriteMeta{}, nil
+}
+
+// Dispatch registers child job of the latest parent job version with complete evaluation
+func (j fakeJobs) Dispatch(jobID string, meta map[string]string, payload []byte, q *api.WriteOptions) (*api.JobDispatchResponse, *api.WriteMeta, error) {
+	parent := j.n.Job(jobID)
+	if parent == nil {
+		return nil, nil, fmt.Errorf("job %s not found", jobID)
+	}
+	if !parent.IsParameterized() {
+		return nil, nil, fmt.Errorf("job %s is not parameterized", jobID)
+	}
+	job, err := copyJob(parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	j.n.mu.Lock()
+	defer j.n.mu.Unlock()
+	id := fmt.Sprintf("%s/dispatch-%d", jobID, j.n.index+1)
+	job.ID, job.Name, job.ParentID = &id, &id, &jobID
+	job.ParameterizedJob = nil
+	job.Meta = meta
+	job.Payload = payload
+	ev := &api.Evaluation{ID: j.n.nextID("eval"), JobID: id, Type: *job.Type, Status: evalStatusComplete}
+	index := j.n.index
+	job.JobModifyIndex = &index
+	j.n.jobs[id] = []*api.Job{job}
+	j.n.evals[ev.ID] = ev
+	return &api.JobDispatchResponse{DispatchedJobID: id, EvalID: ev.ID, JobCreateIndex: index}, &api.WriteMeta{}, nil
+}
+
+func (j fakeJobs) EnforceRegister(job *api.Job, modifyIndex uint64, q *api.WriteOptions) (*api.JobRegisterResponse, *api.WriteMeta, error) {
+	r, err := j.n.register(job, modifyIndex, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, &api.WriteMeta{}, nil
+}
+
+func (j fakeJobs) Info(jobID string, q *api.QueryOptions) (*api.Job, *api.QueryMeta, error) {
+	job := j.n.Job(jobID)
+	if job == nil {
+		return nil, nil, fmt.Errorf("Unexpected response code: 404 (job not found)")
+	}
+	return job, j.n.queryMeta(), nil
+}
+
+func (j fakeJobs) LatestDeployment(jobID string, q *api.QueryOptions) (*api.Deployment, *api.QueryMeta, error) {
+	ds, qm, err := j.Deployments(jobID, q)
+	if err != nil || len(ds) == 0 {
+		return nil, qm, err
+	}
+	return ds[0], qm, nil
+}
+
+func (j fakeJobs) List(q *api.QueryOptions) ([]*api.JobListStub, *api.QueryMeta, error) {
+	j.n.mu.Lock()
+	defer j.n.mu.Unlock()
+	var js []*api.JobListStub
+	for id, vs := range j.n.jobs {
+		job := vs[len(vs)-1]
+		js = append(js, &api.JobListStub{ID: id, Name: *job.Name, Type: *job.Type, Status: "running", JobModifyIndex: *job.JobModifyIndex})
+	}
+	sort.Slice(js, func(a, b int) bool { return js[a].ID < js[b].ID })
+	return js, j.n.queryMeta(), nil
+}
+
+func (j fakeJobs) Plan(job *api.Job, diff bool, q *api.WriteOptions) (*api.JobPlanResponse, *api.WriteMeta, error) {
+	var index uint64
+	if current := j.n.Job(*job.ID); current != nil {
+		index = *current.JobModifyIndex
+	}
+	return &api.JobPlanResponse{JobModifyIndex: index}, &api.WriteMeta{}, nil
+}
+
+func (j fakeJobs) Revert(jobID string, version uint64, enforcePriorVersion *uint64, q *api.WriteOptions) (*api.JobRegisterResponse, *api.WriteMeta, error) {
+	j.n.mu.Lock()
+	var target *api.Job
+	for _, v := range j.n.jobs[jobID] {
+		if *v.Version == version {
+			target = v
+		}
+	}
+	j.n.mu.Unlock()
+	if target == nil {
+		return nil, nil, fmt.Errorf("job %s version %d not found", jobID, version)
+	}
+	r, err := j.n.register(target, 0, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, &api.WriteMeta{}, nil
+}
+
+func (j fakeJobs) Summary(jobID string, q *api.QueryOptions) (*api.JobSummary, *api.QueryMeta, error) {
+	return nil, nil, errFakeNotSupported
+}
+
+func (j fakeJobs) Validate(job *api.Job, q *api.WriteOptions) (*api.JobValidateResponse, *api.WriteMeta, error) {
+	return &api.JobValidateResponse{}, &api.WriteMeta{}, nil
+}
+
+func (j fakeJobs) Versions(jobID string, diffs bool, q *api.QueryOptions) ([]*api.Job, []*api.JobDiff, *api.QueryMeta, error) {
+	j.n.mu.Lock()
+	defer j.n.mu.Unlock()
+	vs := j.n.jobs[jobID]
+	if len(vs) == 0 {
+		return nil, nil, nil, fmt.Errorf("job %s not found", jobID)
+	}
+	// newest first, as in Nomad
+	var js []*api.Job
+	for i := len(vs) - 1; i >= 0; i-- {
+		js = append(js, vs[i])
+	}
+	return js, nil, j.n.queryMeta(), nil
+}
+
+type fakeNodes struct{ n *FakeNomad }
+
+func (f fakeNodes) Allocations(nodeID string, q *api.QueryOptions) ([]*api.Allocation, *api.QueryMeta, error) {
+	return nil, nil, errFakeNotSupported
+}
+
+func (f fakeNodes) Info(nodeID string, q *api.QueryOptions) (*api.Node, *api.QueryMeta, error) {
+	return nil, nil, errFakeNotSupported
+}
+
+func (f fakeNodes) List(q *api.QueryOptions) ([]*api.NodeListStub, *api.QueryMeta, error) {
+	return nil, f.n.queryMeta(), nil
+}
+
+func (f fakeNodes) MonitorDrain(ctx context.Context, nodeID string, index uint64, ignoreSys bool) <-chan *api.MonitorMessage {
+	ch := make(chan *api.MonitorMessage)
+	close(ch)
+	return ch
+}
+
+func (f fakeNodes) ToggleEligibility(nodeID string, eligible bool, q *api.WriteOptions) (*api.NodeEligibilityUpdateResponse, error) {
+	return nil, errFakeNotSupported
+}
+
+func (f fakeNodes) UpdateDrain(nodeID string, spec *api.DrainSpec, markEligible bool, q *api.WriteOptions) (*api.NodeDrainUpdateResponse, error) {
+	return nil, errFakeNotSupported
+}
+
+type fakeRaw struct{ n *FakeNomad }
+
+func (r fakeRaw) Response(endpoint string, q *api.QueryOptions) (io.ReadCloser, error) {
+	return nil, errFakeNotSupported
+}
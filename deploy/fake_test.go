@@ -0,0 +1,160 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeServiceJob(service string) *api.Job {
+	job := api.NewServiceJob(service, service, "global", 50)
+	tg := api.NewTaskGroup(service, 1)
+	ta := api.NewTask(service, "docker").SetConfig("image", "placeholder")
+	ta.Env = map[string]string{}
+	tg.AddTask(ta)
+	job.AddTaskGroup(tg)
+	return job
+}
+
+func fakeConfig(service string) *DeploymentConfig {
+	return &DeploymentConfig{Datacenters: map[string]*DcConfig{
+		"pg1": {Services: map[string]*ServiceConfig{
+			service: {Image: "api:1.0", Count: 2},
+		}},
+	}}
+}
+
+func TestDeployerWithFakeNomad(t *testing.T) {
+	n := NewFakeNomad("pg1")
+	jobs := StaticJobLoader{"api": fakeServiceJob("api")}
+	d := NewDeployerWithClient(n, jobs, "", "api", "api:1.1", fakeConfig("api"), "pg1", "s2")
+	assert.Nil(t, d.Go(context.Background(), false))
+
+	job := n.Job("api")
+	assert.NotNil(t, job)
+	assert.Equal(t, "api:1.1", job.TaskGroups[0].Tasks[0].Config["image"])
+	assert.Equal(t, 2, *job.TaskGroups[0].Count)
+	assert.Equal(t, uint64(0), *job.Version)
+	assert.NotEmpty(t, d.jobDeploymentID)
+	// loader job is not changed by deployer
+	assert.Equal(t, "placeholder", jobs["api"].TaskGroups[0].Tasks[0].Config["image"])
+
+	d = NewDeployerWithClient(n, jobs, "", "api", "api:1.2", fakeConfig("api"), "pg1", "s2")
+	n.DeploymentStatus = DeploymentStatusFailed
+	assert.NotNil(t, d.Go(context.Background(), false))
+	assert.Equal(t, uint64(1), *n.Job("api").Version)
+	assert.False(t, *n.Job("api").Stable)
+}
+
+func TestRunWithFakes(t *testing.T) {
+	src := &StaticConfigSource{Config: fakeConfig("api")}
+	audit := &MemAuditStore{}
+	err := runWorker(context.Background(), newWorker(Options{
+		Deployment:   "s2",
+		Service:      "api",
+		Dc:           "pg1",
+		Image:        "api:1.1",
+		Yes:          true,
+		NoLock:       true,
+		ConfigSource: src,
+		NomadClient:  NewFakeNomad("pg1"),
+		JobLoader:    StaticJobLoader{"api": fakeServiceJob("api")},
+		AuditStore:   audit,
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, "api:1.1", src.Config.Datacenters["pg1"].Services["api"].Image)
+	rs, _ := audit.List()
+	assert.Len(t, rs, 1)
+	assert.Equal(t, "succeeded", rs[0].Result)
+	assert.Equal(t, "api:1.1", rs[0].Image)
+}
+
+func TestDeploymentIDTimeout(t *testing.T) {
+	n := NewFakeNomad("pg1")
+	jr, _, err := n.Jobs().EnforceRegister(fakeServiceJob("api"), 0, nil)
+	assert.Nil(t, err)
+	d := NewDeployerWithClient(n, nil, "", "api", "api:1.1", fakeConfig("api"), "pg1", "s2")
+	d.jobEvalID = jr.EvalID
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	err = d.getDeploymentID(ctx)
+	assert.Equal(t, ErrDeploymentTimeout, err)
+	assert.Equal(t, ExitTimeout, ExitCode(WithExitCode(ExitFailed, err)))
+	dep, _, err := n.Deployments().Info(d.jobDeploymentID, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, DeploymentStatusFailed, dep.Status)
+}
+
+func TestRunEvents(t *testing.T) {
+	n := NewFakeNomad("pg1")
+	jobs := StaticJobLoader{"api": fakeServiceJob("api")}
+	d := NewDeployerWithClient(n, jobs, "", "api", "api:1.1", fakeConfig("api"), "pg1", "s2")
+	events, err := d.Run(context.Background())
+	assert.Nil(t, err)
+	var types []EventType
+	for e := range events {
+		assert.Equal(t, "api", e.Service)
+		assert.Equal(t, "pg1", e.Dc)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []EventType{EventValidated, EventPlanned, EventRegistered, EventAllocHealthy, EventSucceeded}, types)
+
+	n.DeploymentStatus = DeploymentStatusFailed
+	d = NewDeployerWithClient(n, jobs, "", "api", "api:1.2", fakeConfig("api"), "pg1", "s2")
+	events, err = d.Run(context.Background())
+	assert.Nil(t, err)
+	var last DeployEvent
+	for e := range events {
+		last = e
+	}
+	assert.Equal(t, EventFailed, last.Type)
+	assert.NotNil(t, last.Err)
+	assert.NotEmpty(t, last.DeploymentID)
+}
+
+func TestDeployPeriodicJob(t *testing.T) {
+	job := fakeServiceJob("cleanup")
+	typ, spec := JobTypeBatch, "*/15 * * * *"
+	job.Type = &typ
+	job.AddPeriodicConfig(&api.PeriodicConfig{Spec: &spec})
+	n := NewFakeNomad("pg1")
+	d := NewDeployerWithClient(n, StaticJobLoader{"cleanup": job}, "", "cleanup", "cleanup:1.1", fakeConfig("cleanup"), "pg1", "s2")
+	assert.Nil(t, d.Go(context.Background(), false))
+	assert.Empty(t, d.jobEvalID)
+	assert.Equal(t, "cleanup:1.1", n.Job("cleanup").TaskGroups[0].Tasks[0].Config["image"])
+}
+
+func TestFollowPlacementFailure(t *testing.T) {
+	n := NewFakeNomad("pg1")
+	n.evals["eval-1"] = &api.Evaluation{ID: "eval-1", Status: evalStatusComplete,
+		FailedTGAllocs: map[string]*api.AllocationMetric{"report": {}}}
+	d := NewDeployerWithClient(n, nil, "", "report", "", nil, "pg1", "s2")
+	d.jobEvalID = "eval-1"
+	err := d.follow(context.Background())
+	assert.EqualError(t, err, "evaluation eval-1 failed to place allocations")
+
+	n.evals["eval-1"].FailedTGAllocs = nil
+	err = d.follow(context.Background())
+	assert.EqualError(t, err, "evaluation eval-1 placed no allocations")
+}
+
+func TestDispatchRegistersJob(t *testing.T) {
+	job := fakeServiceJob("report")
+	typ := JobTypeBatch
+	job.Type = &typ
+	job.ParameterizedJob = &api.ParameterizedJobConfig{MetaOptional: []string{"version"}}
+	n := NewFakeNomad("pg1")
+	d := NewDeployerWithClient(n, StaticJobLoader{"report": job}, "", "report", "report:1.1", fakeConfig("report"), "pg1", "s2")
+	err := d.Dispatch(context.Background(), map[string]string{"version": "42"}, nil)
+	// fake doesn't place allocations
+	assert.EqualError(t, err, fmt.Sprintf("evaluation %s placed no allocations", d.jobEvalID))
+	assert.Equal(t, "report:1.1", n.Job("report").TaskGroups[0].Tasks[0].Config["image"])
+	child := n.Job(n.evals[d.jobEvalID].JobID)
+	assert.Equal(t, "report:1.1", child.TaskGroups[0].Tasks[0].Config["image"])
+	assert.Equal(t, "42", child.Meta["version"])
+}
@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/log"
+)
+
+// GrafanaConfig configures Grafana annotations of the successful deploys
+type GrafanaConfig struct {
+	URL          string   `yaml:"url"`                     // Grafana url, like https://grafana.example.com
+	Token        string   `yaml:"token"`                   // API key or service account token, use ${env:VAR} reference
+	DashboardUID string   `yaml:"dashboard_uid,omitempty"` // annotate only this dashboard, organization wide if empty
+	Tags         []string `yaml:"tags,omitempty"`          // added to deploy, service, dc and deployment tags
+}
+
+// Annotation is posted to the Grafana annotations API
+type Annotation struct {
+	Time         int64    `json:"time"` // unix milliseconds
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+func (c *GrafanaConfig) annotation(n Notification, t time.Time) Annotation {
+	tags := append([]string{"deploy", n.Service, n.Dc, n.Deployment}, c.Tags...)
+	return Annotation{
+		Time:         t.UnixNano() / int64(time.Millisecond),
+		DashboardUID: c.DashboardUID,
+		Tags:         tags,
+		Text:         n.text(),
+	}
+}
+
+// annotate posts annotation of the deployment.
+// Failed annotation is logged, it doesn't break deployment.
+func (c *GrafanaConfig) annotate(n Notification) {
+	if c == nil || c.URL == "" {
+		return
+	}
+	if err := c.post(c.annotation(n, time.Now())); err != nil {
+		log.S("url", c.URL).Error(err)
+	}
+}
+
+func (c *GrafanaConfig) post(a Annotation) error {
+	buf, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(c.URL, "/") + "/api/annotations"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	rsp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		return fmt.Errorf("grafana annotation failed: %s", rsp.Status)
+	}
+	return nil
+}
+
+// annotate successful deploy in the datacenter
+func (w *Worker) annotate(dc string) {
+	if w.dryRun || w.depConfig == nil {
+		return
+	}
+	w.depConfig.Grafana.annotate(Notification{
+		Event:      NotifySucceeded,
+		Deployment: w.deployment,
+		Service:    w.service,
+		Image:      w.image,
+		Dc:         dc,
+		User:       currentUser(),
+	})
+}
@@ -0,0 +1,38 @@
+package deploy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrafanaAnnotate(t *testing.T) {
+	var auth string
+	var as []Annotation
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/annotations", r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		var a Annotation
+		json.NewDecoder(r.Body).Decode(&a)
+		as = append(as, a)
+	}))
+	defer srv.Close()
+
+	c := &GrafanaConfig{URL: srv.URL + "/", Token: "secret", Tags: []string{"team"}}
+	c.annotate(Notification{Event: NotifySucceeded, Deployment: "s2", Service: "api", Image: "api:1", Dc: "pg1", User: "me"})
+	assert.Len(t, as, 1)
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, []string{"deploy", "api", "pg1", "s2", "team"}, as[0].Tags)
+	assert.Equal(t, "me deploy of api (api:1) to s2/pg1 succeeded", as[0].Text)
+
+	a := c.annotation(Notification{}, time.Unix(1, 0))
+	assert.Equal(t, int64(1000), a.Time)
+
+	// nil config is noop
+	var nc *GrafanaConfig
+	nc.annotate(Notification{})
+}
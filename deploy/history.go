@@ -0,0 +1,111 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
+)
+
+// History shows previous job versions of the service in Dc
+func History(ctx context.Context, o Options) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	versions, err := d.History(ctx)
+	if err != nil {
+		return done(err)
+	}
+	err = render(o.Output, versions, func(w io.Writer) {
+		for _, v := range versions {
+			fmt.Fprintf(w, "%s\n", v)
+		}
+	})
+	if err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// JobVersion is summary of the one job version
+type JobVersion struct {
+	Version    uint64    `json:"version" yaml:"version"`
+	Image      string    `json:"image" yaml:"image"`
+	SubmitTime time.Time `json:"submit_time" yaml:"submit_time"`
+	User       string    `json:"user" yaml:"user"`
+	Stable     bool      `json:"stable" yaml:"stable"`
+	Status     string    `json:"status" yaml:"status"` // status of the deployment of this version
+}
+
+func (v JobVersion) String() string {
+	stable := " "
+	if v.Stable {
+		stable = "*"
+	}
+	tag := v.Image
+	if i := strings.LastIndex(tag, ":"); i >= 0 {
+		tag = tag[i+1:]
+	}
+	h := units.HumanDuration(time.Now().Sub(v.SubmitTime)) + " ago"
+	return fmt.Sprintf("%s %-5d %-25s %-15s %-12s %-12s %s",
+		stable,
+		v.Version,
+		h,
+		v.SubmitTime.Format("02.01. 15:04"),
+		v.User,
+		v.Status,
+		tag)
+}
+
+// History returns all known job versions, from the newest one
+func (d *Deployer) History(ctx context.Context) ([]JobVersion, error) {
+	if err := d.connect(ctx); err != nil {
+		return nil, err
+	}
+	jobs, _, _, err := d.cli.Jobs().Versions(d.service, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	deps, _, err := d.cli.Jobs().Deployments(d.service, nil)
+	if err != nil {
+		return nil, err
+	}
+	status := make(map[uint64]string)
+	for _, dep := range deps {
+		status[dep.JobVersion] = dep.Status
+	}
+
+	var versions []JobVersion
+	for _, j := range jobs {
+		v := JobVersion{
+			Image: jobImage(j, d.service),
+			User:  j.Meta[DeployedByMeta],
+		}
+		if j.Version != nil {
+			v.Version = *j.Version
+			v.Status = status[v.Version]
+		}
+		if j.SubmitTime != nil {
+			v.SubmitTime = time.Unix(0, *j.SubmitTime)
+		}
+		if j.Stable != nil {
+			v.Stable = *j.Stable
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// currentUser returns name of the user running deployment
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,79 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/minus5/svckit/log"
+)
+
+// HooksConfig local commands run around service deployment
+type HooksConfig struct {
+	Pre         []string `yaml:"pre,omitempty"`
+	PostSuccess []string `yaml:"post_success,omitempty"`
+	PostFailure []string `yaml:"post_failure,omitempty"`
+}
+
+// runPreHooks runs pre deploy hooks of the service, failed hook stops deployment
+func (w *Worker) runPreHooks(d *Deployer) error {
+	h := w.hooks(d.dc)
+	if h == nil {
+		return nil
+	}
+	return w.runHooks(d, h.Pre, nil)
+}
+
+// runPostHooks runs post deploy hooks depending on deployment result
+func (w *Worker) runPostHooks(d *Deployer, derr error) {
+	h := w.hooks(d.dc)
+	if h == nil {
+		return
+	}
+	cmds := h.PostSuccess
+	if derr != nil {
+		cmds = h.PostFailure
+	}
+	if err := w.runHooks(d, cmds, derr); err != nil {
+		log.Error(err)
+	}
+}
+
+func (w *Worker) hooks(dc string) *HooksConfig {
+	if w.dryRun || w.depConfig == nil {
+		return nil
+	}
+	s := w.depConfig.FindForDc(w.service, dc)
+	if s == nil {
+		return nil
+	}
+	return s.Hooks
+}
+
+// runHooks runs commands in shell from the deployment directory.
+// Deployment info is passed in environment variables.
+func (w *Worker) runHooks(d *Deployer, cmds []string, derr error) error {
+	env := append(os.Environ(),
+		"SERVICE="+w.service,
+		"DC="+d.dc,
+		"IMAGE="+w.image,
+		"DEPLOYMENT="+w.deployment,
+		"DEPLOYMENT_ID="+d.jobDeploymentID,
+		"EVAL_ID="+d.jobEvalID,
+		"DEPLOY_ERROR="+errString(derr),
+	)
+	dir := filepath.Dir(w.depConfig.FileName())
+	for _, c := range cmds {
+		log.S("cmd", c).S("dc", d.dc).Info("running hook")
+		cmd := exec.Command("sh", "-c", c)
+		cmd.Env = env
+		cmd.Dir = dir
+		cmd.Stdout = termOut
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook %q failed: %v", c, err)
+		}
+	}
+	return nil
+}
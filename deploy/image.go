@@ -1,16 +1,19 @@
 package deploy
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os/exec"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/docker/go-units"
 	"github.com/manifoldco/promptui"
+	"github.com/minus5/svckit/env"
 	"github.com/minus5/svckit/log"
 )
 
@@ -143,3 +146,197 @@ func (a tags) Less(i, j int) bool {
 	}
 	return t1.created.After(t2.created)
 }
+
+// manifest media types accepted when resolving image digest
+var manifestTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// splitImage splits image reference to registry, repository and tag
+func splitImage(ref string) (registry, repo, tag string) {
+	if i := strings.Index(ref, "/"); i > 0 && strings.ContainsAny(ref[:i], ".:") {
+		registry, ref = ref[:i], ref[i+1:]
+	}
+	repo = ref
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repo, tag = ref[:i], ref[i+1:]
+	}
+	return
+}
+
+// isMutableTag is tag not a build timestamp, like latest or branch name
+func isMutableTag(tag string) bool {
+	return NewTag(tag, false).created.IsZero()
+}
+
+// pinImage resolves mutable image tag to digest.
+// Returns image unchanged if it is already pinned or tag is immutable.
+func pinImage(image string) (string, error) {
+	if strings.Contains(image, "@") {
+		return image, nil
+	}
+	registry, repo, tag := splitImage(image)
+	if registry == "" {
+		return image, nil
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	if !isMutableTag(tag) {
+		return image, nil
+	}
+	digest, err := manifestDigest(registry, repo, tag)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s@%s", registry, repo, digest), nil
+}
+
+// imageDigest returns digest of the image, from reference if it is pinned
+// or from registry. Empty if image is not in private registry.
+func imageDigest(image string) (string, error) {
+	if i := strings.Index(image, "@"); i > 0 {
+		return image[i+1:], nil
+	}
+	registry, repo, tag := splitImage(image)
+	if registry == "" {
+		return "", nil
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	return manifestDigest(registry, repo, tag)
+}
+
+// manifestDigest finds digest of the image manifest in registry
+func manifestDigest(registry, repo, tag string) (string, error) {
+	resp, err := manifestRequest(http.MethodHead, registry, repo, tag)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("image %s/%s:%s manifest not found: %s", registry, repo, tag, resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s didn't return digest for %s:%s", registry, repo, tag)
+	}
+	return digest, nil
+}
+
+// manifestRequest sends request for the image manifest by tag or digest
+func manifestRequest(method, registry, repo, reference string) (*http.Response, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s/v2/%s/manifests/%s", registry, repo, reference), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if auth := registryAuth(registry); auth != "" {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// CredentialsHelper is docker credential helper, like pass or osxkeychain,
+// used to get registry credentials before docker config auths
+var CredentialsHelper string
+
+// helperAuth gets registry credentials from docker-credential-<helper>
+func helperAuth(helper, registry string) string {
+	c := exec.Command("docker-credential-"+helper, "get")
+	c.Stdin = strings.NewReader(registry)
+	out, err := c.Output()
+	if err != nil {
+		log.S("helper", helper).S("registry", registry).Error(err)
+		return ""
+	}
+	cr := struct {
+		Username string
+		Secret   string
+	}{}
+	if err := json.Unmarshal(out, &cr); err != nil {
+		log.S("helper", helper).Error(err)
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(cr.Username + ":" + cr.Secret))
+}
+
+// registryAuth finds basic auth credentials for registry from credentials helper
+// or in docker config
+func registryAuth(registry string) string {
+	if CredentialsHelper != "" {
+		if auth := helperAuth(CredentialsHelper, registry); auth != "" {
+			return auth
+		}
+	}
+	fn := env.ExpandPath("~/.docker/config.json")
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return ""
+	}
+	cfg := struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(buf, &cfg); err != nil {
+		log.S("file", fn).Error(err)
+		return ""
+	}
+	for _, k := range []string{registry, "http://" + registry, "https://" + registry} {
+		if a, ok := cfg.Auths[k]; ok {
+			return a.Auth
+		}
+	}
+	return ""
+}
+
+// platformImage returns image pinned to the manifest for linux/arch platform,
+// if image is multi-arch manifest list. Empty otherwise.
+func platformImage(image, arch string) (string, error) {
+	registry, repo, tag := splitImage(image)
+	if registry == "" {
+		return "", nil
+	}
+	if i := strings.Index(image, "@"); i > 0 {
+		registry, repo, _ = splitImage(image[:i])
+		tag = image[i+1:]
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	resp, err := manifestRequest(http.MethodGet, registry, repo, tag)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("image %s manifest not found: %s", image, resp.Status)
+	}
+	ml := struct {
+		MediaType string
+		Manifests []struct {
+			Digest   string
+			Platform struct {
+				Architecture string
+				OS           string
+			}
+		}
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&ml); err != nil {
+		return "", err
+	}
+	if len(ml.Manifests) == 0 {
+		return "", nil
+	}
+	for _, m := range ml.Manifests {
+		if m.Platform.Architecture == arch && m.Platform.OS == "linux" {
+			return fmt.Sprintf("%s/%s@%s", registry, repo, m.Digest), nil
+		}
+	}
+	return "", fmt.Errorf("image %s has no linux/%s platform", image, arch)
+}
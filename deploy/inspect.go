@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Inspect writes definition of the service job running in Dc to stdout,
+// as json by default or yaml.
+func Inspect(ctx context.Context, o Options) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	if err := d.connect(ctx); err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	job, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return done(err)
+	}
+	format := o.Output
+	if format == "" || format == OutputTable {
+		format = OutputJSON
+	}
+	if err := render(format, job, nil); err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// Diff shows differences between the service job running in Dc and the job
+// rendered from repository .nomad file and config.yml.
+// Diff is made locally, job is not planned, so it needs only read access to Nomad.
+// Returns error if jobs differ.
+func Diff(ctx context.Context, o Options) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.loadDepConfig,
+		func() error { return w.diff(ctx) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) diff(ctx context.Context) error {
+	s := w.depConfig.FindForDc(w.service, w.dc)
+	if s == nil {
+		return WithExitCode(ExitValidation, fmt.Errorf("service %s not found in datacenter %s", w.service, w.dc))
+	}
+	if w.image == "" {
+		w.image = s.Image
+	}
+	d := w.newDeployer(w.dc)
+	diff, err := d.diff(ctx)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Printf("%s %s\n", w.service, success("in sync"))
+		return nil
+	}
+	fmt.Print(colorDiff(diff))
+	return fmt.Errorf("running job %s differs from repository", w.service)
+}
+
+// diff renders job from repository and returns unified diff
+// from the running job to the rendered one
+func (d *Deployer) diff(ctx context.Context) (string, error) {
+	steps := []func(context.Context) error{
+		exitStep(ExitConnection, d.connect),
+		exitStep(ExitValidation, d.loadServiceConfig),
+		exitStep(ExitValidation, d.validate),
+	}
+	if err := runContextSteps(ctx, steps); err != nil {
+		return "", err
+	}
+	running, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return "", err
+	}
+	copyDeployMeta(d.job, running)
+	d.job.Canonicalize()
+	running.Canonicalize()
+	a, err := comparableJob(running)
+	if err != nil {
+		return "", err
+	}
+	b, err := comparableJob(d.job)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: "running",
+		ToFile:   "repository",
+		Context:  3,
+	})
+}
+
+// copyDeployMeta sets deploy info meta of the running job to job,
+// it is not a difference
+func copyDeployMeta(job, running *api.Job) {
+	for _, k := range []string{DeployedByMeta, ImageDigestMeta, RestartedMeta} {
+		if v, ok := running.Meta[k]; ok {
+			job.SetMeta(k, v)
+		} else {
+			delete(job.Meta, k)
+		}
+	}
+}
+
+// comparableJob returns job json without fields set by Nomad
+func comparableJob(job *api.Job) (string, error) {
+	j := *job
+	j.Status = nil
+	j.StatusDescription = nil
+	j.Stable = nil
+	j.Version = nil
+	j.SubmitTime = nil
+	j.CreateIndex = nil
+	j.ModifyIndex = nil
+	j.JobModifyIndex = nil
+	buf, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(buf) + "\n", nil
+}
+
+// colorDiff colors added and removed lines of unified diff
+func colorDiff(diff string) string {
+	var b strings.Builder
+	for _, line := range difflib.SplitLines(diff) {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			b.WriteString(line)
+		case strings.HasPrefix(line, "+"):
+			b.WriteString(success(strings.TrimSuffix(line, "\n")) + "\n")
+		case strings.HasPrefix(line, "-"):
+			b.WriteString(warn(strings.TrimSuffix(line, "\n")) + "\n")
+		default:
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparableJob(t *testing.T) {
+	id, version := "backend_api", uint64(3)
+	running := &api.Job{ID: &id, Version: &version, Meta: map[string]string{DeployedByMeta: "ianic"}}
+	job := &api.Job{ID: &id}
+	copyDeployMeta(job, running)
+	assert.Equal(t, "ianic", job.Meta[DeployedByMeta])
+
+	a, err := comparableJob(running)
+	assert.Nil(t, err)
+	b, err := comparableJob(job)
+	assert.Nil(t, err)
+	assert.Equal(t, a, b)
+	assert.Equal(t, uint64(3), *running.Version)
+}
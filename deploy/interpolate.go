@@ -0,0 +1,71 @@
+package deploy
+
+import (
+	"os"
+	"regexp"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// matches ${VAR}, ${env:VAR} and ${env:VAR:-default}
+var interpolationRx = regexp.MustCompile(`\$\{(env:)?([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces environment variable references in config.
+// Default value is used if variable is not set or empty.
+func interpolate(s string) string {
+	return interpolationRx.ReplaceAllStringFunc(s, func(m string) string {
+		p := interpolationRx.FindStringSubmatch(m)
+		if v := os.Getenv(p[2]); v != "" {
+			return v
+		}
+		return p[4]
+	})
+}
+
+func hasInterpolation(s string) bool {
+	return interpolationRx.MatchString(s)
+}
+
+// marshalWithInterpolations marshals config keeping variable references
+// from the raw config. Only service images are changed by deployments,
+// so they are updated in the raw config when changed.
+func (c *DeploymentConfig) marshalWithInterpolations() ([]byte, error) {
+	var raw yaml.MapSlice
+	if err := yaml.Unmarshal(c.raw, &raw); err != nil {
+		return nil, err
+	}
+	expanded := &DeploymentConfig{}
+	if err := yaml.Unmarshal([]byte(interpolate(string(c.raw))), expanded); err != nil {
+		return nil, err
+	}
+	expanded.applyDefaults()
+	for dc, d := range c.Datacenters {
+		for name, s := range d.Services {
+			if es := expanded.FindForDc(name, dc); es != nil && es.Image == s.Image {
+				continue
+			}
+			setMapValue(raw, s.Image, "datacenters", dc, "services", name, "image")
+		}
+	}
+	return yaml.Marshal(raw)
+}
+
+// setMapValue sets value in nested map slices, missing keys are added
+func setMapValue(m yaml.MapSlice, v interface{}, path ...string) yaml.MapSlice {
+	for i, item := range m {
+		if k, ok := item.Key.(string); !ok || k != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			m[i].Value = v
+			return m
+		}
+		child, _ := item.Value.(yaml.MapSlice)
+		m[i].Value = setMapValue(child, v, path[1:]...)
+		return m
+	}
+	if len(path) == 1 {
+		return append(m, yaml.MapItem{Key: path[0], Value: v})
+	}
+	return append(m, yaml.MapItem{Key: path[0], Value: setMapValue(nil, v, path[1:]...)})
+}
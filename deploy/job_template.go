@@ -0,0 +1,67 @@
+package deploy
+
+import (
+	"bytes"
+	"path/filepath"
+	"text/template"
+)
+
+// Delimiters of the .nomad file template actions. Differ from the default
+// ones so consul-template data in job template stanzas is left as it is.
+const (
+	jobTemplateLeftDelim  = "[["
+	jobTemplateRightDelim = "]]"
+)
+
+// jobContext is data available in .nomad file template, like [[ .Dc ]] or [[ .Vars.domain ]]
+type jobContext struct {
+	Dc         string // datacenter from config.yml
+	Region     string // Nomad region
+	Deployment string
+	Service    string
+	Image      string
+	Config     *ServiceConfig    // service config for the datacenter
+	Vars       map[string]string // vars from config.yml, datacenter overrides deployment
+}
+
+// renderJob renders .nomad file content through text/template
+func (d *Deployer) renderJob(fn string, buf []byte) ([]byte, error) {
+	t, err := template.New(filepath.Base(fn)).
+		Delims(jobTemplateLeftDelim, jobTemplateRightDelim).
+		Option("missingkey=error").
+		Parse(string(buf))
+	if err != nil {
+		return nil, err
+	}
+	ctx := jobContext{
+		Dc:         d.cdc,
+		Region:     d.region,
+		Deployment: d.deployment,
+		Service:    d.service,
+		Image:      d.image,
+		Vars:       map[string]string{},
+	}
+	if d.config != nil {
+		ctx.Config = d.config.FindForDc(d.service, d.cdc)
+		ctx.Vars = d.config.dcVars(d.cdc)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, ctx); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// dcVars merges deployment and datacenter vars
+func (c *DeploymentConfig) dcVars(dc string) map[string]string {
+	vars := make(map[string]string)
+	for k, v := range c.Vars {
+		vars[k] = v
+	}
+	if dcc, ok := c.Datacenters[dc]; ok && dcc != nil {
+		for k, v := range dcc.Vars {
+			vars[k] = v
+		}
+	}
+	return vars
+}
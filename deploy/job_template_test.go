@@ -0,0 +1,37 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderJob(t *testing.T) {
+	c := &DeploymentConfig{
+		Vars: map[string]string{"domain": "example.com", "log": "info"},
+		Datacenters: map[string]*DcConfig{
+			"dc1": {
+				Vars:     map[string]string{"log": "debug"},
+				Services: map[string]*ServiceConfig{"api": {Image: "api:1", Count: 2}},
+			},
+		},
+	}
+	d := NewDeployer("", "api", "api:2", c, "", "dc1", "s2")
+	d.region = "eu"
+	buf, err := d.renderJob("api.nomad", []byte(`job "api" {
+  datacenters = ["[[ .Dc ]]"]
+  region = "[[ .Region ]]"
+  meta { domain = "[[ .Vars.domain ]]" log = "[[ .Vars.log ]]" count = "[[ .Config.Count ]]" }
+  template { data = "{{ key \"app/config\" }}" }
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `job "api" {
+  datacenters = ["dc1"]
+  region = "eu"
+  meta { domain = "example.com" log = "debug" count = "2" }
+  template { data = "{{ key \"app/config\" }}" }
+}`, string(buf))
+
+	_, err = d.renderJob("api.nomad", []byte(`[[ .Vars.missing ]]`))
+	assert.Error(t, err)
+}
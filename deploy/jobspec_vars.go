@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/printer"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/jobspec"
+)
+
+// prefix of environment variables setting job spec variables, same as in Nomad
+const jobVarEnvPrefix = "NOMAD_VAR_"
+
+// jobVarRx matches variable reference in job spec, like ${var.image}
+var jobVarRx = regexp.MustCompile(`\$\{\s*var\.([A-Za-z0-9_-]+)\s*\}`)
+
+// jobVariable is variable block in job spec
+type jobVariable struct {
+	Type        string      `hcl:"type"`
+	Description string      `hcl:"description"`
+	Default     interface{} `hcl:"default"`
+}
+
+// parseJob parses Nomad job spec file content with variable blocks.
+// Job spec is HCL1, parsed by the Nomad 0.8 jobspec parser, it is not HCL2.
+// Variables are only substituted in ${var.name} string references,
+// HCL2 expressions, functions, locals and dynamic blocks are not supported.
+// Variables are set from defaults, NOMAD_VAR_ environment variables,
+// var files and vars (name=value), later ones overriding previous.
+func parseJob(fn string, buf []byte, vars, varFiles []string) (*api.Job, error) {
+	src, err := applyJobVars(buf, vars, varFiles)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return jobspec.Parse(bytes.NewReader(src))
+}
+
+// applyJobVars removes variable blocks from job spec
+// and replaces variable references with values
+func applyJobVars(buf []byte, vars, varFiles []string) ([]byte, error) {
+	f, err := hcl.ParseBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := f.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("job spec root should be an object")
+	}
+	values := make(map[string]string)
+	declared := make(map[string]bool)
+	job := &ast.ObjectList{}
+	if err := hcl2Blocks(list); err != nil {
+		return nil, err
+	}
+	for _, item := range list.Items {
+		if len(item.Keys) == 0 || item.Keys[0].Token.Value() != "variable" {
+			job.Add(item)
+			continue
+		}
+		if len(item.Keys) != 2 {
+			return nil, fmt.Errorf("variable block should have a name")
+		}
+		name := item.Keys[1].Token.Value().(string)
+		var v jobVariable
+		if err := hcl.DecodeObject(&v, item.Val); err != nil {
+			return nil, fmt.Errorf("variable %s: %v", name, err)
+		}
+		declared[name] = true
+		if v.Default != nil {
+			values[name] = fmt.Sprint(v.Default)
+		}
+	}
+	if len(declared) == 0 {
+		return buf, nil
+	}
+	if err := setJobVars(values, vars, varFiles); err != nil {
+		return nil, err
+	}
+	f.Node = job
+	var out bytes.Buffer
+	if err := printer.Fprint(&out, f); err != nil {
+		return nil, err
+	}
+	var missing []string
+	src := jobVarRx.ReplaceAllStringFunc(out.String(), func(ref string) string {
+		name := jobVarRx.FindStringSubmatch(ref)[1]
+		v, ok := values[name]
+		if !ok || !declared[name] {
+			missing = append(missing, name)
+			return ref
+		}
+		q := strconv.Quote(v)
+		return q[1 : len(q)-1]
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("variables not set: %s", strings.Join(missing, ", "))
+	}
+	return []byte(src), nil
+}
+
+// hcl2Blocks fails on HCL2 only blocks, which would be silently
+// accepted by the HCL1 parser as unknown job spec keys
+func hcl2Blocks(list *ast.ObjectList) error {
+	var err error
+	ast.Walk(list, func(n ast.Node) (ast.Node, bool) {
+		item, ok := n.(*ast.ObjectItem)
+		if !ok || len(item.Keys) == 0 || err != nil {
+			return n, err == nil
+		}
+		if key := item.Keys[0].Token.Value(); key == "locals" || key == "dynamic" {
+			err = fmt.Errorf("%s block at %s requires HCL2 job spec, only variable blocks are supported", key, item.Pos())
+		}
+		return n, err == nil
+	})
+	return err
+}
+
+// setJobVars sets variable values from environment, var files and name=value pairs
+func setJobVars(values map[string]string, vars, varFiles []string) error {
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, jobVarEnvPrefix) {
+			kv := strings.SplitN(strings.TrimPrefix(e, jobVarEnvPrefix), "=", 2)
+			values[kv[0]] = kv[1]
+		}
+	}
+	for _, fn := range varFiles {
+		buf, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		m := make(map[string]interface{})
+		if err := hcl.Unmarshal(buf, &m); err != nil {
+			return fmt.Errorf("%s: %v", fn, err)
+		}
+		for k, v := range m {
+			values[k] = fmt.Sprint(v)
+		}
+	}
+	for _, v := range vars {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid var %q, expected name=value", v)
+		}
+		values[kv[0]] = kv[1]
+	}
+	return nil
+}
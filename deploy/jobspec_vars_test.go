@@ -0,0 +1,70 @@
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/nomad/jobspec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyJobVars(t *testing.T) {
+	src := []byte(`
+variable "dc" {
+  default = "dc1"
+}
+variable "count" {
+  type = "number"
+  default = 2
+}
+variable "tag" {}
+
+job "api" {
+  datacenters = ["${var.dc}"]
+  group "api" {
+    count = "${var.count}"
+    task "api" {
+      driver = "docker"
+      config {
+        image = "api:${var.tag}"
+      }
+    }
+  }
+}
+`)
+	_, err := applyJobVars(src, nil, nil)
+	assert.Error(t, err)
+
+	fn, err := ioutil.TempFile("", "vars")
+	assert.NoError(t, err)
+	defer os.Remove(fn.Name())
+	fn.WriteString(`tag = "1"` + "\n" + `dc = "dc2"`)
+	fn.Close()
+	os.Setenv(jobVarEnvPrefix+"count", "3")
+	defer os.Unsetenv(jobVarEnvPrefix + "count")
+
+	buf, err := applyJobVars(src, []string{"tag=2"}, []string{fn.Name()})
+	assert.NoError(t, err)
+	job, err := jobspec.Parse(bytes.NewReader(buf))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dc2"}, job.Datacenters)
+	assert.Equal(t, 3, *job.TaskGroups[0].Count)
+	assert.Equal(t, "api:2", job.TaskGroups[0].Tasks[0].Config["image"])
+
+	// job without variables is unchanged
+	buf, err = applyJobVars([]byte(`job "api" {}`), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `job "api" {}`, string(buf))
+
+	// HCL2 blocks are rejected
+	_, err = applyJobVars([]byte(`variable "tag" {}
+job "api" {
+  group "api" {
+    dynamic "task" {}
+  }
+}`), nil, nil)
+	assert.Contains(t, fmt.Sprint(err), "dynamic block")
+}
@@ -0,0 +1,151 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/minus5/svckit/log"
+)
+
+// ServiceKVPrefix is Consul KV prefix of the services runtime configuration.
+// Keys of the service are under service/<service>/, like service/backend_api/feature_x,
+// and can be used in service env_kv config.
+const ServiceKVPrefix = "service"
+
+// serviceKey returns service and Consul KV key of the <service>/<key> path.
+// Key can be omitted in tree path.
+func serviceKey(path string, tree bool) (string, string, error) {
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if parts[0] == "" || (!tree && len(parts) < 2) {
+		return "", "", fmt.Errorf("invalid key %s, expecting <service>/<key>", path)
+	}
+	return parts[0], ServiceKVPrefix + "/" + path, nil
+}
+
+func kvClient(o Options) (*capi.KV, *capi.QueryOptions, error) {
+	cli, err := capi.NewClient(&capi.Config{Address: o.Consul})
+	if err != nil {
+		return nil, nil, WithExitCode(ExitConnection, err)
+	}
+	return cli.KV(), &capi.QueryOptions{Datacenter: o.Dc}, nil
+}
+
+// KVGet writes value of the service key to stdout
+func KVGet(ctx context.Context, o Options, path string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	_, key, err := serviceKey(path, false)
+	if err != nil {
+		return done(WithExitCode(ExitValidation, err))
+	}
+	kv, q, err := kvClient(o)
+	if err != nil {
+		return done(err)
+	}
+	p, _, err := kv.Get(key, q.WithContext(ctx))
+	if err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	if p == nil {
+		return done(WithExitCode(ExitValidation, fmt.Errorf("key %s not found", key)))
+	}
+	os.Stdout.Write(p.Value)
+	return nil
+}
+
+// KVTree lists service keys under path with their values
+func KVTree(ctx context.Context, o Options, path string) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
+	l := newTerminalLogger()
+	defer l.Close()
+	service, prefix, err := serviceKey(path, true)
+	if err != nil {
+		return done(WithExitCode(ExitValidation, err))
+	}
+	if strings.TrimSuffix(prefix, "/") == ServiceKVPrefix+"/"+service {
+		// don't match services with the same name prefix
+		prefix += "/"
+	}
+	kv, q, err := kvClient(o)
+	if err != nil {
+		return done(err)
+	}
+	ps, _, err := kv.List(prefix, q.WithContext(ctx))
+	if err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	m := make(map[string]string)
+	for _, p := range ps {
+		m[strings.TrimPrefix(p.Key, ServiceKVPrefix+"/")] = string(p.Value)
+	}
+	err = render(o.Output, m, func(w io.Writer) {
+		for _, p := range ps {
+			fmt.Fprintf(w, "%-50s %s\n", strings.TrimPrefix(p.Key, ServiceKVPrefix+"/"), kvValue(p.Value))
+		}
+	})
+	if err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// kvValue is value shown in tree, the first line of it
+func kvValue(v []byte) string {
+	s := string(v)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + faint(" ...")
+	}
+	return s
+}
+
+// KVPut sets value of the service key and records it in the deployment audit log
+func KVPut(ctx context.Context, o Options, path string, value []byte, reason string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	service, key, err := serviceKey(path, false)
+	if err != nil {
+		return done(WithExitCode(ExitValidation, err))
+	}
+	if o.Deployment == "" {
+		return done(WithExitCode(ExitValidation, errors.New("deployment is required for audit log")))
+	}
+	o.Service = service
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.loadDepConfig,
+		func() error { return w.kvPut(ctx, o, key, value, reason) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) kvPut(ctx context.Context, o Options, key string, value []byte, reason string) error {
+	kv, q, err := kvClient(o)
+	if err != nil {
+		return err
+	}
+	t := time.Now()
+	_, err = kv.Put(&capi.KVPair{Key: key, Value: value}, (&capi.WriteOptions{Datacenter: q.Datacenter}).WithContext(ctx))
+	if err != nil {
+		err = WithExitCode(ExitConnection, err)
+	}
+	// key is recorded with reason, kv actions have no image
+	if reason != "" {
+		reason = ": " + reason
+	}
+	w.auditAction("kv put", key+reason, &Deployer{dc: w.dc}, t, err)
+	if err != nil {
+		return err
+	}
+	log.S("key", key).I("bytes", len(value)).Info("kv put")
+	return nil
+}
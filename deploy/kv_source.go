@@ -0,0 +1,117 @@
+package deploy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+const (
+	kvScheme     = "consul://"
+	kvCacheDir   = "~/.pitwall/kv"
+	kvIndexFile  = ".kv_index"
+	kvFolderMark = "/"
+)
+
+// kvSource is deployment repository layout stored in Consul KV under prefix.
+// Keys are cached to the local directory which is used as repository root.
+type kvSource struct {
+	consul string
+	prefix string
+	root   string
+}
+
+// newKVSource returns KV source for config like consul://deploy/pg1, nil for other configs
+func newKVSource(consul, config string) *kvSource {
+	if !strings.HasPrefix(config, kvScheme) {
+		return nil
+	}
+	prefix := strings.Trim(strings.TrimPrefix(config, kvScheme), "/")
+	return &kvSource{
+		consul: consul,
+		prefix: prefix,
+		root:   filepath.Join(env.ExpandPath(kvCacheDir), prefix),
+	}
+}
+
+// configRoot returns deployment repository root, from KV cache if config source is Consul
+func configRoot(o Options) (string, error) {
+	kv := newKVSource(o.Consul, o.Config)
+	if kv == nil {
+		if o.Config != "" {
+			return "", fmt.Errorf("unsupported config source %s", o.Config)
+		}
+		return env.ExpandPath(o.Path), nil
+	}
+	return kv.root, kv.fetch()
+}
+
+// fetch refreshes local cache from KV.
+// Uses cache if Consul is unavailable.
+func (s *kvSource) fetch() error {
+	cli, err := capi.NewClient(&capi.Config{Address: s.consul})
+	if err != nil {
+		return err
+	}
+	pairs, meta, err := cli.KV().List(s.prefix+"/", nil)
+	if err != nil {
+		if _, serr := os.Stat(s.root); serr == nil {
+			log.S("prefix", s.prefix).S("cache", s.root).Error(err)
+			return nil
+		}
+		return err
+	}
+	if len(pairs) == 0 {
+		return fmt.Errorf("no config found in Consul KV under %s", s.prefix)
+	}
+	index := strconv.FormatUint(meta.LastIndex, 10)
+	if buf, err := ioutil.ReadFile(filepath.Join(s.root, kvIndexFile)); err == nil && string(buf) == index {
+		return nil
+	}
+	if err := os.RemoveAll(s.root); err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		if strings.HasSuffix(p.Key, kvFolderMark) {
+			continue
+		}
+		fn := filepath.Join(s.root, strings.TrimPrefix(p.Key, s.prefix+"/"))
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fn, p.Value, 0644); err != nil {
+			return err
+		}
+	}
+	log.S("prefix", s.prefix).I("keys", len(pairs)).Debug("config fetched from Consul KV")
+	return ioutil.WriteFile(filepath.Join(s.root, kvIndexFile), []byte(index), 0644)
+}
+
+// put writes local cache file to KV
+func (s *kvSource) put(fn string) error {
+	rel, err := filepath.Rel(s.root, fn)
+	if err != nil {
+		return err
+	}
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	cli, err := capi.NewClient(&capi.Config{Address: s.consul})
+	if err != nil {
+		return err
+	}
+	key := s.prefix + "/" + filepath.ToSlash(rel)
+	if _, err := cli.KV().Put(&capi.KVPair{Key: key, Value: buf}, nil); err != nil {
+		return err
+	}
+	log.S("key", key).Info("config saved to Consul KV")
+	return nil
+}
@@ -0,0 +1,52 @@
+package deploy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKVSource(t *testing.T) {
+	kv := map[string][]byte{
+		"deploy/pg1/deployments/test/config.yml": []byte("datacenters: {}\n"),
+		"deploy/pg1/nomad/service/api.nomad":     []byte(`job "api" {}`),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path[len("/v1/kv/"):]
+		if r.Method == http.MethodPut {
+			kv[key], _ = ioutil.ReadAll(r.Body)
+			w.Write([]byte("true"))
+			return
+		}
+		var pairs []map[string]interface{}
+		for k, v := range kv {
+			pairs = append(pairs, map[string]interface{}{"Key": k, "Value": v})
+		}
+		w.Header().Set("X-Consul-Index", "7")
+		json.NewEncoder(w).Encode(pairs)
+	}))
+	defer srv.Close()
+
+	root, err := ioutil.TempDir("", "kv")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	s := newKVSource(srv.URL, "consul://deploy/pg1/")
+	assert.Equal(t, "deploy/pg1", s.prefix)
+	s.root = root
+	assert.NoError(t, s.fetch())
+	buf, err := ioutil.ReadFile(root + "/nomad/service/api.nomad")
+	assert.NoError(t, err)
+	assert.Equal(t, `job "api" {}`, string(buf))
+
+	fn := root + "/deployments/test/config.yml"
+	assert.NoError(t, ioutil.WriteFile(fn, []byte("federated_dcs: pg1\n"), 0644))
+	assert.NoError(t, s.put(fn))
+	assert.Equal(t, "federated_dcs: pg1\n", string(kv["deploy/pg1/deployments/test/config.yml"]))
+
+	assert.Nil(t, newKVSource("", "/some/path"))
+}
@@ -0,0 +1,23 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceKey(t *testing.T) {
+	service, key, err := serviceKey("backend_api/features/x", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "backend_api", service)
+	assert.Equal(t, "service/backend_api/features/x", key)
+
+	_, key, err = serviceKey("backend_api/", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "service/backend_api", key)
+
+	_, _, err = serviceKey("backend_api", false)
+	assert.Error(t, err)
+	_, _, err = serviceKey("/", true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Lint validates config.yml of the deployment, or of all deployments if
+// Deployment option is empty. Returns error if any problem is found.
+func Lint(o Options) error {
+	root, err := configRoot(o)
+	if err != nil {
+		return err
+	}
+	deployments := []string{o.Deployment}
+	if o.Deployment == "" {
+		fns, _ := filepath.Glob(filepath.Join(root, "deployments", "*", "config.yml"))
+		deployments = deployments[:0]
+		for _, fn := range fns {
+			deployments = append(deployments, filepath.Base(filepath.Dir(fn)))
+		}
+	}
+	problems := 0
+	for _, dep := range deployments {
+		errs := lintDeployment(root, dep)
+		for _, err := range errs {
+			fmt.Printf("%s %s\n", warn(dep), err)
+		}
+		if len(errs) == 0 {
+			fmt.Printf("%s %s\n", dep, success("ok"))
+		}
+		problems += len(errs)
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problems", problems)
+	}
+	return nil
+}
+
+// lintDeployment finds problems in deployment config
+func lintDeployment(root, deployment string) []error {
+	c := &DeploymentConfig{root: root, deployment: deployment}
+	data, err := readConfigFile(c.FileName())
+	if err != nil {
+		return []error{err}
+	}
+	// strict unmarshal fails on unknown keys, wrong types and duplicate keys
+	if err := yaml.UnmarshalStrict([]byte(interpolate(string(data))), c); err != nil {
+		return []error{err}
+	}
+	c.applyDefaults()
+
+	var errs []error
+	if c.Timeout != "" {
+		if _, err := time.ParseDuration(c.Timeout); err != nil {
+			errs = append(errs, fmt.Errorf("invalid timeout %s", c.Timeout))
+		}
+	}
+	all := make(map[string]bool)
+	for _, dc := range c.Datacenters {
+		for name := range dc.Services {
+			all[name] = true
+		}
+	}
+	var dcs []string
+	for dc := range c.Datacenters {
+		dcs = append(dcs, dc)
+	}
+	sort.Strings(dcs)
+	for _, dc := range dcs {
+		var names []string
+		for name := range c.Datacenters[dc].Services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			s := c.Datacenters[dc].Services[name]
+			if s == nil {
+				errs = append(errs, fmt.Errorf("%s/%s: empty service config", dc, name))
+				continue
+			}
+			for _, err := range lintService(root, name, s, all) {
+				errs = append(errs, fmt.Errorf("%s/%s: %v", dc, name, err))
+			}
+		}
+	}
+	return errs
+}
+
+func lintService(root, name string, s *ServiceConfig, services map[string]bool) []error {
+	var errs []error
+	if !nomadFileExists(root, name) {
+		errs = append(errs, fmt.Errorf("nomad file not found"))
+	}
+	if s.Image == "" {
+		errs = append(errs, fmt.Errorf("image not set"))
+	}
+	for _, d := range s.DependsOn {
+		if !services[d] {
+			errs = append(errs, fmt.Errorf("depends on unknown service %s", d))
+		}
+	}
+	if st := s.SmokeTest; st != nil {
+		if st.URL == "" {
+			errs = append(errs, fmt.Errorf("smoke test url not set"))
+		}
+		if _, err := time.ParseDuration(st.Timeout); st.Timeout != "" && err != nil {
+			errs = append(errs, fmt.Errorf("invalid smoke test timeout %s", st.Timeout))
+		}
+	}
+	if c := s.DNS; c != nil {
+		if c.Zone == "" || c.Name == "" {
+			errs = append(errs, fmt.Errorf("dns zone or name not set"))
+		}
+		if _, ok := DNSProviders[c.provider()]; !ok {
+			errs = append(errs, fmt.Errorf("unknown dns provider %s", c.provider()))
+		}
+	}
+	for k, v := range s.Environment {
+		if _, _, err := parseVaultRef(v); isVaultRef(v) && err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %v", k, err))
+		}
+	}
+	for k, c := range s.Constraints {
+		if c == nil || c.Attribute == "" {
+			errs = append(errs, fmt.Errorf("constraint %s attribute not set", k))
+		}
+	}
+	return errs
+}
+
+// nomadFileExists checks for service .nomad file in any of the job type directories
+func nomadFileExists(root, service string) bool {
+	for _, dir := range []string{"service", "system", "batch"} {
+		if _, err := os.Stat(fmt.Sprintf("%s/nomad/%s/%s.nomad", root, dir, service)); err == nil {
+			return true
+		}
+	}
+	return false
+}
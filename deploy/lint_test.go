@@ -0,0 +1,54 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintDeployment(t *testing.T) {
+	root, err := ioutil.TempDir("", "lint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	write := func(fn, content string) {
+		fn = filepath.Join(root, fn)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fn), 0755))
+		assert.NoError(t, ioutil.WriteFile(fn, []byte(content), 0644))
+	}
+	write("nomad/service/api.nomad", "")
+	write("deployments/ok/config.yml", `
+datacenters:
+    dc1:
+        services:
+            api:
+                image: api_image
+`)
+	write("deployments/bad/config.yml", `
+datacenters:
+    dc1:
+        services:
+            api:
+                image: api_image
+                depends_on: [db]
+            worker:
+                image: worker_image
+`)
+	write("deployments/unknown/config.yml", `
+datacenters:
+    dc1:
+        services:
+            api:
+                image: api_image
+                coutn: 2
+`)
+
+	assert.Len(t, lintDeployment(root, "ok"), 0)
+	errs := lintDeployment(root, "bad")
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "dc1/api: depends on unknown service db")
+	assert.EqualError(t, errs[1], "dc1/worker: nomad file not found")
+	assert.Len(t, lintDeployment(root, "unknown"), 1)
+}
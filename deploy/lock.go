@@ -0,0 +1,61 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+// lockPrefix is Consul KV prefix of the deploy locks
+const lockPrefix = "pitwall/lock"
+
+// lockKey is Consul KV key of the deploy lock for service in datacenter
+func (d *Deployer) lockKey() string {
+	return fmt.Sprintf("%s/%s/%s/%s", lockPrefix, d.deployment, d.dc, d.service)
+}
+
+// lockService acquires Consul lock for the service in datacenter,
+// so concurrent deploys of the same service fail instead of racing.
+// Lock is released by unlock.
+func (d *Deployer) lockService(_ context.Context) error {
+	if d.noLock || d.consul == "" {
+		return nil
+	}
+	cli, err := capi.NewClient(&capi.Config{Address: d.consul})
+	if err != nil {
+		return err
+	}
+	owner := fmt.Sprintf("%s %s", currentUser(), time.Now().Format(time.RFC3339))
+	l, err := cli.LockOpts(&capi.LockOptions{
+		Key:          d.lockKey(),
+		Value:        []byte(owner),
+		SessionName:  "pitwall deploy " + d.service,
+		SessionTTL:   "30s",
+		LockTryOnce:  true,
+		LockWaitTime: time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	lost, err := l.Lock(nil)
+	if err != nil {
+		return err
+	}
+	if lost == nil {
+		holder := ""
+		if kv, _, err := cli.KV().Get(d.lockKey(), nil); err == nil && kv != nil {
+			holder = string(kv.Value)
+		}
+		return fmt.Errorf("service %s in %s is locked by another deploy %s", d.service, d.dc, holder)
+	}
+	d.unlock = func() {
+		if err := l.Unlock(); err != nil {
+			d.log().S("key", d.lockKey()).Error(err)
+		}
+		l.Destroy()
+	}
+	d.log().S("key", d.lockKey()).Debug("deploy locked")
+	return nil
+}
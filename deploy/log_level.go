@@ -0,0 +1,55 @@
+package deploy
+
+import (
+	"fmt"
+	"io"
+)
+
+// logLevels are svckit log levels, from the most verbose
+var logLevels = []string{"debug", "info", "notice", "event", "error", "fatal"}
+
+// LogLevel is the least severe level of log lines shown in terminal
+var LogLevel = "info"
+
+// LogJSON shows log lines in terminal as json, for machine consumption
+var LogJSON bool
+
+// ValidLogLevel checks if level is one of svckit log levels
+func ValidLogLevel(level string) error {
+	if levelIndex(level) < 0 {
+		return fmt.Errorf("unknown log level %s, expecting one of %v", level, logLevels)
+	}
+	return nil
+}
+
+func levelIndex(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// logEnabled is level shown with current LogLevel, unknown levels are always shown
+func logEnabled(level string) bool {
+	i := levelIndex(level)
+	return i < 0 || i >= levelIndex(LogLevel)
+}
+
+// LogWriter writes svckit log lines to terminal in the same way
+// as deploy commands do, used as log output by other commands.
+type LogWriter struct {
+	out io.Writer
+}
+
+// NewLogWriter creates log writer to out
+func NewLogWriter(out io.Writer) *LogWriter {
+	return &LogWriter{out: out}
+}
+
+func (l *LogWriter) Write(p []byte) (int, error) {
+	termMu.Lock()
+	defer termMu.Unlock()
+	return writeLog(l.out, p)
+}
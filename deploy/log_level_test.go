@@ -0,0 +1,33 @@
+package deploy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogWriter(t *testing.T) {
+	defer func() { LogLevel, LogJSON = "info", false }()
+	debug := []byte(`{"level":"debug","msg":"checking status","allocs":2}` + "\n")
+	errLine := []byte(`{"level":"error","msg":"failed"}` + "\n")
+
+	var buf bytes.Buffer
+	w := NewLogWriter(&buf)
+	w.Write(debug)
+	assert.Equal(t, "", buf.String())
+
+	LogLevel = "debug"
+	w.Write(debug)
+	assert.Contains(t, buf.String(), "checking status")
+	assert.Contains(t, buf.String(), "allocs: 2")
+
+	buf.Reset()
+	LogLevel, LogJSON = "error", true
+	w.Write(debug)
+	w.Write(errLine)
+	assert.Equal(t, string(errLine), buf.String())
+
+	assert.Nil(t, ValidLogLevel("notice"))
+	assert.Error(t, ValidLogLevel("trace"))
+}
@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// logsTailOffset is number of bytes from the end of the log shown before following
+const logsTailOffset = 4096
+
+// Logs writes stdout, or stderr, of the service task in Dc to the stdout.
+// Allocation is selected if there are more than one running.
+// In follow mode only the end of the log is shown and new output streamed until interrupted.
+func Logs(ctx context.Context, o Options, task string, stderr, follow bool) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	if err := d.connect(ctx); err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	if task == "" {
+		task = d.service
+	}
+	if err := d.Logs(ctx, os.Stdout, task, stderr, follow); err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// Logs streams task log of the selected service allocation to out.
+func (d *Deployer) Logs(ctx context.Context, out io.Writer, task string, stderr, follow bool) error {
+	stub, err := d.selectAlloc()
+	if err != nil {
+		return err
+	}
+	alloc, _, err := d.cli.Allocations().Info(stub.ID, nil)
+	if err != nil {
+		return err
+	}
+	logType, origin, offset := logsQuery(stderr, follow)
+	d.log().S("alloc", stub.ID[:8]).S("task", task).S("type", logType).Info("logs")
+
+	cancel := make(chan struct{})
+	frames, errCh := d.cli.AllocFS().Logs(alloc, follow, task, logType, origin, offset, cancel, nil)
+	r := api.NewFrameReader(frames, errCh, cancel)
+	defer r.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.Close()
+		case <-cancel:
+		}
+	}()
+	if _, err := io.Copy(out, r); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// logsQuery returns log type, origin and offset of the logs request
+func logsQuery(stderr, follow bool) (string, string, int64) {
+	logType := "stdout"
+	if stderr {
+		logType = "stderr"
+	}
+	if follow {
+		return logType, "end", logsTailOffset
+	}
+	return logType, "start", 0
+}
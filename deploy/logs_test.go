@@ -0,0 +1,19 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogsQuery(t *testing.T) {
+	typ, origin, offset := logsQuery(false, false)
+	assert.Equal(t, "stdout", typ)
+	assert.Equal(t, "start", origin)
+	assert.Equal(t, int64(0), offset)
+
+	typ, origin, offset = logsQuery(true, true)
+	assert.Equal(t, "stderr", typ)
+	assert.Equal(t, "end", origin)
+	assert.Equal(t, int64(logsTailOffset), offset)
+}
@@ -1,10 +1,14 @@
 package deploy
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/minus5/svckit/dcy"
@@ -16,61 +20,231 @@ import (
 // prikazi koji je trenutni image
 // povezati s deploy-erom
 
+// DefaultRepo is url of the deployment git repository
+const DefaultRepo = "git@github.com:minus5/infrastructure.git"
+
+// Options for deployment process
+type Options struct {
+	Deployment   string
+	Service      string
+	Services     []string // deploy multiple services, dependencies first
+	Repo         string   // deployment git repository url
+	Src          string   // service source repository path, for building and finding image
+	ImageFromGit bool     // deploy image built from the source repository head
+	Path         string
+	Registry     string
+	Image        string
+	Consul       string
+	Dc           string // limit to single datacenter
+	NoGit        bool
+	DryRun       bool
+	Canary       bool          // stop when canaries are healthy, wait for promote
+	Parallel     bool          // deploy to all datacenters concurrently
+	WaitTime     time.Duration // max duration of Nomad blocking queries
+	AutoRevert   bool          // revert to previous job version on failed deployment
+	Timeout      time.Duration // max duration of deployment in each datacenter
+	Nomad        NomadConfig   // overrides datacenter Nomad connection config
+	Namespace    string        // overrides service and datacenter Nomad namespace
+	Yes          bool          // don't ask for confirmation of the job plan
+	Output       string        // json or yaml writes deploy report to stdout, logs to stderr
+	NoLock       bool          // don't acquire Consul deploy lock
+	Env          string        // environment overlay, like prod for config.prod.yml
+	Vars         []string      // job spec variables, name=value
+	VarFiles     []string      // job spec variable files
+	Config       string        // remote config source, like consul://deploy/pg1, overrides Path
+
+	// for embedding deployments in other tools and tests
+	ConfigSource ConfigSource // loads and saves deployment config instead of git repository in Path
+	NomadClient  NomadClient  // used instead of connecting to Nomad of each datacenter
+	JobLoader    JobLoader    // loads service jobs instead of .nomad files
+	AuditStore   AuditStore   // records audit log instead of Consul KV or audit file
+}
+
+func newWorker(o Options) *Worker {
+	w := &Worker{
+		service:      o.Service,
+		root:         env.ExpandPath(o.Path),
+		registryURL:  o.Registry,
+		deployment:   o.Deployment,
+		image:        o.Image,
+		noGit:        o.NoGit,
+		consul:       o.Consul,
+		dc:           o.Dc,
+		dryRun:       o.DryRun,
+		canary:       o.Canary,
+		parallel:     o.Parallel,
+		waitTime:     o.WaitTime,
+		autoRevert:   o.AutoRevert,
+		timeout:      o.Timeout,
+		nomad:        o.Nomad,
+		namespace:    o.Namespace,
+		yes:          o.Yes,
+		noLock:       o.NoLock,
+		gitURL:       o.Repo,
+		src:          o.Src,
+		imageFromGit: o.ImageFromGit,
+		env:          o.Env,
+		vars:         o.Vars,
+		varFiles:     o.VarFiles,
+		nomadClient:  o.NomadClient,
+		jobLoader:    o.JobLoader,
+		auditLog:     o.AuditStore,
+	}
+	if o.ConfigSource != nil {
+		w.source = o.ConfigSource
+		w.noGit = true
+	}
+	if machineOutput(o.Output) {
+		w.report = newReport()
+		w.output = o.Output
+	}
+	if kv := newKVSource(o.Consul, o.Config); kv != nil {
+		w.kv = kv
+		w.root = kv.root
+		w.noGit = true
+		if err := kv.fetch(); err != nil {
+			log.Error(err)
+		}
+	}
+	return w
+}
+
 // Run deployment process
-func Run(deployment, service, path, registry, image string, noGit bool, consul string, dryRun bool) {
+// Canceling ctx stops waiting for the Nomad deployment to finish.
+func Run(ctx context.Context, o Options) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
 	l := newTerminalLogger()
 	defer l.Close()
-	w := Worker{
-		service:     service,
-		root:        env.ExpandPath(path),
-		registryURL: registry,
-		deployment:  deployment,
-		image:       image,
-		noGit:       noGit,
-		consul:      consul,
-		dryRun:      dryRun,
-	}
-
-	if err := w.Go(); err != nil {
+	if len(o.Services) > 0 {
+		return done(runServices(ctx, o))
+	}
+	return done(runWorker(ctx, newWorker(o)))
+}
+
+// runWorker runs deployment process and writes report if requested
+func runWorker(ctx context.Context, w *Worker) error {
+	err := w.Go(ctx)
+	w.pushMetrics(err)
+	if w.report != nil {
+		w.report.write(w, err)
+	}
+	return err
+}
+
+// done shows result of the command, returns err
+func done(err error) error {
+	if err != nil {
 		log.Error(err)
 	} else {
-		fmt.Printf("%s %s\n", promptui.IconGood, success("done"))
+		fmt.Fprintf(termOut, "%s %s\n", promptui.IconGood, success("done"))
 	}
+	return err
 }
 
 // Worker structure for deployment
 type Worker struct {
-	root        string
-	registryURL string
-	deployment  string
-	service     string
-	image       string
-	consul      string
-	consulDc    string
-	noGit       bool
-	dryRun      bool
+	root         string
+	registryURL  string
+	deployment   string
+	service      string
+	image        string
+	consul       string
+	consulDc     string
+	dc           string
+	noGit        bool
+	dryRun       bool
+	canary       bool
+	parallel     bool
+	yes          bool
+	noLock       bool
+	gitURL       string
+	digest       string
+	build        bool   // build and push image before deploy
+	imageFromGit bool   // find image of the source repository head
+	src          string // service source repository path
+	waitTime     time.Duration
+	autoRevert   bool
+	timeout      time.Duration
+	nomad        NomadConfig
+	namespace    string
+	env          string // environment overlay
+	vars         []string
+	varFiles     []string
+	kv           *kvSource    // config from Consul KV instead of git repository
+	source       ConfigSource // deployment config source instead of root
+	nomadClient  NomadClient  // Nomad client instead of connecting to datacenter Nomad
+	jobLoader    JobLoader    // job loader instead of .nomad files
+	auditLog     AuditStore   // audit store instead of Consul KV or audit file
 
 	depConfig     *DeploymentConfig
 	serviceConfig *ServiceConfig
 	repo          Repo
 	deployer      *Deployer
+	report        *Report
+	output        string         // report output format
+	steps         []stepDuration // durations of the deployment steps, for metrics
 }
 
 // Go starts deployment process
-func (w *Worker) Go() error {
+func (w *Worker) Go(ctx context.Context) error {
 	steps := []func() error{
-		w.pull,
-		w.selectService,
-		w.selectImage,
+		w.step("pull", w.pull),
+		w.step("select_service", w.selectService),
+		w.step("build_image", w.buildImage),
+		w.step("select_image", w.selectImage),
+		w.step("pin_image", w.pinImage),
+		w.step("verify_image", w.verifyImage),
 		//w.confirmSelection,
-		w.deploy,
-		w.pullChanges,
-		w.updateDepConfig,
-		w.push,
+		w.step("deploy", func() error { return w.deploy(ctx) }),
+		w.step("pull_changes", w.pullChanges),
+		w.step("update_config", w.updateDepConfig),
+		w.step("push", w.push),
 	}
 	return runSteps(steps)
 }
 
+// step records step duration and result in report and metrics and sets exit code of the step error
+func (w *Worker) step(name string, fn func() error) func() error {
+	return func() error {
+		t := time.Now()
+		err := WithExitCode(stepExitCodes[name], fn())
+		w.steps = append(w.steps, stepDuration{name: name, duration: time.Since(t)})
+		if w.report != nil {
+			w.report.addStep(name, time.Since(t), err)
+		}
+		return err
+	}
+}
+
+// goDeployer runs deployer with its hooks, records result in report and sends notifications
+func (w *Worker) goDeployer(ctx context.Context, d *Deployer) error {
+	if err := w.runPreHooks(d); err != nil {
+		return err
+	}
+	w.notify(NotifyStarted, d.dc, nil)
+	t := time.Now()
+	err := d.Go(ctx, w.dryRun)
+	if err == nil {
+		err = w.updateDNS(d)
+	}
+	w.runPostHooks(d, err)
+	w.audit(d, t, err)
+	w.pushDcMetrics(d, time.Since(t), err)
+	if w.report != nil {
+		w.report.addDc(d, time.Since(t), err)
+	}
+	if err != nil {
+		w.notify(NotifyFailed, d.dc, err)
+	} else {
+		w.notify(NotifySucceeded, d.dc, nil)
+		w.annotate(d.dc)
+		w.sentryRelease(t)
+	}
+	return err
+}
+
 func runSteps(steps []func() error) error {
 	for _, step := range steps {
 		if err := step(); err != nil {
@@ -80,35 +254,132 @@ func runSteps(steps []func() error) error {
 	return nil
 }
 
-func (w *Worker) deploy() error {
-	dcs := w.depConfig.FindDatacenters(w.service)
-	if len(dcs) == 0 {
-		log.Fatal(fmt.Errorf("datacenters for service %s not set", w.service))
+// runContextSteps runs steps until the first error or ctx cancellation
+func runContextSteps(ctx context.Context, steps []func(context.Context) error) error {
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := step(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) deploy(ctx context.Context) error {
+	dcs, err := w.datacenters()
+	if err != nil {
+		return err
+	}
+	if w.parallel {
+		return w.deployParallel(ctx, dcs)
 	}
 	for _, dc := range dcs {
 		log.Info("Deploying service %s to dacenter %s", w.service, dc)
-		// temporary fix until switch is made
-		nomadName := "nomad"
-		ndc := dc // datacenter used to query nomad from consul
-		if ndc == "js" {
-			ndc = "s2"
-			nomadName = "nomad-js"
-		}
-		address := w.getServiceAddressByTag("http", nomadName, ndc)
-		d := NewDeployer(w.root, w.service, w.image, w.depConfig, address, dc, w.deployment)
+		d := w.newDeployer(dc)
 		w.deployer = d
-		if err := d.Go(w.dryRun); err != nil {
+		if err := w.goDeployer(ctx, d); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (w *Worker) newDeployer(dc string) *Deployer {
+	address := ""
+	if w.nomadClient == nil {
+		address = w.nomadAddress(dc)
+	}
+	d := NewDeployer(w.root, w.service, w.image, w.depConfig, address, dc, w.deployment)
+	d.manualPromote = w.canary
+	d.autoRevert = w.autoRevert
+	d.timeout = w.timeout
+	d.nomad = w.nomad
+	d.namespace = w.namespace
+	d.confirm = !w.yes && w.report == nil // json and yaml output are non-interactive
+	d.consul = w.consul
+	d.noLock = w.noLock
+	d.digest = w.digest
+	d.vars = w.vars
+	d.varFiles = w.varFiles
+	d.cli = w.nomadClient
+	d.loader = w.jobLoader
+	if w.serviceConfig != nil {
+		d.src = w.sourceDir()
+	}
+	if d.timeout == 0 && w.depConfig != nil {
+		d.timeout = w.depConfig.DeployTimeout()
+	}
+	if w.waitTime > 0 {
+		d.waitTime = w.waitTime
+	}
+	return d
+}
+
+// deployParallel runs deployer for each datacenter concurrently
+// and shows result summary for each datacenter.
+func (w *Worker) deployParallel(ctx context.Context, dcs []string) error {
+	// find nomad addresses before starting deployers
+	deployers := make([]*Deployer, len(dcs))
+	for i, dc := range dcs {
+		deployers[i] = w.newDeployer(dc)
+	}
+
+	log.Info("Deploying service %s to dacenters %v", w.service, dcs)
+	errs := make([]error, len(dcs))
+	var wg sync.WaitGroup
+	for i, d := range deployers {
+		wg.Add(1)
+		go func(i int, d *Deployer) {
+			defer wg.Done()
+			errs[i] = w.goDeployer(ctx, d)
+		}(i, d)
+	}
+	wg.Wait()
+
+	failed := 0
+	var first error
+	for i, dc := range dcs {
+		if err := errs[i]; err != nil {
+			if failed == 0 {
+				first = err
+			}
+			failed++
+			fmt.Fprintf(termOut, "%s %-10s %s\n", promptui.IconBad, dc, warn(err.Error()))
+			continue
+		}
+		fmt.Fprintf(termOut, "%s %-10s %s\n", promptui.IconGood, dc, success("deployed"))
+	}
+	if failed > 0 {
+		return wrapExit(first, fmt.Errorf("deployment failed in %d of %d datacenters", failed, len(dcs)))
+	}
+	return nil
+}
+
+// datacenters of the service, or just the one selected by dc option
+func (w *Worker) datacenters() ([]string, error) {
+	if w.dc != "" {
+		if w.depConfig.FindForDc(w.service, w.dc) == nil {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("service %s not found in datacenter %s", w.service, w.dc))
+		}
+		return []string{w.dc}, nil
+	}
+	dcs := w.depConfig.FindDatacenters(w.service)
+	if len(dcs) == 0 {
+		return nil, WithExitCode(ExitValidation, fmt.Errorf("datacenters for service %s not set", w.service))
+	}
+	return dcs, nil
+}
+
 func (w *Worker) pull() error {
 	if w.noGit {
 		return nil
 	}
-	gitURL := "git@github.com:minus5/infrastructure.git"
+	gitURL := w.gitURL
+	if gitURL == "" {
+		gitURL = DefaultRepo
+	}
 	repo, err := NewRepo(w.root, gitURL)
 	if err != nil {
 		return err
@@ -118,6 +389,9 @@ func (w *Worker) pull() error {
 }
 
 func (w *Worker) pullChanges() error {
+	if w.kv != nil {
+		return w.kv.fetch()
+	}
 	if w.noGit {
 		return nil
 	}
@@ -125,20 +399,26 @@ func (w *Worker) pullChanges() error {
 }
 
 func (w *Worker) push() error {
+	return w.commit(fmt.Sprintf("deployed %s to %s", w.service, w.deployment))
+}
+
+func (w *Worker) commit(msg string) error {
+	if w.kv != nil && !w.dryRun {
+		return w.kv.put(w.depConfig.SavedFileName())
+	}
 	if w.noGit {
 		return nil
 	}
-	return w.repo.Commit(fmt.Sprintf("deployed %s to %s", w.service, w.deployment), w.depConfig.FileName())
+	return w.repo.Commit(msg, w.depConfig.SavedFileName())
 }
 
 func (w *Worker) selectService() error {
-	c, err := NewDeploymentConfig(w.root, w.deployment)
-	if err != nil {
+	if err := w.loadDepConfig(); err != nil {
 		return err
 	}
-	w.depConfig = c
+	c := w.depConfig
 	if w.service == "" {
-		s, err := c.Select()
+		s, err := c.SelectInDc(w.dc)
 		if err != nil {
 			return err
 		}
@@ -158,6 +438,9 @@ func (w *Worker) selectImage() error {
 		log.S("image", w.image).Info("image preselected with flag")
 		return nil
 	}
+	if w.imageFromGit {
+		return w.gitImage()
+	}
 
 	i, err := NewImage(w.registryURL, w.service, w.serviceConfig.Image)
 	if err != nil {
@@ -192,8 +475,33 @@ func (w *Worker) confirmSelection() error {
 	return nil
 }
 
+// pinImage replaces mutable image tag with digest,
+// so all datacenters run the same image
+func (w *Worker) pinImage() error {
+	image, err := pinImage(w.image)
+	if err != nil {
+		return err
+	}
+	if image != w.image {
+		log.S("tag", w.image).S("digest", image).Info("image pinned")
+		w.image = image
+	}
+	return nil
+}
+
+// verifyImage checks that image exists in registry before deploying it.
+// Image digest is recorded in job meta and audit log.
+func (w *Worker) verifyImage() error {
+	digest, err := imageDigest(w.image)
+	if err != nil {
+		return fmt.Errorf("image not found: %v", err)
+	}
+	w.digest = digest
+	return nil
+}
+
 func (w *Worker) updateDepConfig() error {
-	return w.depConfig.Save()
+	return w.configSource().Save(w.depConfig)
 }
 
 type terminalLogger struct {
@@ -218,22 +526,45 @@ var success = promptui.Styler(promptui.FGGreen)
 var warn = promptui.Styler(promptui.FGRed)
 var lastMsg = ""
 
+// guards terminal output, deployers can log concurrently
+var termMu sync.Mutex
+
+// terminal output, stderr when stdout is used for json report
+var termOut = os.Stdout
+
 func (l terminalLogger) Write(p []byte) (int, error) {
+	termMu.Lock()
+	defer termMu.Unlock()
+	l.f.Write(p)
+	return writeLog(termOut, p)
+}
+
+// writeLog writes svckit log line to terminal, formatted or as json
+// and filtered by LogLevel
+func writeLog(out io.Writer, p []byte) (int, error) {
 	var m map[string]interface{}
 	json.Unmarshal(p, &m)
-	switch m["level"] {
+	level, _ := m["level"].(string)
+	if !logEnabled(level) {
+		return len(p), nil
+	}
+	if LogJSON {
+		out.Write(p)
+		return len(p), nil
+	}
+	switch level {
 	case "error", "fatal":
 		if m := m["msg"].(string); m != lastMsg {
-			fmt.Printf("%s ", promptui.IconBad)
-			fmt.Printf("%s", warn(m))
+			fmt.Fprintf(out, "%s ", promptui.IconBad)
+			fmt.Fprintf(out, "%s", warn(m))
 			lastMsg = m
 		} else {
 			return len(p), nil
 		}
 	case "info":
-		fmt.Printf("%s", info(m["msg"]))
+		fmt.Fprintf(out, "%s", info(m["msg"]))
 	case "debug":
-		fmt.Printf("%s", faint(m["msg"]))
+		fmt.Fprintf(out, "%s", faint(m["msg"]))
 	}
 
 	var keys []string
@@ -246,10 +577,9 @@ func (l terminalLogger) Write(p []byte) (int, error) {
 	sort.Strings(keys)
 	for _, k := range keys {
 		v := m[k]
-		fmt.Printf(faint(fmt.Sprintf(" %s: %v", k, v)))
+		fmt.Fprint(out, faint(fmt.Sprintf(" %s: %v", k, v)))
 	}
-	fmt.Printf("\n")
-	l.f.Write(p)
+	fmt.Fprintf(out, "\n")
 	return len(p), nil
 }
 
@@ -262,6 +592,18 @@ func (l *terminalLogger) Close() {
 	l.f.Close()
 }
 
+// nomadAddress finds Nomad http address for datacenter in Consul
+func (w *Worker) nomadAddress(dc string) string {
+	// temporary fix until switch is made
+	nomadName := "nomad"
+	ndc := dc // datacenter used to query nomad from consul
+	if ndc == "js" {
+		ndc = "s2"
+		nomadName = "nomad-js"
+	}
+	return w.getServiceAddressByTag("http", nomadName, ndc)
+}
+
 func (w *Worker) getServiceAddressByTag(tag, name, dc string) string {
 	if err := dcy.ConnectTo(w.consul); err != nil {
 		log.Fatal(err)
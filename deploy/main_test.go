@@ -1,6 +1,9 @@
 package deploy
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,3 +19,89 @@ func TestTag(t *testing.T) {
 	assert.True(t, tag.created.IsZero())
 
 }
+
+func TestPinImage(t *testing.T) {
+	r, repo, tag := splitImage("registry.dev.minus5.hr:5000/backend_api:20160613151056.99a146a")
+	assert.Equal(t, "registry.dev.minus5.hr:5000", r)
+	assert.Equal(t, "backend_api", repo)
+	assert.Equal(t, "20160613151056.99a146a", tag)
+	r, repo, tag = splitImage("minus5/backend_api")
+	assert.Equal(t, "", r)
+	assert.Equal(t, "minus5/backend_api", repo)
+	assert.Equal(t, "", tag)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/backend_api/manifests/master", r.URL.Path)
+		w.Header().Set("Docker-Content-Digest", "sha256:abc")
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	image, err := pinImage(host + "/backend_api:master")
+	assert.NoError(t, err)
+	assert.Equal(t, host+"/backend_api@sha256:abc", image)
+
+	// immutable tags are not resolved
+	image, err = pinImage(host + "/backend_api:20160613151056.99a146a")
+	assert.NoError(t, err)
+	assert.Equal(t, host+"/backend_api:20160613151056.99a146a", image)
+}
+
+func TestFindGitTag(t *testing.T) {
+	ts := tags{
+		NewTag("20190410120000.1a2b3c4", false),
+		NewTag("20190409120000.99a146a.b8a1fbf", false),
+		NewTag("v1.2.0", false),
+	}
+	assert.Equal(t, "20190409120000.99a146a.b8a1fbf", findGitTag(ts, "b8a1fbf", ""))
+	assert.Equal(t, "20190410120000.1a2b3c4", findGitTag(ts, "1a2b3c4d", "v1.2.0"))
+	assert.Equal(t, "v1.2.0", findGitTag(ts, "0000000", "v1.2.0"))
+	assert.Equal(t, "", findGitTag(ts, "0000000", ""))
+}
+
+func TestImageSha(t *testing.T) {
+	assert.Equal(t, "99a146a", imageSha("registry.dev.minus5.hr/backend_api:20160613151056.99a146a.b8a1fbf"))
+	assert.Equal(t, "", imageSha("registry.dev.minus5.hr/backend_api:latest"))
+	assert.Equal(t, "", imageSha("registry.dev.minus5.hr/backend_api@sha256:abc"))
+}
+
+func TestGCCandidates(t *testing.T) {
+	ts := tags{
+		NewTag("20190413120000.aaaaaaa", false),
+		NewTag("20190412120000.bbbbbbb", false),
+		NewTag("20190411120000.ccccccc", false),
+		NewTag("20190410120000.ddddddd", false),
+		NewTag("latest", false),
+	}
+	c := gcCandidates(ts, map[string]bool{"20190411120000.ccccccc": true}, 2)
+	assert.Equal(t, map[string]bool{"20190410120000.ddddddd": true}, c)
+}
+
+func TestImageRef(t *testing.T) {
+	tag, digest := imageRef("registry.example.com:5000/api:20190413120000.aaaaaaa")
+	assert.Equal(t, "20190413120000.aaaaaaa", tag)
+	assert.Empty(t, digest)
+	tag, digest = imageRef("registry.example.com:5000/api@sha256:1a2b")
+	assert.Empty(t, tag)
+	assert.Equal(t, "sha256:1a2b", digest)
+	tag, digest = imageRef("api:latest@sha256:1a2b")
+	assert.Equal(t, "latest", tag)
+	assert.Equal(t, "sha256:1a2b", digest)
+}
+
+func TestPlatformImage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"mediaType":"application/vnd.docker.distribution.manifest.list.v2+json","manifests":[
+			{"digest":"sha256:amd","platform":{"architecture":"amd64","os":"linux"}},
+			{"digest":"sha256:arm","platform":{"architecture":"arm64","os":"linux"}}]}`))
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	image, err := platformImage(host+"/backend_api:20190410120000.1a2b3c4", "arm64")
+	assert.NoError(t, err)
+	assert.Equal(t, host+"/backend_api@sha256:arm", image)
+
+	_, err = platformImage(host+"/backend_api@sha256:list", "ppc64le")
+	assert.Error(t, err)
+}
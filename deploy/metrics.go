@@ -0,0 +1,162 @@
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/log"
+)
+
+const defaultMetricsJob = "pitwall"
+
+// MetricsConfig configures Prometheus Pushgateway where deploy metrics are pushed.
+// Deploy frequency is graphed with changes(pitwall_deploy_last_success_timestamp_seconds[1d])
+// and time to recovery from last failure and success timestamps.
+type MetricsConfig struct {
+	Pushgateway string `yaml:"pushgateway"`   // Pushgateway url, like http://pushgateway:9091
+	Job         string `yaml:"job,omitempty"` // job grouping label, default pitwall
+}
+
+func (c *MetricsConfig) job() string {
+	if c.Job == "" {
+		return defaultMetricsJob
+	}
+	return c.Job
+}
+
+type stepDuration struct {
+	name     string
+	duration time.Duration
+}
+
+// metric is sample in Prometheus text exposition format
+type metric struct {
+	name   string
+	help   string
+	labels [][2]string
+	value  float64
+}
+
+// writeMetrics writes metrics in Prometheus text format,
+// samples of the same metric have to be consecutive
+func writeMetrics(ms []metric) string {
+	var b strings.Builder
+	prev := ""
+	for _, m := range ms {
+		if m.name != prev {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+			prev = m.name
+		}
+		b.WriteString(m.name)
+		if len(m.labels) > 0 {
+			var ls []string
+			for _, l := range m.labels {
+				ls = append(ls, fmt.Sprintf("%s=%q", l[0], l[1]))
+			}
+			fmt.Fprintf(&b, "{%s}", strings.Join(ls, ","))
+		}
+		fmt.Fprintf(&b, " %g\n", m.value)
+	}
+	return b.String()
+}
+
+// pushMetrics pushes durations of the worker deployment steps
+func (w *Worker) pushMetrics(err error) {
+	c := w.metricsConfig()
+	if c == nil || len(w.steps) == 0 {
+		return
+	}
+	steps := append([]stepDuration{}, w.steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].name < steps[j].name })
+	var ms []metric
+	for _, s := range steps {
+		ms = append(ms, metric{
+			name:   "pitwall_deploy_step_duration_seconds",
+			help:   "Duration of the last deploy step.",
+			labels: [][2]string{{"step", s.name}},
+			value:  s.duration.Seconds(),
+		})
+	}
+	ms = append(ms, resultMetric(err))
+	if perr := c.push(w.metricsGroup(""), ms); perr != nil {
+		log.Errorf("unable to push metrics: %v", perr)
+	}
+}
+
+// pushDcMetrics pushes result of the deployment in datacenter
+func (w *Worker) pushDcMetrics(d *Deployer, du time.Duration, err error) {
+	c := w.metricsConfig()
+	if c == nil {
+		return
+	}
+	ms := []metric{{
+		name:  "pitwall_deploy_duration_seconds",
+		help:  "Duration of the last deploy in datacenter.",
+		value: du.Seconds(),
+	}}
+	if err == nil && d.timeToHealthy > 0 {
+		ms = append(ms, metric{
+			name:  "pitwall_deploy_time_to_healthy_seconds",
+			help:  "Time from job registration until all allocations are healthy.",
+			value: d.timeToHealthy.Seconds(),
+		})
+	}
+	ms = append(ms, resultMetric(err))
+	if perr := c.push(w.metricsGroup(d.dc), ms); perr != nil {
+		log.Errorf("unable to push metrics: %v", perr)
+	}
+}
+
+// resultMetric is timestamp of the last successful or failed deploy
+func resultMetric(err error) metric {
+	m := metric{
+		name:  "pitwall_deploy_last_success_timestamp_seconds",
+		help:  "Unix time of the last successful deploy.",
+		value: float64(time.Now().Unix()),
+	}
+	if err != nil {
+		m.name = "pitwall_deploy_last_failure_timestamp_seconds"
+		m.help = "Unix time of the last failed deploy."
+	}
+	return m
+}
+
+func (w *Worker) metricsConfig() *MetricsConfig {
+	if w.dryRun || w.depConfig == nil || w.depConfig.Metrics == nil || w.depConfig.Metrics.Pushgateway == "" {
+		return nil
+	}
+	return w.depConfig.Metrics
+}
+
+// metricsGroup is Pushgateway grouping key of the service deployment, with dc if set
+func (w *Worker) metricsGroup(dc string) [][2]string {
+	g := [][2]string{{"deployment", w.deployment}, {"service", w.service}}
+	if dc != "" {
+		g = append(g, [2]string{"dc", dc})
+	}
+	return g
+}
+
+var metricsClient = &http.Client{Timeout: 5 * time.Second}
+
+// push replaces metrics with the same names in the group
+func (c *MetricsConfig) push(group [][2]string, ms []metric) error {
+	u := strings.TrimSuffix(c.Pushgateway, "/") + "/metrics/job/" + url.PathEscape(c.job())
+	for _, g := range group {
+		u += "/" + g[0] + "/" + url.PathEscape(g[1])
+	}
+	rsp, err := metricsClient.Post(u, "text/plain; version=0.0.4", bytes.NewBufferString(writeMetrics(ms)))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		return fmt.Errorf("push to %s failed: %s", u, rsp.Status)
+	}
+	return nil
+}
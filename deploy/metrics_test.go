@@ -0,0 +1,53 @@
+package deploy
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	s := writeMetrics([]metric{
+		{name: "m", help: "help", labels: [][2]string{{"step", "pull"}}, value: 1.5},
+		{name: "m", help: "help", labels: [][2]string{{"step", "deploy"}}, value: 2},
+		{name: "n", help: "other", value: 3},
+	})
+	assert.Equal(t, `# HELP m help
+# TYPE m gauge
+m{step="pull"} 1.5
+m{step="deploy"} 2
+# HELP n other
+# TYPE n gauge
+n 3
+`, s)
+}
+
+func TestPushMetrics(t *testing.T) {
+	var path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		buf, _ := ioutil.ReadAll(r.Body)
+		body = string(buf)
+	}))
+	defer srv.Close()
+
+	w := &Worker{
+		deployment: "s2",
+		service:    "api",
+		depConfig:  &DeploymentConfig{Metrics: &MetricsConfig{Pushgateway: srv.URL}},
+	}
+	w.pushDcMetrics(&Deployer{dc: "pg1"}, 0, errors.New("failed"))
+	assert.Equal(t, "/metrics/job/pitwall/deployment/s2/service/api/dc/pg1", path)
+	assert.True(t, strings.Contains(body, "pitwall_deploy_last_failure_timestamp_seconds "))
+	assert.False(t, strings.Contains(body, "time_to_healthy"))
+
+	w.dryRun = true
+	path = ""
+	w.pushDcMetrics(&Deployer{dc: "pg1"}, 0, nil)
+	assert.Equal(t, "", path)
+}
@@ -0,0 +1,309 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/manifoldco/promptui"
+	"github.com/minus5/svckit/log"
+)
+
+// node meta attributes used in job constraints
+const (
+	NodeMetaHostGroup = "hostgroup"
+	NodeMetaNode      = "node"
+	NodeMetaDcRegion  = "dc_region"
+)
+
+// nodeEligible is scheduling eligibility of the node accepting new allocations
+const nodeEligible = "eligible"
+
+// NodeFilter selects nodes by name and meta attributes.
+// Empty fields match all nodes.
+type NodeFilter struct {
+	Names     []string // node names
+	HostGroup string
+	Node      string
+	DcRegion  string
+}
+
+func (f NodeFilter) empty() bool {
+	return len(f.Names) == 0 && f.HostGroup == "" && f.Node == "" && f.DcRegion == ""
+}
+
+func (f NodeFilter) match(n *api.Node) bool {
+	if len(f.Names) > 0 {
+		found := false
+		for _, name := range f.Names {
+			if name == n.Name || strings.HasPrefix(n.ID, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for k, v := range map[string]string{
+		NodeMetaHostGroup: f.HostGroup,
+		NodeMetaNode:      f.Node,
+		NodeMetaDcRegion:  f.DcRegion,
+	} {
+		if v != "" && n.Meta[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// NodeInfo is Nomad client node with services running on it
+type NodeInfo struct {
+	ID          string   `json:"id" yaml:"id"`
+	Name        string   `json:"name" yaml:"name"`
+	Address     string   `json:"address" yaml:"address"`
+	Status      string   `json:"status" yaml:"status"`
+	Eligibility string   `json:"eligibility" yaml:"eligibility"`
+	Drain       bool     `json:"drain" yaml:"drain"`
+	HostGroup   string   `json:"hostgroup" yaml:"hostgroup"`
+	Node        string   `json:"node" yaml:"node"`
+	DcRegion    string   `json:"dc_region" yaml:"dc_region"`
+	Services    []string `json:"services" yaml:"services"`       // jobs with running allocations
+	System      []string `json:"system" yaml:"system,omitempty"` // system jobs with running allocations
+}
+
+func (n NodeInfo) String() string {
+	status := n.Status
+	if status != "ready" {
+		status = warn(status)
+	}
+	eligibility := n.Eligibility
+	if n.Drain {
+		eligibility = warn("draining")
+	} else if eligibility != nodeEligible {
+		eligibility = warn(eligibility)
+	}
+	return fmt.Sprintf("%-8s %-20s %-8s %-12s %-12s %-10s %-10s %s",
+		n.ID[:8],
+		n.Name,
+		status,
+		eligibility,
+		n.HostGroup,
+		n.Node,
+		n.DcRegion,
+		strings.Join(n.Services, ", "))
+}
+
+// Nodes lists Nomad client nodes in Dc matching filter,
+// with services running on them.
+func Nodes(ctx context.Context, o Options, f NodeFilter) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	if err := d.connect(ctx); err != nil {
+		return done(WithExitCode(ExitConnection, err))
+	}
+	ns, err := d.Nodes(ctx, f)
+	if err != nil {
+		return done(err)
+	}
+	err = render(o.Output, ns, func(w io.Writer) {
+		for _, n := range ns {
+			fmt.Fprintf(w, "%s\n", n)
+		}
+	})
+	if err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// DrainNodes enables, or disables, drain of the nodes in Dc matching filter.
+// Services which will move are shown and confirmation asked unless Yes is set.
+// Nodes are drained one by one, waiting for each to finish unless detach is set,
+// so services don't lose all allocations at once.
+func DrainNodes(ctx context.Context, o Options, f NodeFilter, enable bool, deadline time.Duration, ignoreSystem, detach bool) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	ns, err := d.selectNodes(ctx, f)
+	if err != nil {
+		return done(err)
+	}
+	if enable && !o.Yes {
+		if err := confirmNodes("Drain", ns, !ignoreSystem); err != nil {
+			return done(err)
+		}
+	}
+	for _, n := range ns {
+		var spec *api.DrainSpec
+		if enable {
+			spec = &api.DrainSpec{Deadline: deadline, IgnoreSystemJobs: ignoreSystem}
+		}
+		// disabling drain makes node eligible again
+		resp, err := d.cli.Nodes().UpdateDrain(n.ID, spec, !enable, nil)
+		if err != nil {
+			return done(fmt.Errorf("node %s: %v", n.Name, err))
+		}
+		log.S("node", n.Name).B("drain", enable).Info("drain updated")
+		if !enable || detach {
+			continue
+		}
+		if err := d.monitorDrain(ctx, n, resp.LastIndex, ignoreSystem); err != nil {
+			return done(err)
+		}
+	}
+	return nil
+}
+
+// NodesEligibility marks nodes in Dc matching filter as eligible,
+// or ineligible, for scheduling new allocations. Running allocations are not moved.
+func NodesEligibility(ctx context.Context, o Options, f NodeFilter, eligible bool) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	ns, err := d.selectNodes(ctx, f)
+	if err != nil {
+		return done(err)
+	}
+	if !eligible && !o.Yes {
+		if err := confirmNodes("Mark ineligible", ns, false); err != nil {
+			return done(err)
+		}
+	}
+	for _, n := range ns {
+		if _, err := d.cli.Nodes().ToggleEligibility(n.ID, eligible, nil); err != nil {
+			return done(fmt.Errorf("node %s: %v", n.Name, err))
+		}
+		log.S("node", n.Name).B("eligible", eligible).Info("eligibility updated")
+	}
+	return nil
+}
+
+// selectNodes connects and finds nodes matching filter, which must not be empty
+func (d *Deployer) selectNodes(ctx context.Context, f NodeFilter) ([]*NodeInfo, error) {
+	if f.empty() {
+		return nil, WithExitCode(ExitValidation, errors.New("select nodes by name, hostgroup, node or dc_region"))
+	}
+	if err := d.connect(ctx); err != nil {
+		return nil, WithExitCode(ExitConnection, err)
+	}
+	ns, err := d.Nodes(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if len(ns) == 0 {
+		return nil, WithExitCode(ExitValidation, fmt.Errorf("no nodes found in %s", d.dc))
+	}
+	return ns, nil
+}
+
+// Nodes returns nodes in deployer datacenter matching filter, sorted by name
+func (d *Deployer) Nodes(ctx context.Context, f NodeFilter) ([]*NodeInfo, error) {
+	stubs, _, err := d.cli.Nodes().List(nil)
+	if err != nil {
+		return nil, err
+	}
+	var ns []*NodeInfo
+	for _, s := range stubs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if s.Datacenter != d.dc {
+			continue
+		}
+		n, _, err := d.cli.Nodes().Info(s.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %v", s.Name, err)
+		}
+		if !f.match(n) {
+			continue
+		}
+		allocs, _, err := d.cli.Nodes().Allocations(n.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("node %s allocations: %v", s.Name, err)
+		}
+		ni := &NodeInfo{
+			ID:          n.ID,
+			Name:        n.Name,
+			Address:     s.Address,
+			Status:      n.Status,
+			Eligibility: n.SchedulingEligibility,
+			Drain:       n.Drain,
+			HostGroup:   n.Meta[NodeMetaHostGroup],
+			Node:        n.Meta[NodeMetaNode],
+			DcRegion:    n.Meta[NodeMetaDcRegion],
+		}
+		ni.Services, ni.System = nodeJobs(allocs)
+		ns = append(ns, ni)
+	}
+	sort.Slice(ns, func(i, j int) bool { return ns[i].Name < ns[j].Name })
+	return ns, nil
+}
+
+// nodeJobs returns sorted service and system jobs of the running allocations
+func nodeJobs(allocs []*api.Allocation) ([]string, []string) {
+	services := make(map[string]struct{})
+	system := make(map[string]struct{})
+	for _, a := range allocs {
+		if a.DesiredStatus != "run" || a.ClientStatus != "running" {
+			continue
+		}
+		if a.Job != nil && a.Job.Type != nil && *a.Job.Type == JobTypeSystem {
+			system[a.JobID] = struct{}{}
+			continue
+		}
+		services[a.JobID] = struct{}{}
+	}
+	return sortedKeys(services), sortedKeys(system)
+}
+
+// monitorDrain waits for node drain to finish
+func (d *Deployer) monitorDrain(ctx context.Context, n *NodeInfo, index uint64, ignoreSystem bool) error {
+	for msg := range d.cli.Nodes().MonitorDrain(ctx, n.ID, index, ignoreSystem) {
+		switch msg.Level {
+		case api.MonitorMsgLevelError:
+			d.log().S("node", n.Name).ErrorS(msg.Message)
+		case api.MonitorMsgLevelWarn:
+			d.log().S("node", n.Name).Info(warn(msg.Message))
+		default:
+			d.log().S("node", n.Name).Info(msg.Message)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		d.log().S("node", n.Name).Info("stopped watching drain, it is still running in Nomad")
+		return err
+	}
+	return nil
+}
+
+// confirmNodes shows nodes with services which will be affected and asks for confirmation
+func confirmNodes(action string, ns []*NodeInfo, system bool) error {
+	for _, n := range ns {
+		jobs := n.Services
+		if system {
+			jobs = append(jobs, n.System...)
+		}
+		fmt.Fprintf(termOut, "%-20s %s\n", n.Name, strings.Join(jobs, ", "))
+	}
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("%s %d nodes", action, len(ns)),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return ErrAborted
+	}
+	return nil
+}
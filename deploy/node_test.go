@@ -0,0 +1,30 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeFilter(t *testing.T) {
+	n := &api.Node{ID: "abcdef12", Name: "app3", Meta: map[string]string{"hostgroup": "app", "node": "app3"}}
+	assert.True(t, NodeFilter{}.empty())
+	assert.True(t, NodeFilter{HostGroup: "app"}.match(n))
+	assert.True(t, NodeFilter{Names: []string{"abcd"}}.match(n))
+	assert.False(t, NodeFilter{HostGroup: "app", DcRegion: "east"}.match(n))
+	assert.False(t, NodeFilter{Names: []string{"app4"}}.match(n))
+}
+
+func TestNodeJobs(t *testing.T) {
+	system := JobTypeSystem
+	allocs := []*api.Allocation{
+		{JobID: "api", DesiredStatus: "run", ClientStatus: "running"},
+		{JobID: "api", DesiredStatus: "run", ClientStatus: "running"},
+		{JobID: "old", DesiredStatus: "stop", ClientStatus: "complete"},
+		{JobID: "fluentd", DesiredStatus: "run", ClientStatus: "running", Job: &api.Job{Type: &system}},
+	}
+	services, sys := nodeJobs(allocs)
+	assert.Equal(t, []string{"api"}, services)
+	assert.Equal(t, []string{"fluentd"}, sys)
+}
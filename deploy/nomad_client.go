@@ -0,0 +1,104 @@
+package deploy
+
+import (
+	"context"
+	"io"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// NomadClient is the part of Nomad API used by Deployer.
+// NewNomadClient adapts *api.Client to it, FakeNomad is in memory implementation for tests.
+type NomadClient interface {
+	Agent() NomadAgent
+	Allocations() NomadAllocations
+	AllocFS() NomadAllocFS
+	Deployments() NomadDeployments
+	Evaluations() NomadEvaluations
+	Jobs() NomadJobs
+	Nodes() NomadNodes
+	Raw() NomadRaw
+}
+
+// NomadAgent is implemented by *api.Agent
+type NomadAgent interface {
+	Datacenter() (string, error)
+	Region() (string, error)
+}
+
+// NomadAllocations is implemented by *api.Allocations
+type NomadAllocations interface {
+	Info(allocID string, q *api.QueryOptions) (*api.Allocation, *api.QueryMeta, error)
+	List(q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error)
+}
+
+// NomadAllocFS is implemented by *api.AllocFS
+type NomadAllocFS interface {
+	Logs(alloc *api.Allocation, follow bool, task, logType, origin string,
+		offset int64, cancel <-chan struct{}, q *api.QueryOptions) (<-chan *api.StreamFrame, <-chan error)
+}
+
+// NomadDeployments is implemented by *api.Deployments
+type NomadDeployments interface {
+	Allocations(deploymentID string, q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error)
+	Fail(deploymentID string, q *api.WriteOptions) (*api.DeploymentUpdateResponse, *api.WriteMeta, error)
+	Info(deploymentID string, q *api.QueryOptions) (*api.Deployment, *api.QueryMeta, error)
+	PromoteAll(deploymentID string, q *api.WriteOptions) (*api.DeploymentUpdateResponse, *api.WriteMeta, error)
+}
+
+// NomadEvaluations is implemented by *api.Evaluations
+type NomadEvaluations interface {
+	Allocations(evalID string, q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error)
+	Info(evalID string, q *api.QueryOptions) (*api.Evaluation, *api.QueryMeta, error)
+}
+
+// NomadJobs is implemented by *api.Jobs
+type NomadJobs interface {
+	Allocations(jobID string, allAllocs bool, q *api.QueryOptions) ([]*api.AllocationListStub, *api.QueryMeta, error)
+	Deployments(jobID string, q *api.QueryOptions) ([]*api.Deployment, *api.QueryMeta, error)
+	Deregister(jobID string, purge bool, q *api.WriteOptions) (string, *api.WriteMeta, error)
+	Dispatch(jobID string, meta map[string]string, payload []byte, q *api.WriteOptions) (*api.JobDispatchResponse, *api.WriteMeta, error)
+	EnforceRegister(job *api.Job, modifyIndex uint64, q *api.WriteOptions) (*api.JobRegisterResponse, *api.WriteMeta, error)
+	Info(jobID string, q *api.QueryOptions) (*api.Job, *api.QueryMeta, error)
+	LatestDeployment(jobID string, q *api.QueryOptions) (*api.Deployment, *api.QueryMeta, error)
+	List(q *api.QueryOptions) ([]*api.JobListStub, *api.QueryMeta, error)
+	Plan(job *api.Job, diff bool, q *api.WriteOptions) (*api.JobPlanResponse, *api.WriteMeta, error)
+	Revert(jobID string, version uint64, enforcePriorVersion *uint64, q *api.WriteOptions) (*api.JobRegisterResponse, *api.WriteMeta, error)
+	Summary(jobID string, q *api.QueryOptions) (*api.JobSummary, *api.QueryMeta, error)
+	Validate(job *api.Job, q *api.WriteOptions) (*api.JobValidateResponse, *api.WriteMeta, error)
+	Versions(jobID string, diffs bool, q *api.QueryOptions) ([]*api.Job, []*api.JobDiff, *api.QueryMeta, error)
+}
+
+// NomadNodes is implemented by *api.Nodes
+type NomadNodes interface {
+	Allocations(nodeID string, q *api.QueryOptions) ([]*api.Allocation, *api.QueryMeta, error)
+	Info(nodeID string, q *api.QueryOptions) (*api.Node, *api.QueryMeta, error)
+	List(q *api.QueryOptions) ([]*api.NodeListStub, *api.QueryMeta, error)
+	MonitorDrain(ctx context.Context, nodeID string, index uint64, ignoreSys bool) <-chan *api.MonitorMessage
+	ToggleEligibility(nodeID string, eligible bool, q *api.WriteOptions) (*api.NodeEligibilityUpdateResponse, error)
+	UpdateDrain(nodeID string, spec *api.DrainSpec, markEligible bool, q *api.WriteOptions) (*api.NodeDrainUpdateResponse, error)
+}
+
+// NomadRaw is implemented by *api.Raw
+type NomadRaw interface {
+	Response(endpoint string, q *api.QueryOptions) (io.ReadCloser, error)
+}
+
+// nomadClient adapts *api.Client to NomadClient
+type nomadClient struct {
+	cli *api.Client
+}
+
+// NewNomadClient returns NomadClient using Nomad api client
+func NewNomadClient(cli *api.Client) NomadClient {
+	return nomadClient{cli: cli}
+}
+
+func (c nomadClient) Agent() NomadAgent             { return c.cli.Agent() }
+func (c nomadClient) Allocations() NomadAllocations { return c.cli.Allocations() }
+func (c nomadClient) AllocFS() NomadAllocFS         { return c.cli.AllocFS() }
+func (c nomadClient) Deployments() NomadDeployments { return c.cli.Deployments() }
+func (c nomadClient) Evaluations() NomadEvaluations { return c.cli.Evaluations() }
+func (c nomadClient) Jobs() NomadJobs               { return c.cli.Jobs() }
+func (c nomadClient) Nodes() NomadNodes             { return c.cli.Nodes() }
+func (c nomadClient) Raw() NomadRaw                 { return c.cli.Raw() }
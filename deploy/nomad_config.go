@@ -0,0 +1,78 @@
+package deploy
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/minus5/svckit/env"
+)
+
+// NomadConfig contains Nomad connection parameters
+// It can be set for each datacenter in config.yml or with flags.
+type NomadConfig struct {
+	Token      string `yaml:"-"` // ACL token, never stored in config.yml
+	TLS        bool   `yaml:"tls,omitempty"`
+	CACert     string `yaml:"ca_cert,omitempty"`
+	ClientCert string `yaml:"client_cert,omitempty"`
+	ClientKey  string `yaml:"client_key,omitempty"`
+	ServerName string `yaml:"server_name,omitempty"`
+	Insecure   bool   `yaml:"insecure,omitempty"`
+}
+
+// merge sets non empty values from o
+func (n *NomadConfig) merge(o NomadConfig) {
+	if o.Token != "" {
+		n.Token = o.Token
+	}
+	if o.TLS {
+		n.TLS = true
+	}
+	if o.CACert != "" {
+		n.CACert = o.CACert
+	}
+	if o.ClientCert != "" {
+		n.ClientCert = o.ClientCert
+	}
+	if o.ClientKey != "" {
+		n.ClientKey = o.ClientKey
+	}
+	if o.ServerName != "" {
+		n.ServerName = o.ServerName
+	}
+	if o.Insecure {
+		n.Insecure = true
+	}
+}
+
+// clientConfig creates Nomad client config for address (host:port).
+// Defaults are read from NOMAD_* environment variables (NOMAD_TOKEN, NOMAD_CACERT...)
+// and overridden by non empty values of n.
+func (n NomadConfig) clientConfig(address string) *api.Config {
+	c := api.DefaultConfig()
+	if n.Token != "" {
+		c.SecretID = n.Token
+	}
+	t := c.TLSConfig
+	if n.CACert != "" {
+		t.CACert = env.ExpandPath(n.CACert)
+	}
+	if n.ClientCert != "" {
+		t.ClientCert = env.ExpandPath(n.ClientCert)
+	}
+	if n.ClientKey != "" {
+		t.ClientKey = env.ExpandPath(n.ClientKey)
+	}
+	if n.ServerName != "" {
+		t.TLSServerName = n.ServerName
+	}
+	if n.Insecure {
+		t.Insecure = true
+	}
+
+	scheme := "http"
+	if n.TLS || t.CACert != "" || t.CAPath != "" || t.ClientCert != "" {
+		scheme = "https"
+	}
+	c.Address = fmt.Sprintf("%s://%s", scheme, address)
+	return c
+}
@@ -0,0 +1,36 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNomadClientConfig(t *testing.T) {
+	n := NomadConfig{CACert: "/etc/nomad/ca.pem", ServerName: "server.global.nomad"}
+	n.merge(NomadConfig{Token: "token", ClientCert: "/etc/nomad/cli.pem"})
+
+	c := n.clientConfig("10.0.0.1:4646")
+	assert.Equal(t, "https://10.0.0.1:4646", c.Address)
+	assert.Equal(t, "token", c.SecretID)
+	assert.Equal(t, "/etc/nomad/ca.pem", c.TLSConfig.CACert)
+	assert.Equal(t, "/etc/nomad/cli.pem", c.TLSConfig.ClientCert)
+	assert.Equal(t, "server.global.nomad", c.TLSConfig.TLSServerName)
+
+	c = NomadConfig{}.clientConfig("10.0.0.1:4646")
+	assert.Equal(t, "http://10.0.0.1:4646", c.Address)
+}
+
+func TestJobNamespace(t *testing.T) {
+	cfg, err := NewDeploymentConfig("./fixture", "test")
+	assert.NoError(t, err)
+
+	d := NewDeployer("./fixture", "service_test2", "", cfg, "", "datacenter3", "test")
+	assert.Equal(t, "ns3", d.jobNamespace())
+
+	d = NewDeployer("./fixture", "service_test2", "", cfg, "", "datacenter1", "test")
+	assert.Equal(t, "", d.jobNamespace())
+
+	d.namespace = "flag"
+	assert.Equal(t, "flag", d.jobNamespace())
+}
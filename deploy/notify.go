@@ -0,0 +1,106 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/minus5/svckit/log"
+)
+
+// notification events
+const (
+	NotifyStarted   = "started"
+	NotifySucceeded = "succeeded"
+	NotifyFailed    = "failed"
+)
+
+// NotifyConfig configures where deploy notifications are sent
+type NotifyConfig struct {
+	SlackWebhook string   `yaml:"slack_webhook,omitempty"`
+	Channels     []string `yaml:"channels,omitempty"` // Slack channels, webhook default channel if empty
+	Webhooks     []string `yaml:"webhooks,omitempty"` // generic webhooks receiving Notification json
+}
+
+// Notification is posted to the generic webhooks
+type Notification struct {
+	Event      string `json:"event"`
+	Deployment string `json:"deployment"`
+	Service    string `json:"service"`
+	Image      string `json:"image"`
+	Dc         string `json:"dc"`
+	User       string `json:"user"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (n Notification) text() string {
+	s := fmt.Sprintf("%s deploy of %s (%s) to %s/%s %s", n.User, n.Service, n.Image, n.Deployment, n.Dc, n.Event)
+	if n.Error != "" {
+		s += ": " + n.Error
+	}
+	return s
+}
+
+var notifyClient = &http.Client{Timeout: 5 * time.Second}
+
+// send notification to all configured destinations
+// Failed notifications are logged, they don't break deployment.
+func (c *NotifyConfig) send(n Notification) {
+	if c == nil {
+		return
+	}
+	if c.SlackWebhook != "" {
+		channels := c.Channels
+		if len(channels) == 0 {
+			channels = []string{""}
+		}
+		for _, ch := range channels {
+			msg := struct {
+				Channel string `json:"channel,omitempty"`
+				Text    string `json:"text"`
+			}{Channel: ch, Text: n.text()}
+			if err := postJSON(c.SlackWebhook, msg); err != nil {
+				log.S("channel", ch).Error(err)
+			}
+		}
+	}
+	for _, url := range c.Webhooks {
+		if err := postJSON(url, n); err != nil {
+			log.S("url", url).Error(err)
+		}
+	}
+}
+
+func postJSON(url string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	rsp, err := notifyClient.Post(url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		return fmt.Errorf("notification to %s failed: %s", url, rsp.Status)
+	}
+	return nil
+}
+
+// notify sends deploy event notification for the datacenter
+func (w *Worker) notify(event, dc string, err error) {
+	if w.dryRun || w.depConfig == nil {
+		return
+	}
+	w.depConfig.Notify.send(Notification{
+		Event:      event,
+		Deployment: w.deployment,
+		Service:    w.service,
+		Image:      w.image,
+		Dc:         dc,
+		User:       currentUser(),
+		Error:      errString(err),
+	})
+}
@@ -0,0 +1,45 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotify(t *testing.T) {
+	var slack []map[string]string
+	var hooks []Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slack" {
+			var m map[string]string
+			json.NewDecoder(r.Body).Decode(&m)
+			slack = append(slack, m)
+			return
+		}
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		hooks = append(hooks, n)
+	}))
+	defer srv.Close()
+
+	c := &NotifyConfig{
+		SlackWebhook: srv.URL + "/slack",
+		Channels:     []string{"#deploy", "#team"},
+		Webhooks:     []string{srv.URL + "/hook"},
+	}
+	c.send(Notification{Event: NotifyFailed, Deployment: "dev", Service: "svc", Image: "img", Dc: "dc1", User: "me", Error: errString(errors.New("boom"))})
+
+	assert.Len(t, slack, 2)
+	assert.Equal(t, "#team", slack[1]["channel"])
+	assert.Equal(t, "me deploy of svc (img) to dev/dc1 failed: boom", slack[0]["text"])
+	assert.Len(t, hooks, 1)
+	assert.Equal(t, "boom", hooks[0].Error)
+
+	// nil config is noop
+	var nc *NotifyConfig
+	nc.send(Notification{})
+}
@@ -0,0 +1,103 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/minus5/svckit/log"
+)
+
+// dependencies of the service from all datacenters
+func (c *DeploymentConfig) dependencies(service string) []string {
+	deps := make(map[string]struct{})
+	for _, dc := range c.Datacenters {
+		if s, ok := dc.Services[service]; ok {
+			for _, d := range s.DependsOn {
+				deps[d] = struct{}{}
+			}
+		}
+	}
+	var ds []string
+	for d := range deps {
+		ds = append(ds, d)
+	}
+	sort.Strings(ds)
+	return ds
+}
+
+// deployOrder sorts services so that dependencies are deployed first.
+// Dependencies which are not in services are expected to be already deployed.
+func (c *DeploymentConfig) deployOrder(services []string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, s := range services {
+		if c.Find(s) == nil {
+			return nil, fmt.Errorf("service %s not found", s)
+		}
+		selected[s] = true
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var order []string
+	var visit func(s string, path []string) error
+	visit = func(s string, path []string) error {
+		switch state[s] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle %s", strings.Join(append(path, s), " -> "))
+		}
+		state[s] = visiting
+		for _, d := range c.dependencies(s) {
+			if !selected[d] {
+				continue
+			}
+			if err := visit(d, append(path, s)); err != nil {
+				return err
+			}
+		}
+		state[s] = visited
+		order = append(order, s)
+		return nil
+	}
+	for _, s := range services {
+		if err := visit(s, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// runServices deploys services one by one, dependencies first.
+// Deployment stops on the first failed service.
+func runServices(ctx context.Context, o Options) error {
+	root, err := configRoot(o)
+	if err != nil {
+		return err
+	}
+	c, err := NewDeploymentEnvConfig(root, o.Deployment, o.Env)
+	if err != nil {
+		return err
+	}
+	order, err := c.deployOrder(o.Services)
+	if err != nil {
+		return WithExitCode(ExitValidation, err)
+	}
+	log.S("order", strings.Join(order, " ")).Info("deploying services")
+	for i, s := range order {
+		so := o
+		so.Service = s
+		so.Services = nil
+		if err := runWorker(ctx, newWorker(so)); err != nil {
+			if rest := order[i+1:]; len(rest) > 0 {
+				return wrapExit(err, fmt.Errorf("%s: %v, not deployed: %s", s, err, strings.Join(rest, " ")))
+			}
+			return wrapExit(err, fmt.Errorf("%s: %v", s, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeployOrder(t *testing.T) {
+	c := &DeploymentConfig{Datacenters: map[string]*DcConfig{
+		"dc1": {Services: map[string]*ServiceConfig{
+			"api":    {DependsOn: []string{"db", "cache"}},
+			"db":     {},
+			"cache":  {DependsOn: []string{"db"}},
+			"worker": {DependsOn: []string{"api"}},
+		}},
+	}}
+	order, err := c.deployOrder([]string{"worker", "api", "cache", "db"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db", "cache", "api", "worker"}, order)
+
+	// dependencies not selected are skipped
+	order, err = c.deployOrder([]string{"worker", "cache"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"worker", "cache"}, order)
+
+	_, err = c.deployOrder([]string{"api", "unknown"})
+	assert.Error(t, err)
+
+	c.Datacenters["dc1"].Services["db"].DependsOn = []string{"worker"}
+	_, err = c.deployOrder([]string{"worker", "api", "db"})
+	assert.EqualError(t, err, "dependency cycle worker -> api -> db -> worker")
+}
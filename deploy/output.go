@@ -0,0 +1,88 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Options.Output values
+const (
+	OutputTable = "table" // human readable, default
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// machineOutput is output format meant for scripts
+func machineOutput(format string) bool {
+	return format == OutputJSON || format == OutputYAML
+}
+
+// ValidOutput checks output format
+func ValidOutput(format string) error {
+	switch format {
+	case "", OutputTable, OutputJSON, OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %s, expecting table, json or yaml", format)
+}
+
+// render writes v to stdout in json or yaml format,
+// or calls table for human readable output
+func render(format string, v interface{}, table func(io.Writer)) error {
+	return renderTo(os.Stdout, format, v, table)
+}
+
+func renderTo(w io.Writer, format string, v interface{}, table func(io.Writer)) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		buf, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	case "", OutputTable:
+		table(w)
+		return nil
+	}
+	return ValidOutput(format)
+}
+
+// yamlValue converts value with yaml tags to structure which
+// can be encoded to json with the same keys
+func yamlValue(v interface{}) (interface{}, error) {
+	buf, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m interface{}
+	if err := yaml.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+	return jsonCompatible(m), nil
+}
+
+// jsonCompatible replaces yaml map[interface{}]interface{} with map[string]interface{}
+func jsonCompatible(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, v := range t {
+			m[fmt.Sprintf("%v", k)] = jsonCompatible(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range t {
+			t[i] = jsonCompatible(v)
+		}
+	}
+	return v
+}
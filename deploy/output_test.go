@@ -0,0 +1,38 @@
+package deploy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	v := []JobVersion{{Version: 3, Image: "api:1", Stable: true}}
+	table := func(w io.Writer) { w.Write([]byte("table\n")) }
+
+	var buf bytes.Buffer
+	assert.NoError(t, renderTo(&buf, "", v, table))
+	assert.Equal(t, "table\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, renderTo(&buf, OutputJSON, v, table))
+	assert.Contains(t, buf.String(), `"version": 3`)
+	assert.Contains(t, buf.String(), `"image": "api:1"`)
+
+	buf.Reset()
+	assert.NoError(t, renderTo(&buf, OutputYAML, v, table))
+	assert.Contains(t, buf.String(), "- version: 3\n  image: api:1\n")
+
+	assert.Error(t, renderTo(&buf, "xml", v, table))
+}
+
+func TestYamlValue(t *testing.T) {
+	v, err := yamlValue(&DcConfig{Namespace: "ns", Services: map[string]*ServiceConfig{"api": {Image: "api:1", Count: 2}}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"namespace": "ns",
+		"services":  map[string]interface{}{"api": map[string]interface{}{"image": "api:1", "count": 2}},
+	}, v)
+}
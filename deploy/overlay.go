@@ -0,0 +1,145 @@
+package deploy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// OverlayFileName returns environment overlay config, like config.prod.yml
+func (c *DeploymentConfig) OverlayFileName() string {
+	return fmt.Sprintf("%s/deployments/%s/config.%s.yml", c.root, c.deployment, c.env)
+}
+
+// SavedFileName returns config file changed by Save
+func (c *DeploymentConfig) SavedFileName() string {
+	if c.env != "" {
+		return c.OverlayFileName()
+	}
+	return c.FileName()
+}
+
+// applyOverlay deep merges environment overlay config into base config data
+func (c *DeploymentConfig) applyOverlay(data []byte) ([]byte, error) {
+	od, err := readConfigFile(c.OverlayFileName())
+	if err != nil {
+		return nil, err
+	}
+	var base, overlay map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(od, &overlay); err != nil {
+		return nil, fmt.Errorf("%s: %v", c.OverlayFileName(), err)
+	}
+	return yaml.Marshal(mergeMaps(base, overlay))
+}
+
+// mergeMaps deep merges src into dst, other values (including lists) are replaced
+func mergeMaps(dst, src map[interface{}]interface{}) map[interface{}]interface{} {
+	if dst == nil {
+		dst = make(map[interface{}]interface{})
+	}
+	for k, v := range src {
+		sm, sok := v.(map[interface{}]interface{})
+		dm, dok := dst[k].(map[interface{}]interface{})
+		if sok && dok {
+			dst[k] = mergeMaps(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// snapshotImages remembers loaded service images, to find the ones changed by deployment
+func (c *DeploymentConfig) snapshotImages() {
+	c.images = make(map[string]string)
+	for dc, d := range c.Datacenters {
+		if d == nil {
+			continue
+		}
+		for name, s := range d.Services {
+			c.images[dc+"/"+name] = s.Image
+		}
+	}
+}
+
+// marshalOverlay writes changed service images to the environment overlay,
+// base config is not changed
+func (c *DeploymentConfig) marshalOverlay() ([]byte, error) {
+	var overlay yaml.MapSlice
+	od, err := ioutil.ReadFile(c.OverlayFileName())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(od, &overlay); err != nil {
+		return nil, err
+	}
+	for _, ch := range c.changedImages() {
+		overlay = setMapValue(overlay, ch.image, "datacenters", ch.dc, "services", ch.service, "image")
+	}
+	return yaml.Marshal(overlay)
+}
+
+type imageChange struct {
+	dc      string
+	service string
+	image   string
+}
+
+// changedImages returns service images changed since config is loaded
+func (c *DeploymentConfig) changedImages() []imageChange {
+	var chs []imageChange
+	for dc, d := range c.Datacenters {
+		for name, s := range d.Services {
+			if c.images[dc+"/"+name] != s.Image {
+				chs = append(chs, imageChange{dc: dc, service: name, image: s.Image})
+			}
+		}
+	}
+	return chs
+}
+
+// ConfigShow prints deployment config files, or with resolved
+// effective config with environment overlay, interpolations and defaults applied.
+// Json and yaml output are always resolved config.
+func ConfigShow(o Options, resolved bool) error {
+	root, err := configRoot(o)
+	if err != nil {
+		return err
+	}
+	c, err := NewDeploymentEnvConfig(root, o.Deployment, o.Env)
+	if err != nil {
+		return err
+	}
+	if o.Output == OutputJSON {
+		v, err := yamlValue(c)
+		if err != nil {
+			return err
+		}
+		return render(o.Output, v, nil)
+	}
+	if resolved || o.Output == OutputYAML {
+		buf, err := yaml.Marshal(c)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(buf))
+		return nil
+	}
+	files := []string{c.FileName()}
+	if c.env != "" {
+		files = append(files, c.OverlayFileName())
+	}
+	for _, fn := range files {
+		buf, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n%s\n", fn, buf)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// diff types returned by Nomad plan
+const (
+	diffTypeNone    = "None"
+	diffTypeAdded   = "Added"
+	diffTypeDeleted = "Deleted"
+	diffTypeEdited  = "Edited"
+)
+
+// formatPlan renders job plan diff and scheduler annotations
+// in the similar way as nomad plan command does.
+func formatPlan(jp *api.JobPlanResponse) string {
+	var b strings.Builder
+	if jp.Diff != nil {
+		formatJobDiff(&b, jp.Diff)
+	}
+	b.WriteString("\n")
+	formatDryRun(&b, jp)
+	if jp.Warnings != "" {
+		b.WriteString(warn(fmt.Sprintf("\nJob Warnings:\n%s", jp.Warnings)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func formatJobDiff(b *strings.Builder, jd *api.JobDiff) {
+	fmt.Fprintf(b, "%s Job: %q\n", diffMarker(jd.Type), jd.ID)
+	formatFields(b, jd.Fields, 1)
+	formatObjects(b, jd.Objects, 1)
+	for _, tg := range jd.TaskGroups {
+		formatTaskGroupDiff(b, tg)
+	}
+}
+
+func formatTaskGroupDiff(b *strings.Builder, tg *api.TaskGroupDiff) {
+	fmt.Fprintf(b, "%s Task Group: %q", diffMarker(tg.Type), tg.Name)
+	if len(tg.Updates) > 0 {
+		var updates []string
+		for k, v := range tg.Updates {
+			updates = append(updates, fmt.Sprintf("%d %s", v, k))
+		}
+		sort.Strings(updates)
+		fmt.Fprintf(b, " (%s)", info(strings.Join(updates, ", ")))
+	}
+	b.WriteString("\n")
+	formatFields(b, tg.Fields, 1)
+	formatObjects(b, tg.Objects, 1)
+	for _, t := range tg.Tasks {
+		if t.Type == diffTypeNone {
+			continue
+		}
+		fmt.Fprintf(b, "  %s Task: %q", diffMarker(t.Type), t.Name)
+		if len(t.Annotations) > 0 {
+			fmt.Fprintf(b, " (%s)", warn(strings.Join(t.Annotations, ", ")))
+		}
+		b.WriteString("\n")
+		formatFields(b, t.Fields, 2)
+		formatObjects(b, t.Objects, 2)
+	}
+}
+
+func formatFields(b *strings.Builder, fields []*api.FieldDiff, level int) {
+	prefix := strings.Repeat("  ", level)
+	for _, f := range fields {
+		if f.Type == diffTypeNone {
+			continue
+		}
+		fmt.Fprintf(b, "%s%s %s: ", prefix, diffMarker(f.Type), f.Name)
+		if strings.Contains(strings.ToLower(f.Name), "password") {
+			// don't show registry credentials
+			f = &api.FieldDiff{Type: f.Type, Old: maskValue(f.Old), New: maskValue(f.New), Annotations: f.Annotations}
+		}
+		switch f.Type {
+		case diffTypeAdded:
+			fmt.Fprintf(b, "%q", f.New)
+		case diffTypeDeleted:
+			fmt.Fprintf(b, "%q", f.Old)
+		default:
+			fmt.Fprintf(b, "%q => %q", f.Old, f.New)
+		}
+		if len(f.Annotations) > 0 {
+			fmt.Fprintf(b, " (%s)", warn(strings.Join(f.Annotations, ", ")))
+		}
+		b.WriteString("\n")
+	}
+}
+
+func formatObjects(b *strings.Builder, objects []*api.ObjectDiff, level int) {
+	prefix := strings.Repeat("  ", level)
+	for _, o := range objects {
+		if o.Type == diffTypeNone {
+			continue
+		}
+		fmt.Fprintf(b, "%s%s %s {\n", prefix, diffMarker(o.Type), o.Name)
+		formatFields(b, o.Fields, level+1)
+		formatObjects(b, o.Objects, level+1)
+		fmt.Fprintf(b, "%s}\n", prefix)
+	}
+}
+
+func formatDryRun(b *strings.Builder, jp *api.JobPlanResponse) {
+	b.WriteString("Scheduler dry-run:\n")
+	if len(jp.FailedTGAllocs) == 0 {
+		b.WriteString(success("- All tasks successfully allocated."))
+		b.WriteString("\n")
+	}
+	formatFailedAllocs(b, jp.FailedTGAllocs)
+}
+
+// formatFailedAllocs shows why task group allocations are not placed
+func formatFailedAllocs(b *strings.Builder, failed map[string]*api.AllocationMetric) {
+	var groups []string
+	for tg := range failed {
+		groups = append(groups, tg)
+	}
+	sort.Strings(groups)
+	for _, tg := range groups {
+		m := failed[tg]
+		b.WriteString(warn(fmt.Sprintf("- WARNING: Failed to place all allocations for task group %q.", tg)))
+		b.WriteString("\n")
+		if m.CoalescedFailures > 0 {
+			fmt.Fprintf(b, "  * %d unplaced\n", m.CoalescedFailures+1)
+		}
+		if m.NodesEvaluated == 0 {
+			b.WriteString("  * No nodes were eligible for evaluation\n")
+		}
+		for k, v := range m.ConstraintFiltered {
+			fmt.Fprintf(b, "  * Constraint %q filtered %d nodes\n", k, v)
+		}
+		for k, v := range m.DimensionExhausted {
+			fmt.Fprintf(b, "  * Resources exhausted on %d nodes, dimension %q\n", v, k)
+		}
+	}
+}
+
+func maskValue(v string) string {
+	if v == "" {
+		return v
+	}
+	return "********"
+}
+
+func diffMarker(typ string) string {
+	switch typ {
+	case diffTypeAdded:
+		return success("+")
+	case diffTypeDeleted:
+		return warn("-")
+	case diffTypeEdited:
+		return info("+/-")
+	}
+	return " "
+}
@@ -0,0 +1,52 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPlan(t *testing.T) {
+	jp := &api.JobPlanResponse{
+		Diff: &api.JobDiff{
+			Type: diffTypeEdited,
+			ID:   "service_test1",
+			TaskGroups: []*api.TaskGroupDiff{
+				{
+					Type:    diffTypeEdited,
+					Name:    "service_test1",
+					Updates: map[string]uint64{"create/destroy update": 2},
+					Tasks: []*api.TaskDiff{
+						{
+							Type:        diffTypeEdited,
+							Name:        "service",
+							Annotations: []string{"forces create/destroy update"},
+							Objects: []*api.ObjectDiff{
+								{
+									Type: diffTypeEdited,
+									Name: "Config",
+									Fields: []*api.FieldDiff{
+										{Type: diffTypeEdited, Name: "image", Old: "image:1", New: "image:2"},
+										{Type: diffTypeNone, Name: "port_map", Old: "http", New: "http"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		FailedTGAllocs: map[string]*api.AllocationMetric{
+			"service_test1": {ConstraintFiltered: map[string]int{"${meta.node} = app1": 3}},
+		},
+	}
+	out := formatPlan(jp)
+	assert.Contains(t, out, `Job: "service_test1"`)
+	assert.Contains(t, out, "2 create/destroy update")
+	assert.Contains(t, out, `Task: "service"`)
+	assert.Contains(t, out, `image: "image:1" => "image:2"`)
+	assert.NotContains(t, out, "port_map")
+	assert.Contains(t, out, `Failed to place all allocations for task group "service_test1"`)
+	assert.Contains(t, out, `Constraint "${meta.node} = app1" filtered 3 nodes`)
+}
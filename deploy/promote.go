@@ -0,0 +1,77 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minus5/svckit/log"
+)
+
+// Promote canaries of the running service deployment.
+// If Dc option is empty deployments in all service datacenters are promoted.
+func Promote(ctx context.Context, o Options) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.selectService,
+		func() error { return w.promote(ctx) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) promote(ctx context.Context) error {
+	dcs, err := w.datacenters()
+	if err != nil {
+		return err
+	}
+	for _, dc := range dcs {
+		log.Info("Promoting service %s in dacenter %s", w.service, dc)
+		d := w.newDeployer(dc)
+		w.deployer = d
+		if err := d.Promote(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Promote canaries of the latest job deployment
+// connect - connects to a Nomad server
+// promote - promotes canaries of the running deployment
+// status - status of the promoted deployment
+func (d *Deployer) Promote(ctx context.Context) error {
+	d.manualPromote = true
+	steps := []func(context.Context) error{
+		d.connect,
+		d.promote,
+		d.status,
+	}
+	return runContextSteps(ctx, steps)
+}
+
+// promote latest job deployment
+func (d *Deployer) promote(_ context.Context) error {
+	dep, _, err := d.cli.Jobs().LatestDeployment(d.service, nil)
+	if err != nil {
+		return err
+	}
+	if dep == nil {
+		return fmt.Errorf("deployment for job %s not found", d.service)
+	}
+	if dep.Status != DeploymentStatusRunning {
+		return fmt.Errorf("deployment %s is not running, status: %s", dep.ID, dep.Status)
+	}
+	job, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return err
+	}
+	if _, _, err := d.cli.Deployments().PromoteAll(dep.ID, nil); err != nil {
+		return err
+	}
+	d.job = job
+	d.jobDeploymentID = dep.ID
+	d.log().S("deploymentID", dep.ID).Info("deployment promoted")
+	return nil
+}
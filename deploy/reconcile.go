@@ -0,0 +1,104 @@
+package deploy
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/log"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Reconcile watches deployment repository and deploys services
+// whose image in config.yml differs from the one running in Nomad,
+// or whose config is changed since the previous check.
+// Runs until ctx is canceled.
+func Reconcile(ctx context.Context, o Options, interval time.Duration) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	o.Yes = true
+	w := newWorker(o)
+	if err := w.pull(); err != nil {
+		return done(err)
+	}
+	configs := make(map[string]string)
+	for {
+		if err := w.reconcile(ctx, configs); err != nil {
+			log.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return done(nil)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// reconcile deploys drifted services
+// configs are service configs marshaled in the previous run, by datacenter and service
+func (w *Worker) reconcile(ctx context.Context, configs map[string]string) error {
+	if err := w.pullChanges(); err != nil {
+		return err
+	}
+	c, err := w.configSource().Load(w.deployment, w.env)
+	if err != nil {
+		return err
+	}
+	w.depConfig = c
+	var dcs []string
+	for dc := range c.Datacenters {
+		dcs = append(dcs, dc)
+	}
+	sort.Strings(dcs)
+	for _, dc := range dcs {
+		if w.dc != "" && dc != w.dc {
+			continue
+		}
+		for name, s := range c.Datacenters[dc].Services {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			key := dc + "/" + name
+			buf, _ := yaml.Marshal(s)
+			prev, seen := configs[key]
+			configs[key] = string(buf)
+
+			w.service = name
+			w.image = s.Image
+			d := w.newDeployer(dc)
+			running, err := d.runningImage(ctx)
+			if err != nil {
+				log.S("service", name).S("dc", dc).Error(err)
+				continue
+			}
+			changed := seen && prev != string(buf)
+			if running == s.Image && !changed {
+				continue
+			}
+			log.S("service", name).S("dc", dc).S("running", running).S("image", s.Image).Info("drift detected, deploying")
+			if err := w.goDeployer(ctx, d); err != nil {
+				log.S("service", name).S("dc", dc).Error(err)
+				delete(configs, key) // retry on the next run
+			}
+		}
+	}
+	return nil
+}
+
+// runningImage returns image of the service job running in Nomad,
+// empty if the job is not found
+func (d *Deployer) runningImage(ctx context.Context) (string, error) {
+	if err := d.connect(ctx); err != nil {
+		return "", err
+	}
+	job, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "404") {
+			// job is not yet deployed
+			return "", nil
+		}
+		return "", err
+	}
+	return jobImage(job, d.service), nil
+}
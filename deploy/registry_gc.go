@@ -0,0 +1,147 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/minus5/svckit/log"
+)
+
+// RegistryGC deletes old service image tags from registry.
+// The newest keep tags and tags or digests referenced by any Nomad job version
+// in the service datacenters are kept.
+func RegistryGC(ctx context.Context, o Options, keep int) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.selectService,
+		func() error { return w.registryGC(ctx, keep) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) registryGC(ctx context.Context, keep int) error {
+	referenced, digests, err := w.referencedImages(ctx)
+	if err != nil {
+		return err
+	}
+	i, err := NewImage(w.registryURL, w.service, "")
+	if err != nil {
+		return err
+	}
+	candidates := gcCandidates(i.tags, referenced, keep)
+	if len(candidates) == 0 {
+		log.I("tags", len(i.tags)).Info("nothing to delete")
+		return nil
+	}
+
+	// deleting manifest deletes all of its tags,
+	// keep digests pinned in job versions and digests of the referenced tags
+	keepDigests := digests
+	for _, t := range i.tags {
+		if !candidates[t.tag] {
+			if d, err := manifestDigest(w.registryURL, w.service, t.tag); err == nil {
+				keepDigests[d] = true
+			}
+		}
+	}
+
+	var tags []string
+	for t := range candidates {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	deleted := 0
+	for _, t := range tags {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		digest, err := manifestDigest(w.registryURL, w.service, t)
+		if err != nil {
+			log.S("tag", t).Error(err)
+			continue
+		}
+		if keepDigests[digest] {
+			log.S("tag", t).Debug("skipping, digest is referenced")
+			continue
+		}
+		if w.dryRun {
+			fmt.Printf("would delete %s:%s\n", w.service, t)
+			continue
+		}
+		rsp, err := manifestRequest(http.MethodDelete, w.registryURL, w.service, digest)
+		if err != nil {
+			return err
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode != http.StatusAccepted {
+			log.S("tag", t).Error(fmt.Errorf("delete failed: %s", rsp.Status))
+			continue
+		}
+		deleted++
+		log.S("tag", t).Debug("deleted")
+	}
+	log.I("deleted", deleted).I("tags", len(i.tags)).Info("registry cleaned")
+	return nil
+}
+
+// referencedImages finds image tags and digests of all job versions in service datacenters.
+// Digest is referenced by image pinned to digest or recorded in job meta.
+func (w *Worker) referencedImages(ctx context.Context) (tags, digests map[string]bool, err error) {
+	dcs, err := w.datacenters()
+	if err != nil {
+		return nil, nil, err
+	}
+	tags = make(map[string]bool)
+	digests = make(map[string]bool)
+	for _, dc := range dcs {
+		d := w.newDeployer(dc)
+		if err := d.connect(ctx); err != nil {
+			return nil, nil, err
+		}
+		versions, _, _, err := d.cli.Jobs().Versions(w.service, false, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, v := range versions {
+			tag, digest := imageRef(jobImage(v, w.service))
+			if tag != "" {
+				tags[tag] = true
+			}
+			if digest != "" {
+				digests[digest] = true
+			}
+			if digest := v.Meta[ImageDigestMeta]; digest != "" {
+				digests[digest] = true
+			}
+		}
+	}
+	return tags, digests, nil
+}
+
+// imageRef splits image to its tag and digest, both are empty if not set
+func imageRef(image string) (tag, digest string) {
+	if i := strings.Index(image, "@"); i > 0 {
+		image, digest = image[:i], image[i+1:]
+	}
+	_, _, tag = splitImage(image)
+	return tag, digest
+}
+
+// gcCandidates returns tags which can be deleted,
+// tags are expected to be sorted from the newest
+func gcCandidates(ts []Tag, referenced map[string]bool, keep int) map[string]bool {
+	c := make(map[string]bool)
+	for i, t := range ts {
+		if i < keep || referenced[t.tag] || t.created.IsZero() {
+			continue
+		}
+		c[t.tag] = true
+	}
+	return c
+}
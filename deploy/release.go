@@ -0,0 +1,134 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// BuildConfig describes how to build service docker image
+type BuildConfig struct {
+	Context    string `yaml:"context,omitempty"`    // service repository path
+	Dockerfile string `yaml:"dockerfile,omitempty"` // relative to context, default Dockerfile
+}
+
+// Release builds service image, pushes it to registry and deploys it
+func Release(ctx context.Context, o Options) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	o.Image = ""
+	w := newWorker(o)
+	w.build = true
+	return done(runWorker(ctx, w))
+}
+
+// sourceDir is service repository path from options, config or current dir
+func (w *Worker) sourceDir() string {
+	dir := w.src
+	if b := w.serviceConfig.Build; dir == "" && b != nil {
+		dir = b.Context
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return env.ExpandPath(dir)
+}
+
+// buildImage builds and pushes service image when releasing.
+// Image is tagged with build time and git sha of the service repository,
+// in the same format as the other images in registry.
+func (w *Worker) buildImage() error {
+	if !w.build {
+		return nil
+	}
+	dir, dockerfile := w.sourceDir(), "Dockerfile"
+	if b := w.serviceConfig.Build; b != nil && b.Dockerfile != "" {
+		dockerfile = b.Dockerfile
+	}
+	sha := repoSha(dir)
+	if sha == "" {
+		return fmt.Errorf("%s is not a git repository", dir)
+	}
+	image := fmt.Sprintf("%s/%s:%s.%s", w.registryURL, w.service, time.Now().Format("20060102150405"), sha)
+
+	log.S("image", image).S("context", dir).Info("building image")
+	if err := docker("build", "-t", image, "-f", filepath.Join(dir, dockerfile), dir); err != nil {
+		return err
+	}
+	log.S("image", image).Info("pushing image")
+	if err := docker("push", image); err != nil {
+		return err
+	}
+	w.image = image
+	return nil
+}
+
+func docker(args ...string) error {
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = termOut
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker %s failed: %v", args[0], err)
+	}
+	return nil
+}
+
+// gitImage finds image built from the source repository head.
+// Image tags are build time followed by git shas, like 20160613151056.99a146a,
+// or exact git tag of the head commit.
+func (w *Worker) gitImage() error {
+	dir := w.sourceDir()
+	sha := repoSha(dir)
+	if sha == "" {
+		return fmt.Errorf("%s is not a git repository", dir)
+	}
+	i, err := NewImage(w.registryURL, w.service, "")
+	if err != nil {
+		return err
+	}
+	tag := findGitTag(i.tags, sha, gitTag(dir))
+	if tag == "" {
+		return fmt.Errorf("image of %s commit %s not found in registry %s", w.service, sha, w.registryURL)
+	}
+	w.image = fmt.Sprintf("%s/%s:%s", w.registryURL, w.service, tag)
+	log.S("image", w.image).S("sha", sha).Info("image found from git")
+	return nil
+}
+
+// findGitTag finds the newest image tag containing git sha, or equal to git tag
+func findGitTag(ts []Tag, sha, gitTag string) string {
+	for _, t := range ts {
+		if t.created.IsZero() {
+			continue
+		}
+		for _, p := range strings.Split(t.tag, ".")[1:] {
+			if len(p) >= 7 && (strings.HasPrefix(sha, p) || strings.HasPrefix(p, sha)) {
+				return t.tag
+			}
+		}
+	}
+	for _, t := range ts {
+		if gitTag != "" && t.tag == gitTag {
+			return t.tag
+		}
+	}
+	return ""
+}
+
+// gitTag returns tag of the repository head commit, if exists
+func gitTag(dir string) string {
+	cmd := exec.Command("git", "describe", "--tags", "--exact-match", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
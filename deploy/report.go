@@ -0,0 +1,118 @@
+package deploy
+
+import (
+	"sync"
+	"time"
+)
+
+// Report is machine readable result of the deployment process
+type Report struct {
+	Deployment  string       `json:"deployment" yaml:"deployment"`
+	Service     string       `json:"service" yaml:"service"`
+	Image       string       `json:"image" yaml:"image"`
+	Started     time.Time    `json:"started" yaml:"started"`
+	Duration    string       `json:"duration" yaml:"duration"`
+	Steps       []StepReport `json:"steps" yaml:"steps"`
+	Datacenters []DcReport   `json:"datacenters" yaml:"datacenters"`
+	Error       string       `json:"error,omitempty" yaml:"error,omitempty"`
+
+	mu sync.Mutex
+}
+
+// StepReport is result of the deployment step
+type StepReport struct {
+	Name     string `json:"name" yaml:"name"`
+	Duration string `json:"duration" yaml:"duration"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// DcReport is result of the deployment in datacenter
+type DcReport struct {
+	Dc           string        `json:"dc" yaml:"dc"`
+	EvalID       string        `json:"eval_id,omitempty" yaml:"eval_id,omitempty"`
+	DeploymentID string        `json:"deployment_id,omitempty" yaml:"deployment_id,omitempty"`
+	Duration     string        `json:"duration" yaml:"duration"`
+	Allocs       []AllocReport `json:"allocs,omitempty" yaml:"allocs,omitempty"`
+	Error        string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// AllocReport is status of the allocation created by deployment
+type AllocReport struct {
+	ID           string `json:"id" yaml:"id"`
+	TaskGroup    string `json:"task_group" yaml:"task_group"`
+	ClientStatus string `json:"client_status" yaml:"client_status"`
+	Healthy      *bool  `json:"healthy,omitempty" yaml:"healthy,omitempty"`
+}
+
+func newReport() *Report {
+	return &Report{Started: time.Now()}
+}
+
+func (r *Report) addStep(name string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Steps = append(r.Steps, StepReport{Name: name, Duration: d.String(), Error: errString(err)})
+}
+
+func (r *Report) addDc(d *Deployer, du time.Duration, err error) {
+	dr := DcReport{
+		Dc:           d.dc,
+		EvalID:       d.jobEvalID,
+		DeploymentID: d.jobDeploymentID,
+		Duration:     du.String(),
+		Allocs:       d.allocReports(),
+		Error:        errString(err),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Datacenters = append(r.Datacenters, dr)
+}
+
+// write report to stdout
+func (r *Report) write(w *Worker, err error) {
+	r.Deployment = w.deployment
+	r.Service = w.service
+	r.Image = w.image
+	r.Duration = time.Since(r.Started).String()
+	r.Error = errString(err)
+	render(w.output, r, nil)
+}
+
+// allocReports finds allocations created by deployment or job evaluation
+func (d *Deployer) allocReports() []AllocReport {
+	if d.cli == nil {
+		return nil
+	}
+	var ars []AllocReport
+	if d.jobDeploymentID != "" {
+		al, _, err := d.cli.Deployments().Allocations(d.jobDeploymentID, nil)
+		if err != nil {
+			return nil
+		}
+		for _, a := range al {
+			ar := AllocReport{ID: a.ID, TaskGroup: a.TaskGroup, ClientStatus: a.ClientStatus}
+			if a.DeploymentStatus != nil {
+				ar.Healthy = a.DeploymentStatus.Healthy
+			}
+			ars = append(ars, ar)
+		}
+		return ars
+	}
+	if d.jobEvalID != "" {
+		al, _, err := d.cli.Evaluations().Allocations(d.jobEvalID, nil)
+		if err != nil {
+			return nil
+		}
+		for _, a := range al {
+			ars = append(ars, AllocReport{ID: a.ID, TaskGroup: a.TaskGroup, ClientStatus: a.ClientStatus})
+		}
+	}
+	return ars
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
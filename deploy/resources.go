@@ -0,0 +1,86 @@
+package deploy
+
+import (
+	"os"
+	"sort"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/minus5/svckit/log"
+)
+
+// setResources overrides task resources, ports and network mode with the ones from service config.
+// Ports with zero value are dynamic, others are static (reserved).
+func setResources(ta *api.Task, s *ServiceConfig) {
+	if ta.Resources == nil {
+		ta.Resources = &api.Resources{}
+	}
+	if s.CPU != 0 {
+		ta.Resources.CPU = &s.CPU
+		log.I("cpu", s.CPU).Debug("setting")
+	}
+	if s.Memory != 0 {
+		ta.Resources.MemoryMB = &s.Memory
+		log.I("memory", s.Memory).Debug("setting")
+	}
+	if s.NetworkMode != "" {
+		ta.Config["network_mode"] = s.NetworkMode
+		log.S("network_mode", s.NetworkMode).Debug("setting")
+	}
+	if len(s.Ports) == 0 {
+		return
+	}
+	if len(ta.Resources.Networks) == 0 {
+		ta.Resources.Networks = []*api.NetworkResource{{}}
+	}
+	n := ta.Resources.Networks[0]
+	var labels []string
+	for l := range s.Ports {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	for _, l := range labels {
+		n.ReservedPorts = removePort(n.ReservedPorts, l)
+		n.DynamicPorts = removePort(n.DynamicPorts, l)
+		p := api.Port{Label: l, Value: s.Ports[l]}
+		if p.Value > 0 {
+			n.ReservedPorts = append(n.ReservedPorts, p)
+		} else {
+			n.DynamicPorts = append(n.DynamicPorts, p)
+		}
+		log.S("label", l).I("port", p.Value).Debug("setting port")
+	}
+}
+
+func removePort(ports []api.Port, label string) []api.Port {
+	var ps []api.Port
+	for _, p := range ports {
+		if p.Label != label {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// RegistryAuth is Docker driver auth for private registry.
+// Values are expanded from environment variables, like ${REGISTRY_PASSWORD}.
+type RegistryAuth struct {
+	Username      string `yaml:"username"`
+	Password      string `yaml:"password"`
+	ServerAddress string `yaml:"server_address,omitempty"`
+}
+
+// setRegistryAuth sets Docker driver auth block
+func setRegistryAuth(ta *api.Task, a *RegistryAuth) {
+	if a == nil {
+		return
+	}
+	auth := map[string]interface{}{
+		"username": os.ExpandEnv(a.Username),
+		"password": os.ExpandEnv(a.Password),
+	}
+	if a.ServerAddress != "" {
+		auth["server_address"] = os.ExpandEnv(a.ServerAddress)
+	}
+	ta.Config["auth"] = []map[string]interface{}{auth}
+	log.S("username", auth["username"].(string)).Debug("setting registry auth")
+}
@@ -0,0 +1,45 @@
+package deploy
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetResources(t *testing.T) {
+	ta := &api.Task{
+		Config: map[string]interface{}{},
+		Resources: &api.Resources{
+			Networks: []*api.NetworkResource{{
+				DynamicPorts: []api.Port{{Label: "http"}, {Label: "debug"}},
+			}},
+		},
+	}
+	s := &ServiceConfig{
+		CPU:         64,
+		Memory:      128,
+		NetworkMode: "host",
+		Ports:       map[string]int{"http": 8080, "grpc": 0},
+	}
+	setResources(ta, s)
+
+	assert.Equal(t, 64, *ta.Resources.CPU)
+	assert.Equal(t, 128, *ta.Resources.MemoryMB)
+	assert.Equal(t, "host", ta.Config["network_mode"])
+	n := ta.Resources.Networks[0]
+	assert.Equal(t, []api.Port{{Label: "http", Value: 8080}}, n.ReservedPorts)
+	assert.Equal(t, []api.Port{{Label: "debug"}, {Label: "grpc"}}, n.DynamicPorts)
+}
+
+func TestSetRegistryAuth(t *testing.T) {
+	os.Setenv("TEST_REGISTRY_PASSWORD", "secret")
+	defer os.Unsetenv("TEST_REGISTRY_PASSWORD")
+	ta := &api.Task{Config: map[string]interface{}{}}
+	setRegistryAuth(ta, &RegistryAuth{Username: "deployer", Password: "${TEST_REGISTRY_PASSWORD}"})
+	auth := ta.Config["auth"].([]map[string]interface{})
+	assert.Equal(t, "deployer", auth[0]["username"])
+	assert.Equal(t, "secret", auth[0]["password"])
+	assert.NotContains(t, auth[0], "server_address")
+}
@@ -0,0 +1,63 @@
+package deploy
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/minus5/svckit/log"
+)
+
+// RestartedMeta is job meta key changed to restart all allocations
+const RestartedMeta = "restarted_at"
+
+// Restart restarts service allocations in Dc, or in all service datacenters
+// if Dc is empty. Nomad api has no alloc restart endpoint so running job is
+// re-registered with changed meta value, which replaces allocations one by one
+// (by job update stanza) waiting for health between them.
+func Restart(ctx context.Context, o Options, reason string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.selectService,
+		func() error { return w.restart(ctx, reason) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) restart(ctx context.Context, reason string) error {
+	dcs, err := w.datacenters()
+	if err != nil {
+		return err
+	}
+	for _, dc := range dcs {
+		log.S("service", w.service).S("dc", dc).S("reason", reason).Info("restarting")
+		d := w.newDeployer(dc)
+		w.deployer = d
+		t := time.Now()
+		err := d.Restart(ctx)
+		w.auditAction("restart", reason, d, t, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restart re-registers running job with changed restart meta
+// and waits for the deployment.
+func (d *Deployer) Restart(ctx context.Context) error {
+	steps := []func(context.Context) error{
+		exitStep(ExitConnection, d.connect),
+		exitStep(ExitFailed, func(ctx context.Context) error {
+			return d.reregister(ctx, func(job *api.Job) error {
+				job.SetMeta(RestartedMeta, time.Now().Format(time.RFC3339))
+				return nil
+			})
+		}),
+		exitStep(ExitFailed, d.status),
+	}
+	return runContextSteps(ctx, steps)
+}
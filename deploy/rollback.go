@@ -0,0 +1,178 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/minus5/svckit/log"
+)
+
+// Rollback reverts service to the previous stable job version
+// or to the toVersion if it is not negative.
+// If Dc option is empty service is reverted in all of its datacenters.
+func Rollback(ctx context.Context, o Options, toVersion int) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.selectService,
+		func() error { return w.rollback(ctx, toVersion) },
+		w.pullChanges,
+		w.updateDepConfig,
+		func() error {
+			return w.commit(fmt.Sprintf("rolled back %s in %s", w.service, w.deployment))
+		},
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) rollback(ctx context.Context, toVersion int) error {
+	dcs, err := w.datacenters()
+	if err != nil {
+		return err
+	}
+	for _, dc := range dcs {
+		log.Info("Rolling back service %s in dacenter %s", w.service, dc)
+		d := w.newDeployer(dc)
+		w.deployer = d
+		if err := d.Rollback(ctx, toVersion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback re-registers previous job version
+// connect - connects to a Nomad server
+// revert - reverts job to the previous stable or selected version
+// status - status of the reverted job
+func (d *Deployer) Rollback(ctx context.Context, toVersion int) error {
+	steps := []func(context.Context) error{
+		d.connect,
+		func(ctx context.Context) error { return d.revert(ctx, toVersion) },
+		d.status,
+	}
+	return runContextSteps(ctx, steps)
+}
+
+// revert job to the selected version
+// Service image in the datacenter config is set to the reverted one.
+func (d *Deployer) revert(ctx context.Context, toVersion int) error {
+	versions, _, _, err := d.cli.Jobs().Versions(d.service, false, nil)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("job %s not found", d.service)
+	}
+	current := versions[0]
+	target := findRollbackVersion(versions, toVersion)
+	if target == nil {
+		if toVersion >= 0 {
+			return fmt.Errorf("version %d of job %s not found", toVersion, d.service)
+		}
+		return fmt.Errorf("job %s has no previous version", d.service)
+	}
+
+	jr, _, err := d.cli.Jobs().Revert(d.service, *target.Version, current.Version, nil)
+	if err != nil {
+		return err
+	}
+	d.job = target
+	d.jobEvalID = jr.EvalID
+	if err := d.getDeploymentID(ctx); err != nil {
+		return err
+	}
+
+	if d.image = jobImage(target, d.service); d.image != "" {
+		if s := d.config.FindForDc(d.service, d.cdc); s != nil {
+			s.Image = d.image
+		}
+	}
+	d.log().I("from", int(*current.Version)).
+		I("to", int(*target.Version)).
+		S("image", d.image).
+		S("deploymentID", d.jobDeploymentID).
+		Info("job reverted")
+	return nil
+}
+
+// statusOrRevert waits for deployment to finish and runs smoke test.
+// If deployment fails and auto revert is enabled job is reverted
+// to the previous stable version.
+func (d *Deployer) statusOrRevert(ctx context.Context) error {
+	err := d.status(ctx)
+	if err == nil {
+		d.timeToHealthy = time.Since(d.registered)
+		err = d.smokeTest(ctx)
+	}
+	if err == nil || ctx.Err() != nil || !d.shouldAutoRevert() {
+		return err
+	}
+	d.log().Error(err)
+	d.log().S("deploymentID", d.jobDeploymentID).Info("auto reverting job")
+	if rerr := d.revert(ctx, -1); rerr != nil {
+		return fmt.Errorf("%v, auto revert failed: %v", err, rerr)
+	}
+	if rerr := d.status(ctx); rerr != nil {
+		return fmt.Errorf("%v, auto revert failed: %v", err, rerr)
+	}
+	return fmt.Errorf("%v, reverted to version %d", err, *d.job.Version)
+}
+
+// shouldAutoRevert is auto revert enabled by flag or in datacenter config
+func (d *Deployer) shouldAutoRevert() bool {
+	if d.isBatch() {
+		return false
+	}
+	if d.autoRevert {
+		return true
+	}
+	s := d.config.FindForDc(d.service, d.cdc)
+	return s != nil && s.AutoRevert
+}
+
+// findRollbackVersion finds job version to revert to.
+// Versions are expected to be sorted from the newest (current) one.
+// If toVersion is negative the latest stable version before current is selected,
+// or the one just before current if there is no stable version.
+func findRollbackVersion(versions []*api.Job, toVersion int) *api.Job {
+	if toVersion >= 0 {
+		for _, v := range versions {
+			if v.Version != nil && *v.Version == uint64(toVersion) {
+				return v
+			}
+		}
+		return nil
+	}
+	if len(versions) < 2 {
+		return nil
+	}
+	for _, v := range versions[1:] {
+		if v.Stable != nil && *v.Stable {
+			return v
+		}
+	}
+	return versions[1]
+}
+
+// jobImage returns image of the service task in job
+func jobImage(job *api.Job, service string) string {
+	for _, tg := range job.TaskGroups {
+		if tg.Name == nil || !(*tg.Name == service || *tg.Name == "services") {
+			continue
+		}
+		for _, ta := range tg.Tasks {
+			if !(ta.Name == service || ta.Name == "service") {
+				continue
+			}
+			if image, ok := ta.Config["image"].(string); ok {
+				return image
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testJobVersion(version uint64, stable bool) *api.Job {
+	return &api.Job{Version: &version, Stable: &stable}
+}
+
+func TestFindRollbackVersion(t *testing.T) {
+	versions := []*api.Job{
+		testJobVersion(4, false),
+		testJobVersion(3, false),
+		testJobVersion(2, true),
+		testJobVersion(1, true),
+	}
+
+	// latest stable before current
+	v := findRollbackVersion(versions, -1)
+	assert.Equal(t, uint64(2), *v.Version)
+
+	// exact version
+	v = findRollbackVersion(versions, 3)
+	assert.Equal(t, uint64(3), *v.Version)
+
+	// non existing version
+	assert.Nil(t, findRollbackVersion(versions, 7))
+
+	// no stable version, one before current
+	v = findRollbackVersion(versions[:2], -1)
+	assert.Equal(t, uint64(3), *v.Version)
+
+	// only current version
+	assert.Nil(t, findRollbackVersion(versions[:1], -1))
+}
@@ -0,0 +1,158 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/minus5/svckit/log"
+)
+
+// Scale sets count of the service task group in Dc, or in all service
+// datacenters if Dc is empty, and waits for the allocations.
+// Nomad api has no scaling endpoint so running job is re-registered with the new count.
+// If save is set count is also written to the datacenter config so it survives next deploy.
+func Scale(ctx context.Context, o Options, group string, count int, reason string, save bool) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.selectService,
+		func() error { return w.scale(ctx, group, count, reason, save) },
+	}
+	if save {
+		steps = append(steps,
+			w.pullChanges,
+			w.updateDepConfig,
+			func() error {
+				return w.commit(fmt.Sprintf("scaled %s in %s to %d", w.service, w.deployment, count))
+			})
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) scale(ctx context.Context, group string, count int, reason string, save bool) error {
+	dcs, err := w.datacenters()
+	if err != nil {
+		return err
+	}
+	for _, dc := range dcs {
+		log.S("service", w.service).S("dc", dc).I("count", count).S("reason", reason).Info("scaling")
+		d := w.newDeployer(dc)
+		w.deployer = d
+		t := time.Now()
+		err := d.Scale(ctx, group, count)
+		w.auditAction("scale", reason, d, t, err)
+		if err != nil {
+			return err
+		}
+		if s := w.depConfig.FindForDc(w.service, dc); save && s != nil {
+			s.Count = count
+		}
+	}
+	return nil
+}
+
+// Scale re-registers running job with the new task group count
+// and waits for the deployment.
+func (d *Deployer) Scale(ctx context.Context, group string, count int) error {
+	steps := []func(context.Context) error{
+		exitStep(ExitConnection, d.connect),
+		exitStep(ExitFailed, func(ctx context.Context) error { return d.scaleJob(ctx, group, count) }),
+		exitStep(ExitFailed, d.status),
+	}
+	return runContextSteps(ctx, steps)
+}
+
+func (d *Deployer) scaleJob(ctx context.Context, group string, count int) error {
+	return d.reregister(ctx, func(job *api.Job) error {
+		tg, err := scaleGroup(job, d.service, group)
+		if err != nil {
+			return WithExitCode(ExitValidation, err)
+		}
+		from := 0
+		if tg.Count != nil {
+			from = *tg.Count
+		}
+		tg.Count = &count
+		d.log().S("group", *tg.Name).I("from", from).I("to", count).Info("scaling job")
+		return nil
+	})
+}
+
+// reregister registers running job changed by modify
+// and waits for the evaluation to create deployment.
+func (d *Deployer) reregister(ctx context.Context, modify func(*api.Job) error) error {
+	job, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return err
+	}
+	if err := modify(job); err != nil {
+		return err
+	}
+	jr, _, err := d.cli.Jobs().EnforceRegister(job, *job.JobModifyIndex, nil)
+	if err != nil {
+		if isConflict(err) {
+			return WithExitCode(ExitConflict, err)
+		}
+		return err
+	}
+	d.job = job
+	d.image = jobImage(job, d.service)
+	d.jobEvalID = jr.EvalID
+	if err := d.evalDeploymentID(ctx); err != nil {
+		return err
+	}
+	d.log().S("evalID", jr.EvalID).S("deploymentID", d.jobDeploymentID).Info("job registered")
+	return nil
+}
+
+// evalDeploymentID waits for evaluation to finish and sets its deployment.
+// Changes like scaling down don't have to create deployment.
+func (d *Deployer) evalDeploymentID(ctx context.Context) error {
+	q := d.blockingQuery()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ev, meta, err := d.cli.Evaluations().Info(d.jobEvalID, q)
+		if err != nil {
+			return err
+		}
+		if ev.DeploymentID != "" {
+			d.jobDeploymentID = ev.DeploymentID
+			return nil
+		}
+		switch ev.Status {
+		case "complete", "failed", "canceled":
+			return nil
+		}
+		q.WaitIndex = meta.LastIndex
+	}
+}
+
+// scaleGroup finds task group by name, or the only one,
+// or the one named as service
+func scaleGroup(job *api.Job, service, group string) (*api.TaskGroup, error) {
+	var names []string
+	for _, tg := range job.TaskGroups {
+		if tg.Name != nil {
+			names = append(names, *tg.Name)
+		}
+	}
+	if group == "" && len(job.TaskGroups) == 1 {
+		return job.TaskGroups[0], nil
+	}
+	if group == "" {
+		group = service
+	}
+	for _, tg := range job.TaskGroups {
+		if tg.Name != nil && *tg.Name == group {
+			return tg, nil
+		}
+	}
+	return nil, fmt.Errorf("task group %s not found in job %s, groups: %s", group, service, strings.Join(names, ", "))
+}
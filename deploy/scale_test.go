@@ -0,0 +1,23 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleGroup(t *testing.T) {
+	job := api.NewServiceJob("api", "api", "global", 50)
+	job.AddTaskGroup(api.NewTaskGroup("api", 2))
+	tg, err := scaleGroup(job, "api", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "api", *tg.Name)
+
+	job.AddTaskGroup(api.NewTaskGroup("worker", 1))
+	tg, err = scaleGroup(job, "api", "worker")
+	assert.Nil(t, err)
+	assert.Equal(t, "worker", *tg.Name)
+	_, err = scaleGroup(job, "other", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	vapi "github.com/hashicorp/vault/api"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// defaultSecretPath is Vault path of the service secrets, in kv v2 engine
+const defaultSecretPath = "secret/data/{service}"
+
+// vaultTokenFile is where vault login stores token
+const vaultTokenFile = "~/.vault-token"
+
+// SecretsConfig configures Vault secrets of the services
+type SecretsConfig struct {
+	Address string `yaml:"address,omitempty"` // Vault address, VAULT_ADDR if empty
+	Path    string `yaml:"path,omitempty"`    // secret path of the service, {service} is replaced with service name
+}
+
+// secretPath returns Vault path of the service secret
+func (c *SecretsConfig) secretPath(service string) string {
+	p := defaultSecretPath
+	if c != nil && c.Path != "" {
+		p = c.Path
+	}
+	return strings.Replace(p, "{service}", service, -1)
+}
+
+// vaultClient connects to Vault with address from config or VAULT_ADDR,
+// and token from VAULT_TOKEN or vault login token file
+func (c *SecretsConfig) vaultClient() (*vapi.Client, error) {
+	cfg := vapi.DefaultConfig()
+	if c != nil && c.Address != "" {
+		cfg.Address = c.Address
+	}
+	cli, err := vapi.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cli.Token() == "" {
+		if buf, err := ioutil.ReadFile(env.ExpandPath(vaultTokenFile)); err == nil {
+			cli.SetToken(strings.TrimSpace(string(buf)))
+		}
+	}
+	return cli, nil
+}
+
+// SecretGet writes value of the service secret field to stdout,
+// or all fields as env file if field is empty
+func SecretGet(ctx context.Context, o Options, field string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.loadDepConfig,
+		func() error {
+			path, data, err := w.readSecret()
+			if err != nil {
+				return err
+			}
+			if field == "" {
+				writeEnvFile(os.Stdout, data)
+				return nil
+			}
+			v, ok := data[field]
+			if !ok {
+				return WithExitCode(ExitValidation, fmt.Errorf("field %s not found in %s", field, path))
+			}
+			fmt.Fprintf(os.Stdout, "%v\n", v)
+			return nil
+		},
+	}
+	if err := runSteps(steps); err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// SecretList shows fields of the service secret, without values,
+// with references to use in service environment config
+func SecretList(ctx context.Context, o Options) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.loadDepConfig,
+		func() error {
+			path, data, err := w.readSecret()
+			if err != nil {
+				return err
+			}
+			refs := make(map[string]string)
+			for k := range data {
+				refs[k] = fmt.Sprintf("%s%s#%s", vaultRefPrefix, path, k)
+			}
+			return render(o.Output, refs, func(w io.Writer) {
+				for _, k := range sortedFields(data) {
+					fmt.Fprintf(w, "%-30s %s\n", k, faint(refs[k]))
+				}
+			})
+		},
+	}
+	if err := runSteps(steps); err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// SecretPut sets fields of the service secret, other fields are kept.
+// Change, without values, is recorded in the deployment audit log.
+func SecretPut(ctx context.Context, o Options, fields map[string]string, reason string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	if len(fields) == 0 {
+		return done(WithExitCode(ExitValidation, errors.New("no secret fields to put")))
+	}
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.loadDepConfig,
+		func() error { return w.secretPut(fields, reason) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) secretPut(fields map[string]string, reason string) error {
+	t := time.Now()
+	path, err := w.writeSecret(fields)
+	var names []string
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	// field names are recorded with reason, values never
+	r := strings.Join(names, ",")
+	if reason != "" {
+		r += ": " + reason
+	}
+	w.auditAction("secret put", r, &Deployer{dc: w.dc}, t, err)
+	if err != nil {
+		return err
+	}
+	log.S("path", path).S("fields", strings.Join(names, ",")).Info("secret updated")
+	return nil
+}
+
+func (w *Worker) secretsConfig() *SecretsConfig {
+	if w.depConfig == nil {
+		return nil
+	}
+	return w.depConfig.Secrets
+}
+
+// readSecret returns path and fields of the service secret,
+// missing secret has no fields
+func (w *Worker) readSecret() (string, map[string]interface{}, error) {
+	c := w.secretsConfig()
+	path := c.secretPath(w.service)
+	cli, err := c.vaultClient()
+	if err != nil {
+		return path, nil, WithExitCode(ExitConnection, err)
+	}
+	s, err := cli.Logical().Read(path)
+	if err != nil {
+		return path, nil, WithExitCode(ExitConnection, err)
+	}
+	return path, secretData(path, s), nil
+}
+
+// writeSecret merges fields into the service secret
+func (w *Worker) writeSecret(fields map[string]string) (string, error) {
+	path, data, err := w.readSecret()
+	if err != nil {
+		return path, err
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	var body map[string]interface{} = data
+	if kvV2(path) {
+		body = map[string]interface{}{"data": data}
+	}
+	cli, err := w.secretsConfig().vaultClient()
+	if err != nil {
+		return path, WithExitCode(ExitConnection, err)
+	}
+	if _, err := cli.Logical().Write(path, body); err != nil {
+		return path, WithExitCode(ExitConnection, err)
+	}
+	return path, nil
+}
+
+// kvV2 checks is path in kv v2 engine, same as in env templates
+func kvV2(path string) bool {
+	return strings.Contains(path, "/data/")
+}
+
+// secretData returns fields of the secret, kv v2 fields are under data
+func secretData(path string, s *vapi.Secret) map[string]interface{} {
+	data := make(map[string]interface{})
+	if s == nil || s.Data == nil {
+		return data
+	}
+	src := s.Data
+	if kvV2(path) {
+		m, ok := s.Data["data"].(map[string]interface{})
+		if !ok {
+			return data
+		}
+		src = m
+	}
+	for k, v := range src {
+		data[k] = v
+	}
+	return data
+}
+
+func sortedFields(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeEnvFile(w io.Writer, data map[string]interface{}) {
+	for _, k := range sortedFields(data) {
+		fmt.Fprintf(w, "%s=%v\n", k, data[k])
+	}
+}
+
+// ReadEnvFile reads NAME=value lines, empty lines and # comments are skipped.
+// Values can be quoted.
+func ReadEnvFile(r io.Reader) (map[string]string, error) {
+	m := make(map[string]string)
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid line %d, expecting NAME=value", n)
+		}
+		k, v := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		m[k] = v
+	}
+	return m, s.Err()
+}
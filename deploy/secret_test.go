@@ -0,0 +1,31 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	vapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretPath(t *testing.T) {
+	var c *SecretsConfig
+	assert.Equal(t, "secret/data/backend_api", c.secretPath("backend_api"))
+	c = &SecretsConfig{Path: "kv/services/{service}/env"}
+	assert.Equal(t, "kv/services/backend_api/env", c.secretPath("backend_api"))
+}
+
+func TestSecretData(t *testing.T) {
+	s := &vapi.Secret{Data: map[string]interface{}{"data": map[string]interface{}{"password": "x"}}}
+	assert.Equal(t, "x", secretData("secret/data/api", s)["password"])
+	assert.Len(t, secretData("secret/data/api", nil), 0)
+}
+
+func TestReadEnvFile(t *testing.T) {
+	m, err := ReadEnvFile(strings.NewReader("# db\nDB_USER=api\nexport DB_PASSWORD=\"s3 cr3t\"\n\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"DB_USER": "api", "DB_PASSWORD": "s3 cr3t"}, m)
+
+	_, err = ReadEnvFile(strings.NewReader("invalid"))
+	assert.Error(t, err)
+}
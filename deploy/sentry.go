@@ -0,0 +1,114 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/log"
+)
+
+const defaultSentryURL = "https://sentry.io"
+
+// SentryConfig configures Sentry releases and deploys of the deployed services
+type SentryConfig struct {
+	URL         string `yaml:"url,omitempty"`         // Sentry url, default https://sentry.io
+	Token       string `yaml:"token"`                 // auth token with project:releases scope, use ${env:VAR} reference
+	Org         string `yaml:"org"`                   // organization slug
+	Project     string `yaml:"project,omitempty"`     // project slug, {service} is replaced with service name, default {service}
+	Environment string `yaml:"environment,omitempty"` // Sentry environment, default deployment name
+}
+
+func (c *SentryConfig) project(service string) string {
+	p := c.Project
+	if p == "" {
+		p = "{service}"
+	}
+	return strings.Replace(p, "{service}", service, -1)
+}
+
+// sentryVersion is release version of the image, its tag or short digest
+func sentryVersion(image string) string {
+	digest := ""
+	if i := strings.Index(image, "@"); i >= 0 {
+		image, digest = image[:i], strings.TrimPrefix(image[i+1:], "sha256:")
+	}
+	if _, _, tag := splitImage(image); tag != "" {
+		return tag
+	}
+	if len(digest) > 12 {
+		digest = digest[:12]
+	}
+	return digest
+}
+
+// release creates Sentry release of the image, if it doesn't exist,
+// and records its deploy to the environment.
+func (c *SentryConfig) release(service, image, env string, started time.Time) error {
+	version := sentryVersion(image)
+	if version == "" {
+		return fmt.Errorf("no version in image %s", image)
+	}
+	if c.Environment != "" {
+		env = c.Environment
+	}
+	base := strings.TrimSuffix(c.URL, "/")
+	if base == "" {
+		base = defaultSentryURL
+	}
+	base += "/api/0/organizations/" + url.PathEscape(c.Org) + "/releases/"
+	rel := map[string]interface{}{
+		"version":  version,
+		"projects": []string{c.project(service)},
+	}
+	if err := c.post(base, rel); err != nil {
+		return err
+	}
+	dep := map[string]interface{}{
+		"environment":  env,
+		"dateStarted":  started.UTC().Format(time.RFC3339),
+		"dateFinished": time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := c.post(base+url.PathEscape(version)+"/deploys/", dep); err != nil {
+		return err
+	}
+	log.S("version", version).S("environment", env).Info("sentry release deployed")
+	return nil
+}
+
+func (c *SentryConfig) post(u string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	rsp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		return fmt.Errorf("sentry %s failed: %s", u, rsp.Status)
+	}
+	return nil
+}
+
+// sentryRelease records successful deploy in Sentry.
+// Failure is logged, it doesn't break deployment.
+func (w *Worker) sentryRelease(started time.Time) {
+	if w.dryRun || w.depConfig == nil || w.depConfig.Sentry == nil {
+		return
+	}
+	if err := w.depConfig.Sentry.release(w.service, w.image, w.deployment, started); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,37 @@
+package deploy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentryVersion(t *testing.T) {
+	assert.Equal(t, "2019-04-10-1200", sentryVersion("registry.dev.minus5.hr/api:2019-04-10-1200"))
+	assert.Equal(t, "0123456789ab", sentryVersion("registry.dev.minus5.hr/api@sha256:0123456789abcdef"))
+	assert.Equal(t, "", sentryVersion("api"))
+}
+
+func TestSentryRelease(t *testing.T) {
+	var paths []string
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.Path)
+		var m map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&m)
+		bodies = append(bodies, m)
+	}))
+	defer srv.Close()
+
+	c := &SentryConfig{URL: srv.URL, Token: "secret", Org: "minus5", Project: "backend-{service}"}
+	assert.Nil(t, c.release("api", "registry/api:1.0", "s2", time.Now()))
+	assert.Equal(t, []string{"/api/0/organizations/minus5/releases/", "/api/0/organizations/minus5/releases/1.0/deploys/"}, paths)
+	assert.Equal(t, "1.0", bodies[0]["version"])
+	assert.Equal(t, []interface{}{"backend-api"}, bodies[0]["projects"])
+	assert.Equal(t, "s2", bodies[1]["environment"])
+}
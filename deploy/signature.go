@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/minus5/svckit/env"
+)
+
+// CosignConfig is used to verify image signatures with cosign.
+// Key is public key path, or keyless signatures are verified by Identity and Issuer.
+type CosignConfig struct {
+	Key      string `yaml:"key,omitempty"`
+	Identity string `yaml:"identity,omitempty"`
+	Issuer   string `yaml:"issuer,omitempty"`
+}
+
+func (c *CosignConfig) args(image string) ([]string, error) {
+	if c == nil {
+		return nil, fmt.Errorf("cosign config not found")
+	}
+	args := []string{"verify"}
+	switch {
+	case c.Key != "":
+		args = append(args, "--key", env.ExpandPath(c.Key))
+	case c.Identity != "" && c.Issuer != "":
+		args = append(args, "--certificate-identity", c.Identity, "--certificate-oidc-issuer", c.Issuer)
+	default:
+		return nil, fmt.Errorf("cosign key or identity and issuer required")
+	}
+	return append(args, image), nil
+}
+
+// verifySignature refuses to deploy unsigned image if service requires signature.
+// Image is verified by digest so the deployed image is the verified one.
+func (d *Deployer) verifySignature(_ context.Context) error {
+	s := d.config.FindForDc(d.service, d.cdc)
+	if s == nil || !s.RequireSignature {
+		return nil
+	}
+	image := d.image
+	if d.digest != "" {
+		registry, repo, _ := splitImage(d.image)
+		image = fmt.Sprintf("%s/%s@%s", registry, repo, d.digest)
+	}
+	args, err := d.config.Cosign.args(image)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("cosign", args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("image %s signature verification failed: %v", image, err)
+	}
+	d.log().S("image", image).Info("signature verified")
+	return nil
+}
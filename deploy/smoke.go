@@ -0,0 +1,175 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/api"
+)
+
+// SmokeTest is http check run against service instances after successful deployment
+type SmokeTest struct {
+	URL     string `yaml:"url"`               // absolute url or path on each healthy instance of the new deployment
+	Service string `yaml:"service,omitempty"` // Consul service name, defaults to the service
+	Status  int    `yaml:"status,omitempty"`  // expected status code, default 200
+	Timeout string `yaml:"timeout,omitempty"` // request timeout, default 5s
+	Retries int    `yaml:"retries,omitempty"` // retries of the failed check
+}
+
+func (s *SmokeTest) status() int {
+	if s.Status == 0 {
+		return http.StatusOK
+	}
+	return s.Status
+}
+
+func (s *SmokeTest) timeout() time.Duration {
+	if t, err := time.ParseDuration(s.Timeout); err == nil {
+		return t
+	}
+	return 5 * time.Second
+}
+
+// smokeTest runs service smoke test, if configured
+func (d *Deployer) smokeTest(ctx context.Context) error {
+	s := d.config.FindForDc(d.service, d.cdc)
+	if s == nil || s.SmokeTest == nil || d.isBatch() {
+		return nil
+	}
+	st := s.SmokeTest
+	urls := []string{st.URL}
+	if !strings.HasPrefix(st.URL, "http") {
+		name := st.Service
+		if name == "" {
+			name = d.service
+		}
+		addrs, err := d.healthyInstances(name)
+		if err != nil {
+			return err
+		}
+		if d.jobDeploymentID != "" {
+			// skip instances of the previous job version
+			deployed, err := d.deploymentAddrs()
+			if err != nil {
+				return err
+			}
+			addrs = filterAddrs(addrs, deployed)
+		}
+		if len(addrs) == 0 {
+			return fmt.Errorf("smoke test: no healthy instances of %s in %s", name, d.dc)
+		}
+		urls = urls[:0]
+		for _, a := range addrs {
+			urls = append(urls, fmt.Sprintf("http://%s%s", a, st.URL))
+		}
+	}
+	for _, u := range urls {
+		if err := st.check(ctx, u); err != nil {
+			return err
+		}
+		d.log().S("url", u).Info("smoke test passed")
+	}
+	return nil
+}
+
+// check url, retrying failed checks
+func (s *SmokeTest) check(ctx context.Context, url string) error {
+	cli := &http.Client{Timeout: s.timeout()}
+	var err error
+	for i := 0; i <= s.Retries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+		var rsp *http.Response
+		rsp, err = cli.Get(url)
+		if err != nil {
+			continue
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode == s.status() {
+			return nil
+		}
+		err = fmt.Errorf("smoke test %s returned %s, expected %d", url, rsp.Status, s.status())
+	}
+	return err
+}
+
+// healthyInstances finds addresses of the service instances passing Consul health checks
+func (d *Deployer) healthyInstances(name string) ([]string, error) {
+	cli, err := capi.NewClient(&capi.Config{Address: d.consul})
+	if err != nil {
+		return nil, err
+	}
+	ses, _, err := cli.Health().Service(name, "", true, &capi.QueryOptions{Datacenter: d.dc})
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, se := range ses {
+		host := se.Service.Address
+		if host == "" {
+			host = se.Node.Address
+		}
+		addrs = append(addrs, fmt.Sprintf("%s:%d", host, se.Service.Port))
+	}
+	return addrs, nil
+}
+
+// deploymentAddrs finds ip:port addresses of the running allocations of the deployment
+func (d *Deployer) deploymentAddrs() (map[string]bool, error) {
+	stubs, _, err := d.cli.Deployments().Allocations(d.jobDeploymentID, nil)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make(map[string]bool)
+	for _, stub := range stubs {
+		if stub.ClientStatus != "running" {
+			continue
+		}
+		alloc, _, err := d.cli.Allocations().Info(stub.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range allocAddrs(alloc) {
+			addrs[a] = true
+		}
+	}
+	return addrs, nil
+}
+
+// allocAddrs are ip:port addresses of all allocation task ports
+func allocAddrs(alloc *api.Allocation) []string {
+	var addrs []string
+	for _, r := range alloc.TaskResources {
+		if r == nil {
+			continue
+		}
+		for _, n := range r.Networks {
+			for _, ports := range [][]api.Port{n.ReservedPorts, n.DynamicPorts} {
+				for _, p := range ports {
+					addrs = append(addrs, fmt.Sprintf("%s:%d", n.IP, p.Value))
+				}
+			}
+		}
+	}
+	return addrs
+}
+
+// filterAddrs keeps addrs which are in keep
+func filterAddrs(addrs []string, keep map[string]bool) []string {
+	var fa []string
+	for _, a := range addrs {
+		if keep[a] {
+			fa = append(fa, a)
+		}
+	}
+	return fa
+}
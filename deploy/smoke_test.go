@@ -0,0 +1,49 @@
+package deploy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmokeTestCheck(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	st := &SmokeTest{URL: srv.URL}
+	assert.Error(t, st.check(context.Background(), srv.URL))
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	st.Retries = 1
+	assert.NoError(t, st.check(context.Background(), srv.URL))
+	assert.Equal(t, 2, calls)
+
+	st.Status = http.StatusNoContent
+	assert.Error(t, st.check(context.Background(), srv.URL))
+}
+
+func TestAllocAddrs(t *testing.T) {
+	alloc := &api.Allocation{TaskResources: map[string]*api.Resources{
+		"api": {Networks: []*api.NetworkResource{{
+			IP:            "10.0.0.1",
+			ReservedPorts: []api.Port{{Label: "http", Value: 80}},
+			DynamicPorts:  []api.Port{{Label: "metrics", Value: 21345}},
+		}}},
+	}}
+	addrs := allocAddrs(alloc)
+	assert.Equal(t, []string{"10.0.0.1:80", "10.0.0.1:21345"}, addrs)
+
+	deployed := map[string]bool{"10.0.0.1:21345": true}
+	assert.Equal(t, []string{"10.0.0.1:21345"}, filterAddrs([]string{"10.0.0.2:21345", "10.0.0.1:21345"}, deployed))
+}
@@ -0,0 +1,65 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	"github.com/minus5/svckit/log"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// sopsKey is metadata key added by sops to encrypted files
+const sopsKey = "sops"
+
+// isSopsEncrypted checks for sops metadata in yaml data
+func isSopsEncrypted(data []byte) bool {
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return false
+	}
+	_, ok := m[sopsKey]
+	return ok
+}
+
+// readConfigFile reads config file, decrypting it with sops if it is encrypted.
+// Keys (age, KMS, PGP) are found by sops from file metadata and environment.
+func readConfigFile(fn string) ([]byte, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil || !isSopsEncrypted(data) {
+		return data, err
+	}
+	out, err := sops("--decrypt", fn)
+	if err != nil {
+		return nil, err
+	}
+	log.S("file", fn).Debug("decrypted with sops")
+	return out, nil
+}
+
+// saveEncrypted sets changed service images in sops encrypted config file,
+// other values stay encrypted as they are.
+func (c *DeploymentConfig) saveEncrypted(fn string) error {
+	for _, ch := range c.changedImages() {
+		v, _ := json.Marshal(ch.image)
+		path := fmt.Sprintf(`["datacenters"][%q]["services"][%q]["image"] %s`, ch.dc, ch.service, v)
+		if _, err := sops("--set", path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sops(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sops", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sops %s failed: %v %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
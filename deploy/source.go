@@ -0,0 +1,37 @@
+package deploy
+
+import "github.com/hashicorp/nomad/api"
+
+// ConfigSource loads and saves deployment config.
+// Default source is the deployment repository directory,
+// StaticConfigSource keeps config in memory.
+type ConfigSource interface {
+	Load(deployment, env string) (*DeploymentConfig, error)
+	Save(c *DeploymentConfig) error
+}
+
+// JobLoader loads Nomad job of the service in the config datacenter.
+// Default loader renders .nomad file from the deployment repository,
+// StaticJobLoader returns jobs from memory.
+type JobLoader interface {
+	LoadJob(service, dc string) (*api.Job, error)
+}
+
+// dirConfigSource is deployment repository directory
+type dirConfigSource string
+
+func (root dirConfigSource) Load(deployment, env string) (*DeploymentConfig, error) {
+	return NewDeploymentEnvConfig(string(root), deployment, env)
+}
+
+func (root dirConfigSource) Save(c *DeploymentConfig) error {
+	return c.Save()
+}
+
+// configSource returns config source from options or deployment repository
+func (w *Worker) configSource() ConfigSource {
+	if w.source != nil {
+		return w.source
+	}
+	return dirConfigSource(w.root)
+}
@@ -0,0 +1,195 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/hashicorp/nomad/api"
+)
+
+// ServiceStatus is summary of the service job running in datacenter
+type ServiceStatus struct {
+	Service    string    `json:"service" yaml:"service"`
+	Type       string    `json:"type" yaml:"type"`
+	Status     string    `json:"status" yaml:"status"` // job status: pending, running or dead
+	Running    int       `json:"running" yaml:"running"`
+	Desired    int       `json:"desired" yaml:"desired"`
+	Image      string    `json:"image" yaml:"image"`
+	Version    uint64    `json:"version" yaml:"version"`
+	Deployed   time.Time `json:"deployed" yaml:"deployed"`     // submit time of the running job version
+	Deployment string    `json:"deployment" yaml:"deployment"` // status of the latest deployment
+	Healthy    int       `json:"healthy" yaml:"healthy"`
+	Unhealthy  int       `json:"unhealthy" yaml:"unhealthy"`
+	Health     string    `json:"health" yaml:"health"`
+}
+
+// service health summaries
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+	HealthStopped   = "stopped"
+)
+
+func (s ServiceStatus) String() string {
+	tag := s.Image
+	if i := strings.LastIndex(tag, ":"); i >= 0 {
+		tag = tag[i+1:]
+	}
+	deployed := ""
+	if !s.Deployed.IsZero() {
+		deployed = units.HumanDuration(time.Since(s.Deployed)) + " ago"
+	}
+	health := s.Health
+	switch health {
+	case HealthHealthy:
+		health = success(health)
+	case HealthDegraded, HealthUnhealthy:
+		health = warn(health)
+	}
+	return fmt.Sprintf("%-30s %-8s %5s %-8s %-20s %-12s %-25s %s",
+		s.Service,
+		s.Status,
+		fmt.Sprintf("%d/%d", s.Running, s.Desired),
+		fmt.Sprintf("v%d", s.Version),
+		deployed,
+		s.Deployment,
+		tag,
+		health)
+}
+
+// health summarizes job status, allocation counts and the latest deployment
+func (s *ServiceStatus) health() string {
+	switch {
+	case s.Status == "dead":
+		return HealthStopped
+	case s.Unhealthy > 0 || s.Deployment == DeploymentStatusFailed:
+		return HealthUnhealthy
+	case s.Type == JobTypeService && s.Running < s.Desired:
+		return HealthDegraded
+	}
+	return HealthHealthy
+}
+
+// Status shows status of the service in Dc,
+// or of all jobs in Dc if service is not set.
+func Status(ctx context.Context, o Options) error {
+	if machineOutput(o.Output) {
+		termOut = os.Stderr
+	}
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	d := w.newDeployer(o.Dc)
+	ss, err := d.Status(ctx)
+	if err != nil {
+		return done(err)
+	}
+	err = render(o.Output, ss, func(w io.Writer) {
+		for _, s := range ss {
+			fmt.Fprintf(w, "%s\n", s)
+		}
+	})
+	if err != nil {
+		return done(err)
+	}
+	return nil
+}
+
+// Status returns status of the deployer service,
+// or of all jobs if service is not set, sorted by name.
+func (d *Deployer) Status(ctx context.Context) ([]*ServiceStatus, error) {
+	if err := d.connect(ctx); err != nil {
+		return nil, err
+	}
+	services := []string{d.service}
+	if d.service == "" {
+		jobs, _, err := d.cli.Jobs().List(nil)
+		if err != nil {
+			return nil, err
+		}
+		services = nil
+		for _, j := range jobs {
+			if j.ParentID == "" {
+				services = append(services, j.ID)
+			}
+		}
+		sort.Strings(services)
+	}
+	var ss []*ServiceStatus
+	for _, service := range services {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		s, err := d.jobStatus(service)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+// jobStatus aggregates job, its summary and the latest deployment
+func (d *Deployer) jobStatus(service string) (*ServiceStatus, error) {
+	job, _, err := d.cli.Jobs().Info(service, nil)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %v", service, err)
+	}
+	s := &ServiceStatus{
+		Service: service,
+		Image:   jobImage(job, service),
+	}
+	if job.Type != nil {
+		s.Type = *job.Type
+	}
+	if job.Status != nil {
+		s.Status = *job.Status
+	}
+	if job.Version != nil {
+		s.Version = *job.Version
+	}
+	if job.SubmitTime != nil {
+		s.Deployed = time.Unix(0, *job.SubmitTime)
+	}
+	for _, tg := range job.TaskGroups {
+		if tg.Count != nil {
+			s.Desired += *tg.Count
+		}
+	}
+	summary, _, err := d.cli.Jobs().Summary(service, nil)
+	if err != nil {
+		return nil, fmt.Errorf("job %s summary: %v", service, err)
+	}
+	s.Running = runningAllocs(summary)
+	dep, _, err := d.cli.Jobs().LatestDeployment(service, nil)
+	if err != nil {
+		return nil, fmt.Errorf("job %s deployment: %v", service, err)
+	}
+	if dep != nil {
+		s.Deployment = dep.Status
+		for _, tg := range dep.TaskGroups {
+			s.Healthy += tg.HealthyAllocs
+			s.Unhealthy += tg.UnhealthyAllocs
+		}
+	}
+	s.Health = s.health()
+	return s, nil
+}
+
+func runningAllocs(summary *api.JobSummary) int {
+	running := 0
+	if summary == nil {
+		return running
+	}
+	for _, tg := range summary.Summary {
+		running += tg.Running
+	}
+	return running
+}
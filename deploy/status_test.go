@@ -0,0 +1,26 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceHealth(t *testing.T) {
+	s := &ServiceStatus{Type: JobTypeService, Status: "running", Running: 2, Desired: 2, Deployment: DeploymentStatusSuccessful}
+	assert.Equal(t, HealthHealthy, s.health())
+	s.Running = 1
+	assert.Equal(t, HealthDegraded, s.health())
+	s.Unhealthy = 1
+	assert.Equal(t, HealthUnhealthy, s.health())
+	s.Status = "dead"
+	assert.Equal(t, HealthStopped, s.health())
+
+	summary := &api.JobSummary{Summary: map[string]api.TaskGroupSummary{
+		"api":    {Running: 2, Failed: 1},
+		"worker": {Running: 1},
+	}}
+	assert.Equal(t, 3, runningAllocs(summary))
+	assert.Equal(t, 0, runningAllocs(nil))
+}
@@ -0,0 +1,94 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/minus5/svckit/log"
+)
+
+// Stop deregisters service job in Dc, or in all service datacenters if Dc is empty.
+// Purged job is removed from Nomad immediately, without history.
+func Stop(ctx context.Context, o Options, purge bool, reason string) error {
+	l := newTerminalLogger()
+	defer l.Close()
+	w := newWorker(o)
+	steps := []func() error{
+		w.pull,
+		w.selectService,
+		func() error { return w.stop(ctx, purge, reason) },
+	}
+	return done(runSteps(steps))
+}
+
+func (w *Worker) stop(ctx context.Context, purge bool, reason string) error {
+	dcs, err := w.datacenters()
+	if err != nil {
+		return err
+	}
+	action := "stop"
+	if purge {
+		action = "purge"
+	}
+	for _, dc := range dcs {
+		if !w.yes {
+			if err := confirmStop(w.service, dc, purge); err != nil {
+				return err
+			}
+		}
+		log.S("service", w.service).S("dc", dc).S("reason", reason).Info(action)
+		d := w.newDeployer(dc)
+		w.deployer = d
+		t := time.Now()
+		err := d.Stop(ctx, purge)
+		w.auditAction(action, reason, d, t, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// confirmStop asks for confirmation, purge has to be confirmed with service name
+func confirmStop(service, dc string, purge bool) error {
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Stop %s in %s", service, dc),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return ErrAborted
+	}
+	if !purge {
+		return nil
+	}
+	prompt = promptui.Prompt{
+		Label: fmt.Sprintf("Purge removes job history, type %s to confirm", service),
+	}
+	if s, err := prompt.Run(); err != nil || s != service {
+		return ErrAborted
+	}
+	return nil
+}
+
+// Stop deregisters job
+func (d *Deployer) Stop(ctx context.Context, purge bool) error {
+	steps := []func(context.Context) error{
+		exitStep(ExitConnection, d.connect),
+		exitStep(ExitFailed, func(_ context.Context) error {
+			job, _, err := d.cli.Jobs().Info(d.service, nil)
+			if err != nil {
+				return err
+			}
+			d.image = jobImage(job, d.service)
+			evalID, _, err := d.cli.Jobs().Deregister(d.service, purge, nil)
+			if err != nil {
+				return err
+			}
+			d.log().S("evalID", evalID).B("purge", purge).Info("job deregistered")
+			return nil
+		}),
+	}
+	return runContextSteps(ctx, steps)
+}
@@ -0,0 +1,99 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func (d *Deployer) isSystem() bool {
+	return d.job != nil && d.job.Type != nil && *d.job.Type == JobTypeSystem
+}
+
+// systemStatus waits for the system job allocations, of the registered job version,
+// to start on all nodes. System jobs don't create deployments.
+// Nodes where allocations failed and reasons of the failed placements are reported.
+func (d *Deployer) systemStatus(ctx context.Context) error {
+	failedPlacement := false
+	if d.jobEvalID != "" {
+		ev, _, err := d.cli.Evaluations().Info(d.jobEvalID, nil)
+		if err != nil {
+			return err
+		}
+		if len(ev.FailedTGAllocs) > 0 {
+			failedPlacement = true
+			var b strings.Builder
+			formatFailedAllocs(&b, ev.FailedTGAllocs)
+			fmt.Fprintf(termOut, "%s", b.String())
+		}
+	}
+
+	job, _, err := d.cli.Jobs().Info(d.service, nil)
+	if err != nil {
+		return err
+	}
+	version := *job.Version
+
+	q := d.blockingQuery()
+	var current []*api.AllocationListStub
+	for {
+		if err := ctx.Err(); err != nil {
+			d.log().S("job", d.service).Info("stopped watching job, it is still running in Nomad")
+			return err
+		}
+		allocs, meta, err := d.cli.Jobs().Allocations(d.service, false, q)
+		if err != nil {
+			return err
+		}
+		current = current[:0]
+		pending := 0
+		for _, a := range allocs {
+			if a.JobVersion != version || a.DesiredStatus != "run" {
+				continue
+			}
+			current = append(current, a)
+			if a.ClientStatus == "pending" {
+				pending++
+			}
+		}
+		if pending == 0 {
+			break
+		}
+		d.log().I("allocs", len(current)).I("pending", pending).Debug("checking status")
+		q.WaitIndex = meta.LastIndex
+	}
+
+	nodes := d.nodeNames()
+	failed := 0
+	for _, a := range current {
+		if a.ClientStatus == "running" || a.ClientStatus == allocClientStatusComplete {
+			continue
+		}
+		failed++
+		d.log().S("node", nodes[a.NodeID]).S("alloc", a.ID[:8]).S("status", a.ClientStatus).ErrorS("allocation failed")
+	}
+	if failed > 0 {
+		return fmt.Errorf("system job failed on %d of %d nodes", failed, len(current))
+	}
+	if failedPlacement {
+		return fmt.Errorf("system job not placed on all nodes")
+	}
+	d.log().I("nodes", len(current)).Info("system job running")
+	return nil
+}
+
+// nodeNames returns map of node names by node ID
+func (d *Deployer) nodeNames() map[string]string {
+	names := make(map[string]string)
+	nodes, _, err := d.cli.Nodes().List(nil)
+	if err != nil {
+		d.log().Error(err)
+		return names
+	}
+	for _, n := range nodes {
+		names[n.ID] = n.Name
+	}
+	return names
+}
@@ -0,0 +1,168 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/minus5/svckit/log"
+)
+
+const (
+	// envKVDestination is where template with environment variables from Consul KV is rendered
+	envKVDestination = "local/env_kv.env"
+	// envVaultDestination is where template with environment variables from Vault is rendered
+	envVaultDestination = "secrets/env_vault.env"
+	// vaultRefPrefix marks environment value as Vault secret reference, like vault:secret/data/app#password
+	vaultRefPrefix = "vault:"
+)
+
+// Template is task template stanza declared in config
+type Template struct {
+	Source       string `yaml:"source,omitempty"`
+	Destination  string `yaml:"destination"`
+	Data         string `yaml:"data,omitempty"`
+	ChangeMode   string `yaml:"change_mode,omitempty"`
+	ChangeSignal string `yaml:"change_signal,omitempty"`
+	Env          bool   `yaml:"env,omitempty"`
+}
+
+func (t *Template) nomad() *api.Template {
+	nt := &api.Template{DestPath: &t.Destination}
+	if t.Source != "" {
+		nt.SourcePath = &t.Source
+	}
+	if t.Data != "" {
+		nt.EmbeddedTmpl = &t.Data
+	}
+	if t.ChangeMode != "" {
+		nt.ChangeMode = &t.ChangeMode
+	}
+	if t.ChangeSignal != "" {
+		nt.ChangeSignal = &t.ChangeSignal
+	}
+	if t.Env {
+		nt.Envvars = &t.Env
+	}
+	return nt
+}
+
+// setTemplates adds templates from service config to the task.
+// Existing task template with the same destination is replaced.
+// Environment variables from Consul KV and Vault are rendered in env templates.
+func setTemplates(ta *api.Task, s *ServiceConfig) {
+	tmpls := s.Templates
+	if len(s.EnvKV) > 0 {
+		tmpls = append(tmpls, &Template{
+			Destination: envKVDestination,
+			Data:        envKVTemplate(s.EnvKV),
+			ChangeMode:  "restart",
+			Env:         true,
+		})
+	}
+	if refs := vaultRefs(s.Environment); len(refs) > 0 {
+		tmpls = append(tmpls, &Template{
+			Destination: envVaultDestination,
+			Data:        envVaultTemplate(refs),
+			ChangeMode:  "restart",
+			Env:         true,
+		})
+	}
+	for _, t := range tmpls {
+		if t.Destination == "" {
+			continue
+		}
+		var ts []*api.Template
+		for _, et := range ta.Templates {
+			if et.DestPath == nil || *et.DestPath != t.Destination {
+				ts = append(ts, et)
+			}
+		}
+		ta.Templates = append(ts, t.nomad())
+		log.S("destination", t.Destination).Debug("setting template")
+	}
+}
+
+// envKVTemplate renders consul-template data which sets
+// environment variables from Consul KV keys
+func envKVTemplate(kv map[string]string) string {
+	var names []string
+	for n := range kv {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, n := range names {
+		fmt.Fprintf(&b, "%s=\"{{ key %q }}\"\n", n, kv[n])
+	}
+	return b.String()
+}
+
+// isVaultRef checks is environment value Vault secret reference
+func isVaultRef(v string) bool {
+	return strings.HasPrefix(v, vaultRefPrefix)
+}
+
+// parseVaultRef splits reference like vault:secret/data/app#password to secret path and field
+func parseVaultRef(v string) (path, field string, err error) {
+	ref := strings.TrimPrefix(v, vaultRefPrefix)
+	i := strings.LastIndex(ref, "#")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("invalid vault reference %s, expected vault:path#field", v)
+	}
+	return ref[:i], ref[i+1:], nil
+}
+
+// vaultRefs returns environment variables which are Vault secret references
+func vaultRefs(env map[string]string) map[string]string {
+	refs := make(map[string]string)
+	for k, v := range env {
+		if isVaultRef(v) {
+			refs[k] = v
+		}
+	}
+	return refs
+}
+
+// envVaultTemplate renders consul-template data which sets
+// environment variables from Vault secrets.
+// Secrets from kv v2 engine (path with /data/) have fields under .Data.data.
+func envVaultTemplate(refs map[string]string) string {
+	var names []string
+	for n := range refs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, n := range names {
+		path, field, err := parseVaultRef(refs[n])
+		if err != nil {
+			continue
+		}
+		data := ".Data"
+		if strings.Contains(path, "/data/") {
+			data = ".Data.data"
+		}
+		fmt.Fprintf(&b, "{{ with secret %q }}%s=\"{{ index %s %q }}\"{{ end }}\n", path, n, data, field)
+	}
+	return b.String()
+}
+
+// checkVaultRefs validates Vault secret references in service environment.
+// Task needs Vault policies to read secrets.
+func checkVaultRefs(ta *api.Task, s *ServiceConfig) error {
+	refs := vaultRefs(s.Environment)
+	if len(refs) == 0 {
+		return nil
+	}
+	for _, v := range refs {
+		if _, _, err := parseVaultRef(v); err != nil {
+			return err
+		}
+	}
+	if len(s.Vault) == 0 && (ta.Vault == nil || len(ta.Vault.Policies) == 0) {
+		return fmt.Errorf("task %s uses vault secrets in env but vault policies are not set", ta.Name)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTemplates(t *testing.T) {
+	dest := "local/app.conf"
+	old := "old"
+	ta := &api.Task{Templates: []*api.Template{{DestPath: &dest, EmbeddedTmpl: &old}}}
+	s := &ServiceConfig{
+		Templates: []*Template{{Destination: dest, Data: "new", ChangeMode: "signal", ChangeSignal: "SIGHUP"}},
+		EnvKV:     map[string]string{"DB_URL": "app/db_url", "API_KEY": "app/api_key"},
+	}
+	setTemplates(ta, s)
+
+	assert.Len(t, ta.Templates, 2)
+	assert.Equal(t, "new", *ta.Templates[0].EmbeddedTmpl)
+	assert.Equal(t, "SIGHUP", *ta.Templates[0].ChangeSignal)
+	assert.Equal(t, envKVDestination, *ta.Templates[1].DestPath)
+	assert.True(t, *ta.Templates[1].Envvars)
+	assert.Equal(t, "API_KEY=\"{{ key \"app/api_key\" }}\"\nDB_URL=\"{{ key \"app/db_url\" }}\"\n", *ta.Templates[1].EmbeddedTmpl)
+}
+
+func TestVaultRefs(t *testing.T) {
+	ta := &api.Task{Name: "app"}
+	s := &ServiceConfig{
+		Environment: map[string]string{
+			"LOG_LEVEL":   "info",
+			"DB_PASSWORD": "vault:secret/data/app#password",
+			"API_KEY":     "vault:secret/app#api-key",
+		},
+	}
+	assert.Error(t, checkVaultRefs(ta, s))
+	s.Vault = []string{"app-read"}
+	assert.NoError(t, checkVaultRefs(ta, s))
+
+	setTemplates(ta, s)
+	assert.Len(t, ta.Templates, 1)
+	assert.Equal(t, envVaultDestination, *ta.Templates[0].DestPath)
+	assert.Equal(t, "{{ with secret \"secret/app\" }}API_KEY=\"{{ index .Data \"api-key\" }}\"{{ end }}\n"+
+		"{{ with secret \"secret/data/app\" }}DB_PASSWORD=\"{{ index .Data.data \"password\" }}\"{{ end }}\n",
+		*ta.Templates[0].EmbeddedTmpl)
+
+	_, _, err := parseVaultRef("vault:secret/app")
+	assert.Error(t, err)
+}
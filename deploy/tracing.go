@@ -0,0 +1,226 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// otlpEndpointEnv is standard OpenTelemetry exporter endpoint environment variable,
+// used when tracing endpoint is not set in config
+const otlpEndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+// TracingConfig configures export of the deploy traces to OpenTelemetry collector
+type TracingConfig struct {
+	Endpoint string `yaml:"endpoint,omitempty"` // collector OTLP/HTTP url, like http://otel-collector:4318
+}
+
+// tracesURL returns OTLP/HTTP traces url, empty if tracing is not configured
+func (c *TracingConfig) tracesURL() string {
+	e := os.Getenv(otlpEndpointEnv)
+	if c != nil && c.Endpoint != "" {
+		e = c.Endpoint
+	}
+	if e == "" {
+		return ""
+	}
+	e = strings.TrimSuffix(e, "/")
+	if strings.HasSuffix(e, "/v1/traces") {
+		return e
+	}
+	return e + "/v1/traces"
+}
+
+// trace collects spans of one deployment, exported when deployment finishes
+type trace struct {
+	url   string
+	id    string
+	root  *span
+	spans []*span
+	mu    sync.Mutex
+}
+
+type span struct {
+	id     string
+	parent string
+	name   string
+	start  time.Time
+	end    time.Time
+	attrs  map[string]string
+	err    error
+}
+
+func newTrace(url, name string, attrs map[string]string) *trace {
+	t := &trace{url: url, id: randomID(16)}
+	t.root = t.start(name, "", attrs)
+	return t
+}
+
+func (t *trace) start(name, parent string, attrs map[string]string) *span {
+	s := &span{id: randomID(8), parent: parent, name: name, start: time.Now(), attrs: attrs}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, s)
+	return s
+}
+
+// traced runs step in the child span of the deployment trace
+func (d *Deployer) traced(name string, step func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if d.trace == nil {
+			return step(ctx)
+		}
+		s := d.trace.start(name, d.trace.root.id, nil)
+		err := step(ctx)
+		s.end, s.err = time.Now(), err
+		return err
+	}
+}
+
+// startTrace starts deployment trace if tracing is configured
+func (d *Deployer) startTrace() {
+	var c *TracingConfig
+	if d.config != nil {
+		c = d.config.Tracing
+	}
+	url := c.tracesURL()
+	if url == "" {
+		return
+	}
+	d.trace = newTrace(url, "deploy", map[string]string{
+		"pitwall.service":    d.service,
+		"pitwall.dc":         d.dc,
+		"pitwall.image":      d.image,
+		"pitwall.deployment": d.deployment,
+	})
+}
+
+// endTrace ends deployment trace and exports it to the collector.
+// Failed export is logged, it doesn't break deployment.
+func (d *Deployer) endTrace(err error) {
+	t := d.trace
+	if t == nil {
+		return
+	}
+	d.trace = nil
+	t.root.end, t.root.err = time.Now(), err
+	if d.jobEvalID != "" {
+		t.root.attrs["nomad.eval_id"] = d.jobEvalID
+	}
+	if d.jobDeploymentID != "" {
+		t.root.attrs["nomad.deployment_id"] = d.jobDeploymentID
+	}
+	if err := t.export(); err != nil {
+		d.log().Error(fmt.Errorf("unable to export trace: %v", err))
+	}
+}
+
+var tracingClient = &http.Client{Timeout: 5 * time.Second}
+
+// export posts spans in OTLP/HTTP json encoding
+func (t *trace) export() error {
+	buf, err := json.Marshal(t.otlp())
+	if err != nil {
+		return err
+	}
+	rsp, err := tracingClient.Post(t.url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		return fmt.Errorf("export to %s failed: %s", t.url, rsp.Status)
+	}
+	return nil
+}
+
+type otlpAttr struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            otlpStatus `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+// otlp returns trace in OTLP ExportTraceServiceRequest json format
+func (t *trace) otlp() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []otlpSpan
+	for _, s := range t.spans {
+		end := s.end
+		if end.IsZero() {
+			end = time.Now()
+		}
+		o := otlpSpan{
+			TraceID:           t.id,
+			SpanID:            s.id,
+			ParentSpanID:      s.parent,
+			Name:              s.name,
+			Kind:              1, // internal
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+			Attributes:        otlpAttrs(s.attrs),
+			Status:            otlpStatus{Code: 1},
+		}
+		if s.err != nil {
+			o.Status = otlpStatus{Code: 2, Message: s.err.Error()}
+		}
+		spans = append(spans, o)
+	}
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttrs(map[string]string{"service.name": "pitwall"}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "pitwall/deploy"},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+func otlpAttrs(m map[string]string) []otlpAttr {
+	var as []otlpAttr
+	for k, v := range m {
+		a := otlpAttr{Key: k}
+		a.Value.StringValue = v
+		as = append(as, a)
+	}
+	sort.Slice(as, func(i, j int) bool { return as[i].Key < as[j].Key })
+	return as
+}
+
+// randomID returns hex encoded random trace or span id of n bytes
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracesURL(t *testing.T) {
+	os.Unsetenv(otlpEndpointEnv)
+	var c *TracingConfig
+	assert.Equal(t, "", c.tracesURL())
+	c = &TracingConfig{Endpoint: "http://collector:4318/"}
+	assert.Equal(t, "http://collector:4318/v1/traces", c.tracesURL())
+	c.Endpoint = "http://collector:4318/v1/traces"
+	assert.Equal(t, "http://collector:4318/v1/traces", c.tracesURL())
+}
+
+func TestTraceExport(t *testing.T) {
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan
+			}
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&req)
+	}))
+	defer srv.Close()
+
+	d := &Deployer{service: "api", dc: "pg1", config: &DeploymentConfig{Tracing: &TracingConfig{Endpoint: srv.URL}}}
+	d.startTrace()
+	ok := d.traced("connect", func(context.Context) error { return nil })
+	failed := d.traced("plan", func(context.Context) error { return errors.New("plan failed") })
+	assert.Nil(t, ok(context.Background()))
+	assert.NotNil(t, failed(context.Background()))
+	d.endTrace(errors.New("plan failed"))
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "deploy", spans[0].Name)
+	assert.Len(t, spans[0].TraceID, 32)
+	assert.Equal(t, spans[0].SpanID, spans[1].ParentSpanID)
+	assert.Equal(t, 1, spans[1].Status.Code)
+	assert.Equal(t, 2, spans[2].Status.Code)
+	assert.Equal(t, "plan failed", spans[2].Status.Message)
+	assert.Nil(t, d.trace)
+}
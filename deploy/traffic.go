@@ -0,0 +1,187 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/api"
+)
+
+// canaryTag is set on Consul services of canary allocations,
+// Fabio route weights select canaries by it
+const canaryTag = "pitwall-canary"
+
+// defaultFabioKey is Consul KV key of the Fabio manual routing table
+const defaultFabioKey = "fabio/config"
+
+// errTrafficStopped is returned when deployment watch ends during traffic shift
+var errTrafficStopped = errors.New("traffic shift stopped")
+
+// TrafficConfig shifts traffic to canaries gradually, with Fabio route weights,
+// before they are promoted. Used only when canaries are promoted by pitwall.
+type TrafficConfig struct {
+	Route string `yaml:"route"`               // Fabio route source, like api.example.com/ or /api
+	Steps []int  `yaml:"steps,omitempty"`     // percents of traffic sent to canaries, default 10, 50
+	Pause string `yaml:"pause,omitempty"`     // duration of each step, default 1m
+	Key   string `yaml:"fabio_key,omitempty"` // Consul KV key of the Fabio routing table, default fabio/config
+}
+
+func (t *TrafficConfig) steps() []int {
+	if len(t.Steps) == 0 {
+		return []int{10, 50}
+	}
+	var steps []int
+	for _, s := range t.Steps {
+		if s > 0 && s < 100 {
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+func (t *TrafficConfig) pause() time.Duration {
+	if p, err := time.ParseDuration(t.Pause); err == nil {
+		return p
+	}
+	return time.Minute
+}
+
+func (t *TrafficConfig) key() string {
+	if t.Key == "" {
+		return defaultFabioKey
+	}
+	return t.Key
+}
+
+// trafficConfig returns traffic config of the deployer service, nil if not configured
+func (d *Deployer) trafficConfig() *TrafficConfig {
+	if d.config == nil {
+		return nil
+	}
+	s := d.config.FindForDc(d.service, d.cdc)
+	if s == nil {
+		return nil
+	}
+	return s.Traffic
+}
+
+// setCanaryTags tags Consul services of the canaries,
+// so Fabio can route part of the traffic to them.
+func (d *Deployer) setCanaryTags(tg *api.TaskGroup) error {
+	t := d.trafficConfig()
+	if t == nil {
+		return nil
+	}
+	if t.Route == "" {
+		return fmt.Errorf("traffic route of service %s is not set", d.service)
+	}
+	for _, ta := range tg.Tasks {
+		for _, s := range ta.Services {
+			tags := append([]string{}, s.Tags...)
+			s.CanaryTags = append(tags, canaryTag)
+			d.trafficServices = append(d.trafficServices, s.Name)
+		}
+	}
+	return nil
+}
+
+// shiftTraffic sets canaries route weight to each step percent
+// and waits step pause, while canaries are healthy.
+func (d *Deployer) shiftTraffic(depID string, shutdownChan chan interface{}) error {
+	t := d.trafficConfig()
+	for _, step := range t.steps() {
+		if err := d.setTrafficWeight(t, step); err != nil {
+			return err
+		}
+		d.log().S("deploymentID", depID).I("percent", step).S("pause", t.pause().String()).Info("traffic shifted to canaries")
+		select {
+		case <-shutdownChan:
+			return errTrafficStopped
+		case <-time.After(t.pause()):
+		}
+		if !d.checkCanaryHealth(depID) {
+			return fmt.Errorf("canaries unhealthy with %d%% of traffic", step)
+		}
+	}
+	return nil
+}
+
+// resetTraffic removes canaries route weights
+func (d *Deployer) resetTraffic() {
+	if err := d.setTrafficWeight(d.trafficConfig(), 0); err != nil {
+		d.log().Error(fmt.Errorf("unable to remove traffic weights: %v", err))
+	}
+}
+
+// setTrafficWeight replaces service routes in Fabio routing table,
+// percent 0 removes them
+func (d *Deployer) setTrafficWeight(t *TrafficConfig, percent int) error {
+	cli, err := capi.NewClient(&capi.Config{Address: d.consul})
+	if err != nil {
+		return err
+	}
+	kv := cli.KV()
+	q := &capi.QueryOptions{Datacenter: d.dc}
+	p, _, err := kv.Get(t.key(), q)
+	if err != nil {
+		return err
+	}
+	var config string
+	var index uint64
+	if p != nil {
+		config, index = string(p.Value), p.ModifyIndex
+	}
+	var routes []string
+	if percent > 0 {
+		for _, s := range d.trafficServices {
+			routes = append(routes, fabioWeightRoute(s, t.Route, percent))
+		}
+	}
+	config = replaceRoutes(config, d.trafficServices, routes)
+	ok, _, err := kv.CAS(&capi.KVPair{Key: t.key(), Value: []byte(config), ModifyIndex: index}, &capi.WriteOptions{Datacenter: d.dc})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s changed while updating, retry", t.key())
+	}
+	return nil
+}
+
+// fabioWeightRoute sends percent of the route traffic to canaries of the service
+func fabioWeightRoute(service, src string, percent int) string {
+	return fmt.Sprintf("route weight %s %s weight %.2f tags %q", service, src, float64(percent)/100, canaryTag)
+}
+
+// replaceRoutes removes canary weight routes of the services from Fabio
+// routing table and appends routes
+func replaceRoutes(config string, services []string, routes []string) string {
+	var lines []string
+	for _, l := range strings.Split(config, "\n") {
+		if l == "" || isCanaryRoute(l, services) {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	lines = append(lines, routes...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func isCanaryRoute(line string, services []string) bool {
+	f := strings.Fields(line)
+	if len(f) < 3 || f[0] != "route" || f[1] != "weight" || !strings.Contains(line, fmt.Sprintf("%q", canaryTag)) {
+		return false
+	}
+	for _, s := range services {
+		if f[2] == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceRoutes(t *testing.T) {
+	config := "route add web /web http://10.0.0.1:80/\n" +
+		"route weight api api.example.com/ weight 0.10 tags \"pitwall-canary\"\n"
+	routes := []string{fabioWeightRoute("api", "api.example.com/", 50)}
+	assert.Equal(t, "route add web /web http://10.0.0.1:80/\n"+
+		"route weight api api.example.com/ weight 0.50 tags \"pitwall-canary\"\n",
+		replaceRoutes(config, []string{"api"}, routes))
+	assert.Equal(t, "route add web /web http://10.0.0.1:80/\n", replaceRoutes(config, []string{"api"}, nil))
+	assert.Equal(t, "", replaceRoutes("", []string{"api"}, nil))
+}
+
+func TestSetCanaryTags(t *testing.T) {
+	c := &DeploymentConfig{Datacenters: map[string]*DcConfig{
+		"pg1": {Services: map[string]*ServiceConfig{
+			"api": {Traffic: &TrafficConfig{Route: "api.example.com/"}},
+		}},
+	}}
+	d := &Deployer{service: "api", cdc: "pg1", config: c}
+	tg := &api.TaskGroup{Tasks: []*api.Task{{Services: []*api.Service{{Name: "api", Tags: []string{"urlprefix-api.example.com/"}}}}}}
+	assert.Nil(t, d.setCanaryTags(tg))
+	assert.Equal(t, []string{"urlprefix-api.example.com/", canaryTag}, tg.Tasks[0].Services[0].CanaryTags)
+	assert.Equal(t, []string{"urlprefix-api.example.com/"}, tg.Tasks[0].Services[0].Tags)
+	assert.Equal(t, []string{"api"}, d.trafficServices)
+	assert.Equal(t, []int{10, 50}, (&TrafficConfig{}).steps())
+}
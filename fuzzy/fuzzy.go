@@ -0,0 +1,32 @@
+// Package fuzzy matches names like fzf, for promptui selects
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Match checks are all pattern characters in s in the same order,
+// case insensitive and ignoring spaces in pattern, like fzf does
+func Match(pattern, s string) bool {
+	s = strings.ToLower(s)
+	i := 0
+	for _, r := range strings.ToLower(pattern) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		j := strings.IndexRune(s[i:], r)
+		if j < 0 {
+			return false
+		}
+		i += j + len(string(r))
+	}
+	return true
+}
+
+// Searcher returns promptui searcher for items names
+func Searcher(names []string) func(string, int) bool {
+	return func(input string, idx int) bool {
+		return Match(input, names[idx])
+	}
+}
@@ -0,0 +1,19 @@
+package fuzzy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("", "backend_api"))
+	assert.True(t, Match("bapi", "backend_api"))
+	assert.True(t, Match("BE API", "backend_api"))
+	assert.False(t, Match("apib", "backend_api"))
+	assert.False(t, Match("x", "backend_api"))
+
+	s := Searcher([]string{"haproxy", "backend_api"})
+	assert.False(t, s("bapi", 0))
+	assert.True(t, s("bapi", 1))
+}
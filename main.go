@@ -7,6 +7,10 @@ import (
 	"github.com/minus5/pitwall/cmd"
 )
 
+// version is set by goreleaser
+var version = "dev"
+
 func main() {
+	cmd.Version = version
 	cmd.Execute()
 }
@@ -0,0 +1,72 @@
+package monit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/log"
+)
+
+// alertInterval is minimal time between two alerts
+var alertInterval = time.Minute
+
+// alert notifies when line matching expression appears
+type alert struct {
+	expr whereExpr
+	cmd  string // shell command run on alert, line is in PITWALL_LINE env and stdin
+	last time.Time
+}
+
+func newAlert(expr, cmd string) (*alert, error) {
+	if expr == "" {
+		if cmd != "" {
+			return nil, fmt.Errorf("alert command requires alert expression")
+		}
+		return nil, nil
+	}
+	e, err := parseWhere(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &alert{expr: e, cmd: cmd}, nil
+}
+
+// match checks is it time to alert for the line
+func (a *alert) match(data []byte, now time.Time) bool {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return false
+	}
+	if !a.expr.eval(m) {
+		return false
+	}
+	if !a.last.IsZero() && now.Sub(a.last) < alertInterval {
+		return false
+	}
+	a.last = now
+	return true
+}
+
+// check rings terminal bell and runs alert command if line matches
+func (a *alert) check(data []byte) {
+	if a == nil || !a.match(data, time.Now()) {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\a")
+	if a.cmd == "" {
+		return
+	}
+	line := strings.TrimSpace(string(data))
+	go func() {
+		c := exec.Command("sh", "-c", a.cmd)
+		c.Env = append(os.Environ(), "PITWALL_LINE="+line)
+		c.Stdin = strings.NewReader(line)
+		if out, err := c.CombinedOutput(); err != nil {
+			log.S("cmd", a.cmd).S("output", string(out)).Error(err)
+		}
+	}()
+}
@@ -0,0 +1,24 @@
+package monit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlert(t *testing.T) {
+	a, err := newAlert(`level == "error"`, "")
+	assert.Nil(t, err)
+	now := time.Now()
+	assert.False(t, a.match([]byte(`{"level":"info"}`), now))
+	assert.True(t, a.match([]byte(`{"level":"error"}`), now))
+	assert.False(t, a.match([]byte(`{"level":"error"}`), now.Add(time.Second)))
+	assert.True(t, a.match([]byte(`{"level":"error"}`), now.Add(alertInterval)))
+
+	a, err = newAlert("", "")
+	assert.Nil(t, err)
+	assert.Nil(t, a)
+	_, err = newAlert("", "notify-send")
+	assert.NotNil(t, err)
+}
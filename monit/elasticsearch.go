@@ -0,0 +1,203 @@
+package monit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// esDefaultIndex is searched if index is not set in address path
+	esDefaultIndex = "logs-*"
+	// esServiceField is document field with service name
+	esServiceField = "app"
+	// esTimeField is document field with log line time
+	esTimeField = "time"
+	// esSize is max number of documents in one search response
+	esSize = 1000
+	// esPollInterval is interval of searches for new documents when tailing
+	esPollInterval = time.Second
+)
+
+// esSource reads logs from Elasticsearch or OpenSearch indices
+type esSource struct {
+	url   string
+	index string
+}
+
+// newESSource creates source from address like es.example.com:9200/logs-*
+func newESSource(address string) esSource {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	s := esSource{url: address, index: esDefaultIndex}
+	if u, err := url.Parse(address); err == nil {
+		if idx := strings.Trim(u.Path, "/"); idx != "" {
+			s.index = idx
+		}
+		u.Path = ""
+		s.url = u.String()
+	}
+	return s
+}
+
+type esHit struct {
+	Source json.RawMessage `json:"_source"`
+	Sort   []interface{}   `json:"sort"`
+}
+
+// Tail polls for documents after the last one received
+func (s esSource) Tail(q Query, handler func([]byte) error) error {
+	q.StartTime = time.Now()
+	var after []interface{}
+	for {
+		time.Sleep(esPollInterval)
+		last, err := s.search(q, after, handler)
+		if err != nil {
+			return err
+		}
+		if last != nil {
+			after = last
+		}
+	}
+}
+
+// Search pages through documents in the query time window
+func (s esSource) Search(q Query, handler func([]byte) error) error {
+	_, err := s.search(q, nil, handler)
+	return err
+}
+
+// search calls handler for all documents sorted after, returns sort values of the last one
+func (s esSource) search(q Query, after []interface{}, handler func([]byte) error) ([]interface{}, error) {
+	for {
+		var rsp struct {
+			Hits struct {
+				Hits []esHit
+			}
+		}
+		if err := s.post("/_search", esQuery(q, after), &rsp); err != nil {
+			return after, err
+		}
+		hits := rsp.Hits.Hits
+		for _, h := range hits {
+			handler(h.Source)
+		}
+		if len(hits) > 0 {
+			after = hits[len(hits)-1].Sort
+		}
+		if len(hits) < esSize {
+			return after, nil
+		}
+	}
+}
+
+// Services lists service field values
+func (s esSource) Services() ([]string, error) {
+	body := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"services": map[string]interface{}{
+				"terms": map[string]interface{}{"field": esServiceField, "size": esSize},
+			},
+		},
+	}
+	var rsp struct {
+		Aggregations struct {
+			Services struct {
+				Buckets []struct {
+					Key string
+				}
+			}
+		}
+	}
+	if err := s.post("/_search", body, &rsp); err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, b := range rsp.Aggregations.Services.Buckets {
+		services = append(services, b.Key)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// esQuery builds query DSL for the service and time window.
+// Include and exclude attributes select document source fields.
+// Attribute grep expressions without regexp meta characters are matched as phrases,
+// others are applied on the client.
+func esQuery(q Query, after []interface{}) map[string]interface{} {
+	filter := []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{esServiceField: q.Service}},
+	}
+	rng := map[string]interface{}{}
+	if !q.StartTime.IsZero() {
+		rng["gte"] = q.StartTime.Format(time.RFC3339Nano)
+	}
+	if !q.EndTime.IsZero() {
+		rng["lt"] = q.EndTime.Format(time.RFC3339Nano)
+	}
+	if len(rng) > 0 {
+		filter = append(filter, map[string]interface{}{"range": map[string]interface{}{esTimeField: rng}})
+	}
+	var mustNot []interface{}
+	for i, exprs := range [][]string{q.Grep, q.GrepV} {
+		for _, e := range exprs {
+			m := attrFilterRx.FindStringSubmatch(e)
+			if m == nil || m[2] == "" || regexp.QuoteMeta(m[2]) != m[2] {
+				continue
+			}
+			c := map[string]interface{}{"match_phrase": map[string]interface{}{m[1]: m[2]}}
+			if i == 0 {
+				filter = append(filter, c)
+			} else {
+				mustNot = append(mustNot, c)
+			}
+		}
+	}
+	boolQuery := map[string]interface{}{"filter": filter}
+	if len(mustNot) > 0 {
+		boolQuery["must_not"] = mustNot
+	}
+	body := map[string]interface{}{
+		"size":  esSize,
+		"query": map[string]interface{}{"bool": boolQuery},
+		"sort":  []interface{}{map[string]interface{}{esTimeField: "asc"}},
+	}
+	if len(q.Include) > 0 || len(q.Exclude) > 0 {
+		src := map[string]interface{}{}
+		if len(q.Include) > 0 {
+			src["includes"] = q.Include
+		}
+		if len(q.Exclude) > 0 {
+			src["excludes"] = q.Exclude
+		}
+		body["_source"] = src
+	}
+	if after != nil {
+		body["search_after"] = after
+	}
+	return body
+}
+
+func (s esSource) post(path string, body, v interface{}) error {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	rsp, err := http.Post(fmt.Sprintf("%s/%s%s", s.url, s.index, path), "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("elasticsearch %s%s: %s", s.index, path, rsp.Status)
+	}
+	return json.NewDecoder(rsp.Body).Decode(v)
+}
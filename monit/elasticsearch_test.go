@@ -0,0 +1,62 @@
+package monit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestESQuery(t *testing.T) {
+	st := time.Date(2019, 4, 10, 12, 0, 0, 0, time.UTC)
+	q := Query{
+		Service:   "backend_api",
+		Grep:      []string{"level=error", "msg=time.*out", "timeout"},
+		GrepV:     []string{"path=/health"},
+		Include:   []string{"msg"},
+		StartTime: st,
+	}
+	buf, err := json.Marshal(esQuery(q, []interface{}{1554897600000}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"size": 1000,
+		"query": {"bool": {
+			"filter": [
+				{"term": {"app": "backend_api"}},
+				{"range": {"time": {"gte": "2019-04-10T12:00:00Z"}}},
+				{"match_phrase": {"level": "error"}}
+			],
+			"must_not": [{"match_phrase": {"path": "/health"}}]
+		}},
+		"sort": [{"time": "asc"}],
+		"_source": {"includes": ["msg"]},
+		"search_after": [1554897600000]
+	}`, string(buf))
+}
+
+func TestESSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/app-logs/_search", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(body), `"backend_api"`)
+		w.Write([]byte(`{"hits":{"hits":[
+			{"_source":{"msg":"first"},"sort":[1]},
+			{"_source":{"msg":"second"},"sort":[2]}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	s := newESSource(srv.URL + "/app-logs")
+	assert.Equal(t, "app-logs", s.index)
+	var lines []string
+	err := s.Search(Query{Service: "backend_api"}, func(data []byte) error {
+		lines = append(lines, string(data))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"msg":"first"}`, `{"msg":"second"}`}, lines)
+}
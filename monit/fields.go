@@ -0,0 +1,58 @@
+package monit
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lookup finds value by attribute path.
+// Dots are for nested objects and [n] or .n for array elements,
+// like req.headers.x-request-id or items[0].id.
+func lookup(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if n, ok := t[path]; ok {
+			return n, true
+		}
+		i := strings.IndexAny(path, ".[")
+		if i <= 0 {
+			return nil, false
+		}
+		n, ok := t[path[:i]]
+		if !ok {
+			return nil, false
+		}
+		return lookup(n, strings.TrimPrefix(path[i:], "."))
+	case []interface{}:
+		seg, rest := path, ""
+		if strings.HasPrefix(path, "[") {
+			j := strings.Index(path, "]")
+			if j < 0 {
+				return nil, false
+			}
+			seg, rest = path[1:j], path[j+1:]
+		} else if i := strings.IndexAny(path, ".["); i > 0 {
+			seg, rest = path[:i], path[i:]
+		}
+		idx, err := strconv.Atoi(seg)
+		if err != nil || idx < 0 || idx >= len(t) {
+			return nil, false
+		}
+		return lookup(t[idx], strings.TrimPrefix(rest, "."))
+	}
+	return nil, false
+}
+
+// pickFields returns values of the fields paths found in line
+func (l *LogLine) pickFields(m map[string]interface{}) map[string]interface{} {
+	p := make(map[string]interface{})
+	for _, f := range l.fields {
+		if v, ok := lookup(m, f); ok {
+			p[f] = v
+		}
+	}
+	return p
+}
@@ -0,0 +1,47 @@
+package monit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	var m map[string]interface{}
+	json.Unmarshal([]byte(`{"req":{"headers":{"x-request-id":"49B6"}},"resp.status":200,
+		"items":[{"id":1},{"id":2,"tags":["a","b"]}]}`), &m)
+
+	cases := map[string]interface{}{
+		"req.headers.x-request-id": "49B6",
+		"resp.status":              200.0,
+		"items[1].id":              2.0,
+		"items.0.id":               1.0,
+		"items[1].tags[1]":         "b",
+	}
+	for path, expected := range cases {
+		v, ok := lookup(m, path)
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, v, path)
+	}
+	for _, path := range []string{"req.missing", "items[2].id", "items[x]", "items[0", "req.headers.x-request-id.x"} {
+		_, ok := lookup(m, path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestFields(t *testing.T) {
+	line := []byte(`{"level":"info","req":{"id":"49B6"},"items":[{"id":7}]}`)
+	var out bytes.Buffer
+	l, err := TailOptions{Json: true, Fields: []string{"req.id", "items[0].id", "missing"}, out: &out}.logLine()
+	assert.Nil(t, err)
+	assert.Nil(t, l.Print(line))
+	assert.Equal(t, `{"items[0].id":7,"req.id":"49B6"}`+"\n", out.String())
+
+	out.Reset()
+	l, _ = TailOptions{Format: FormatCSV, Fields: []string{"req.id", "items[0].id"}, out: &out}.logLine()
+	assert.Nil(t, l.writeHeader())
+	assert.Nil(t, l.Print(line))
+	assert.Equal(t, "req.id,items[0].id\n49B6,7\n", out.String())
+}
@@ -0,0 +1,107 @@
+package monit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// attrFilterRx matches filter on attribute, like msg=timeout.*
+var attrFilterRx = regexp.MustCompile(`^([A-Za-z0-9_.-]+)=(.*)$`)
+
+// lineFilter matches log line, or its attribute, by regular expression
+type lineFilter struct {
+	attr   string
+	rx     *regexp.Regexp
+	invert bool
+}
+
+// parseFilters creates filters from --grep and --grep-v expressions.
+// Expression is regexp matched against whole line, or attribute=regexp.
+func parseFilters(grep, grepV []string) ([]lineFilter, error) {
+	var fs []lineFilter
+	for i, exprs := range [][]string{grep, grepV} {
+		for _, e := range exprs {
+			f := lineFilter{invert: i == 1}
+			if m := attrFilterRx.FindStringSubmatch(e); m != nil {
+				f.attr, e = m[1], m[2]
+			}
+			rx, err := regexp.Compile(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid grep expression %s: %v", e, err)
+			}
+			f.rx = rx
+			fs = append(fs, f)
+		}
+	}
+	return fs, nil
+}
+
+// match checks filter against raw line data or parsed attributes
+func (f lineFilter) match(data []byte, m map[string]interface{}) bool {
+	if f.attr == "" {
+		return f.rx.Match(data)
+	}
+	v, ok := m[f.attr]
+	if !ok {
+		return false
+	}
+	if s, ok := v.(string); ok {
+		return f.rx.MatchString(s)
+	}
+	buf, _ := json.Marshal(v)
+	return f.rx.Match(buf)
+}
+
+// levels of structured log lines, by severity
+var levels = map[string]int{
+	"debug":   1,
+	"info":    2,
+	"notice":  3,
+	"warn":    4,
+	"warning": 4,
+	"error":   5,
+	"fatal":   6,
+	"crit":    6,
+}
+
+// parseLevel returns severity of the level name, zero if level is empty
+func parseLevel(level string) (int, error) {
+	if level == "" {
+		return 0, nil
+	}
+	l, ok := levels[strings.ToLower(level)]
+	if !ok {
+		return 0, fmt.Errorf("unknown log level %s", level)
+	}
+	return l, nil
+}
+
+// skip checks should line be dropped by filters.
+// Line is shown if it matches all grep filters and none of grep-v filters,
+// its level is not below minimal level and it satisfies where expression.
+// Lines without known level are not filtered by level.
+func (l *LogLine) skip(data []byte) bool {
+	if len(l.filters) == 0 && l.minLevel == 0 && l.where == nil {
+		return false
+	}
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	if l.where != nil && !l.where.eval(m) {
+		return true
+	}
+	if l.minLevel > 0 {
+		if v, ok := m["level"].(string); ok {
+			if lv, ok := levels[strings.ToLower(v)]; ok && lv < l.minLevel {
+				return true
+			}
+		}
+	}
+	for _, f := range l.filters {
+		if f.match(data, m) == f.invert {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package monit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	fs, err := parseFilters([]string{"timeout", "app=^backend"}, []string{"level=debug"})
+	assert.NoError(t, err)
+	assert.Len(t, fs, 3)
+	assert.Equal(t, "app", fs[1].attr)
+	l := &LogLine{filters: fs}
+
+	assert.False(t, l.skip([]byte(`{"app":"backend_api","level":"info","msg":"request timeout"}`)))
+	assert.True(t, l.skip([]byte(`{"app":"backend_api","level":"debug","msg":"request timeout"}`)))
+	assert.True(t, l.skip([]byte(`{"app":"frontend","level":"info","msg":"request timeout"}`)))
+	assert.True(t, l.skip([]byte(`{"app":"backend_api","level":"info","msg":"ok"}`)))
+
+	_, err = parseFilters([]string{"("}, nil)
+	assert.Error(t, err)
+}
+
+func TestLevelFilter(t *testing.T) {
+	lv, err := parseLevel("warn")
+	assert.NoError(t, err)
+	l := &LogLine{minLevel: lv}
+	assert.True(t, l.skip([]byte(`{"level":"debug"}`)))
+	assert.True(t, l.skip([]byte(`{"level":"info"}`)))
+	assert.False(t, l.skip([]byte(`{"level":"WARNING"}`)))
+	assert.False(t, l.skip([]byte(`{"level":"error"}`)))
+	assert.False(t, l.skip([]byte(`{"msg":"no level"}`)))
+
+	_, err = parseLevel("verbose")
+	assert.Error(t, err)
+}
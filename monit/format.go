@@ -0,0 +1,120 @@
+package monit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// output formats of the structured log lines
+const (
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+	FormatCSV    = "csv"
+)
+
+func validFormat(format string) error {
+	switch format {
+	case "", FormatText, FormatLogfmt, FormatCSV:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %s, expecting text, logfmt or csv", format)
+}
+
+// csvColumns are columns of csv format, known keys if not set
+func (l *LogLine) csvColumns() []string {
+	if len(l.columns) > 0 {
+		return l.columns
+	}
+	var cs []string
+	for _, k := range l.knownKeys {
+		if l.show(k) {
+			cs = append(cs, k)
+		}
+	}
+	return cs
+}
+
+// logfmtColumns are chosen columns, or all shown keys of the line:
+// known keys first, others sorted
+func (l *LogLine) logfmtColumns(m map[string]interface{}) []string {
+	if len(l.columns) > 0 {
+		return l.columns
+	}
+	var cs, other []string
+	for _, k := range l.knownKeys {
+		if _, ok := m[k]; ok && l.show(k) {
+			cs = append(cs, k)
+		}
+	}
+	for k := range m {
+		if !l.isKnownKey(k) && l.show(k) {
+			other = append(other, k)
+		}
+	}
+	sort.Strings(other)
+	return append(cs, other...)
+}
+
+// formatValue converts json value to string, objects and arrays stay json
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case map[string]interface{}, []interface{}:
+		if buf, err := json.Marshal(t); err == nil {
+			return string(buf)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func (l *LogLine) writeLogfmt(m map[string]interface{}) {
+	var parts []string
+	for _, k := range l.logfmtColumns(m) {
+		v, ok := lookup(m, k)
+		if !ok {
+			continue
+		}
+		s := formatValue(v)
+		if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+			s = strconv.Quote(s)
+		}
+		parts = append(parts, k+"="+s)
+	}
+	fmt.Fprintf(&l.buf, "%s\n", strings.Join(parts, " "))
+}
+
+func (l *LogLine) writeCSV(m map[string]interface{}) error {
+	var rec []string
+	for _, k := range l.csvColumns() {
+		v, _ := lookup(m, k)
+		rec = append(rec, formatValue(v))
+	}
+	return l.csvRecord(rec)
+}
+
+// writeHeader writes csv header line
+func (l *LogLine) writeHeader() error {
+	if l.format != FormatCSV {
+		return nil
+	}
+	l.buf.Reset()
+	if err := l.csvRecord(l.csvColumns()); err != nil {
+		return err
+	}
+	_, err := l.output().Write(l.buf.Bytes())
+	return err
+}
+
+func (l *LogLine) csvRecord(rec []string) error {
+	w := csv.NewWriter(&l.buf)
+	w.Write(rec)
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,49 @@
+package monit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormats(t *testing.T) {
+	line := []byte(`{"app":"backend_api","level":"info","msg":"request done","status":200,"url":"/a=b"}`)
+
+	cases := []struct {
+		format   string
+		columns  []string
+		expected string
+	}{
+		{FormatLogfmt, nil, "app=backend_api level=info msg=\"request done\" status=200 url=\"/a=b\"\n"},
+		{FormatLogfmt, []string{"status", "msg"}, "status=200 msg=\"request done\"\n"},
+		{FormatCSV, []string{"level", "status", "msg", "missing"}, "level,status,msg,missing\ninfo,200,request done,\n"},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		l, err := TailOptions{Format: c.format, Columns: c.columns, out: &out}.logLine()
+		assert.Nil(t, err)
+		l.prefix = "backend_api"
+		assert.Nil(t, l.writeHeader())
+		assert.Nil(t, l.Print(line))
+		assert.Equal(t, c.expected, out.String())
+	}
+
+	_, err := TailOptions{Format: "xml"}.logLine()
+	assert.NotNil(t, err)
+}
+
+func TestDedup(t *testing.T) {
+	var out bytes.Buffer
+	l, err := TailOptions{Json: true, Dedup: true, out: &out}.logLine()
+	assert.Nil(t, err)
+	for _, line := range []string{
+		`{"time":"1","msg":"crash"}`,
+		`{"time":"2","msg":"crash"}`,
+		`{"time":"3","msg":"crash"}`,
+		`{"time":"4","msg":"started"}`,
+	} {
+		assert.Nil(t, l.Print([]byte(line+"\n")))
+	}
+	assert.Equal(t, "{\"time\":\"1\",\"msg\":\"crash\"}\n"+faint("last line repeated 2 times")+"\n{\"time\":\"4\",\"msg\":\"started\"}\n", out.String())
+}
@@ -1,7 +1,6 @@
 package monit
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,10 +8,12 @@ import (
 	"time"
 
 	"github.com/manifoldco/promptui"
+	"github.com/minus5/pitwall/fuzzy"
 	"github.com/minus5/svckit/log"
 )
 
 type GrepOptions struct {
+	Source    string // log source, nsq (default) or loki
 	Address   string
 	Dc        string
 	Service   string
@@ -23,6 +24,22 @@ type GrepOptions struct {
 	Filter    string
 	StartTime time.Time
 	EndTime   time.Time
+	Grep      []string // show only lines matching all regexps, regexp or attribute=regexp
+	GrepV     []string // drop lines matching any of regexps
+	Level     string   // minimal level of lines to show
+}
+
+// logLine creates log line printer with grep options filters
+func (o GrepOptions) logLine() (*LogLine, error) {
+	return TailOptions{
+		Json:    o.Json,
+		Pretty:  o.Pretty,
+		Exclude: o.Exclude,
+		Include: o.Include,
+		Grep:    o.Grep,
+		GrepV:   o.GrepV,
+		Level:   o.Level,
+	}.logLine()
 }
 
 func (o GrepOptions) url() string {
@@ -38,8 +55,21 @@ type cwReq struct {
 }
 
 func Grep(o GrepOptions) error {
+	logLine, err := o.logLine()
+	if err != nil {
+		return err
+	}
+	src, err := newLogSource(o.Source, o.Address)
+	if err != nil {
+		return err
+	}
 	if o.Service == "" {
-		services, err := getGrepServices(o)
+		var services []string
+		if sl, ok := src.(serviceLister); ok {
+			services, err = sl.Services()
+		} else {
+			services, err = getGrepServices(o)
+		}
 		if err != nil {
 			return err
 		}
@@ -49,29 +79,21 @@ func Grep(o GrepOptions) error {
 		}
 	}
 
-	r := cwReq{
-		Service: o.Service,
-		Dc:      o.Dc,
-		Filter:  o.Filter,
-	}
-	if !o.StartTime.IsZero() {
-		r.StartTime = &o.StartTime
-	}
-	if !o.EndTime.IsZero() {
-		r.Endtime = &o.EndTime
-	}
-
-	buf, _ := json.Marshal(r)
-	rsp, err := http.Post(o.url(), "application/json", bytes.NewBuffer(buf))
+	err = src.Search(Query{
+		Service:   o.Service,
+		Dc:        o.Dc,
+		Filter:    o.Filter,
+		Grep:      o.Grep,
+		GrepV:     o.GrepV,
+		Include:   o.Include,
+		Exclude:   o.Exclude,
+		StartTime: o.StartTime,
+		EndTime:   o.EndTime,
+	}, logLine.Print)
 	if err != nil {
 		log.Error(err)
-		return err
 	}
-	logLine := NewLogLine(o.Json, o.Pretty, o.Exclude, o.Include)
-	readSse(rsp.Body, func(data []byte) error {
-		return logLine.Print(data)
-	})
-	return nil
+	return err
 }
 
 func getGrepServices(o GrepOptions) ([]string, error) {
@@ -102,6 +124,8 @@ func selectGrepService(services []string) (string, error) {
 		Templates: &promptui.SelectTemplates{
 			Selected: string([]byte("\033[" + "1A")),
 		},
+		Searcher:          fuzzy.Searcher(services),
+		StartInSearchMode: true,
 	}
 	idx, _, err := prompt.Run()
 	if err != nil {
@@ -0,0 +1,33 @@
+package monit
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/manifoldco/promptui"
+)
+
+var highlightStyle = promptui.Styler(promptui.BGYellow, promptui.FGBlack)
+
+// parseHighlights compiles highlight patterns
+func parseHighlights(patterns []string) ([]*regexp.Regexp, error) {
+	var rxs []*regexp.Regexp
+	for _, p := range patterns {
+		rx, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid highlight pattern %s: %v", p, err)
+		}
+		rxs = append(rxs, rx)
+	}
+	return rxs, nil
+}
+
+// highlight colorizes matches of highlight patterns in s
+func (l *LogLine) highlight(s string) string {
+	for _, rx := range l.highlights {
+		s = rx.ReplaceAllStringFunc(s, func(m string) string {
+			return highlightStyle(m)
+		})
+	}
+	return s
+}
@@ -0,0 +1,19 @@
+package monit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	var out bytes.Buffer
+	l, err := TailOptions{Json: true, Highlight: []string{"49B6[0-9]+", "time.ut"}, out: &out}.logLine()
+	assert.Nil(t, err)
+	assert.Nil(t, l.Print([]byte(`{"req":"49B69912","msg":"timeout"}`)))
+	assert.Equal(t, `{"req":"`+highlightStyle("49B69912")+`","msg":"`+highlightStyle("timeout")+`"}`, out.String())
+
+	_, err = TailOptions{Highlight: []string{"("}}.logLine()
+	assert.NotNil(t, err)
+}
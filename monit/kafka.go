@@ -0,0 +1,75 @@
+package monit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// kafkaSource tails logs from Kafka, topic per service
+type kafkaSource struct {
+	brokers []string
+}
+
+// newKafkaSource creates source from comma separated list of brokers
+func newKafkaSource(address string) kafkaSource {
+	return kafkaSource{brokers: strings.Split(address, ",")}
+}
+
+// Tail reads all partitions of the service topic from the last offset.
+// There is no consumer group, so concurrent tails don't split messages
+// and nothing is left in the brokers after the tail.
+// Lines which handler fails to show are skipped, as in other sources.
+func (s kafkaSource) Tail(q Query, handler func([]byte) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	partitions, err := kafka.DefaultDialer.LookupPartitions(ctx, "tcp", s.brokers[0], q.Service)
+	if err != nil {
+		return err
+	}
+	msgs := make(chan []byte)
+	errs := make(chan error, len(partitions))
+	for _, p := range partitions {
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   s.brokers,
+			Topic:     q.Service,
+			Partition: p.ID,
+			MinBytes:  1,
+			MaxBytes:  10e6,
+			MaxWait:   time.Second,
+		})
+		defer r.Close()
+		if err := r.SetOffset(kafka.LastOffset); err != nil {
+			return err
+		}
+		go func(r *kafka.Reader) {
+			for {
+				m, err := r.ReadMessage(ctx)
+				if err != nil {
+					errs <- err
+					return
+				}
+				select {
+				case msgs <- m.Value:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(r)
+	}
+	for {
+		select {
+		case m := <-msgs:
+			handler(m)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+func (s kafkaSource) Search(q Query, handler func([]byte) error) error {
+	return fmt.Errorf("search is not supported by %s source", SourceKafka)
+}
@@ -0,0 +1,15 @@
+package monit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaSource(t *testing.T) {
+	src, err := newLogSource(SourceKafka, "kafka1:9092,kafka2:9092")
+	assert.NoError(t, err)
+	s := src.(kafkaSource)
+	assert.Equal(t, []string{"kafka1:9092", "kafka2:9092"}, s.brokers)
+	assert.Error(t, s.Search(Query{}, nil))
+}
@@ -1,24 +1,62 @@
 package monit
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/manifoldco/promptui"
 )
 
 type LogLine struct {
-	sizes     map[string]int
-	knownKeys []string
-	json      bool
-	pretty    bool
-	exclude   []string
-	include   []string
+	sizes      map[string]int
+	knownKeys  []string
+	json       bool
+	pretty     bool
+	exclude    []string
+	include    []string
+	prefix     string // printed before each line, service name when tailing multiple services
+	filters    []lineFilter
+	minLevel   int    // drop lines with level below
+	format     string // text (default), logfmt or csv
+	columns    []string
+	dedup      bool   // collapse consecutive identical lines
+	lastKey    string // last printed line without time
+	repeated   int    // number of lines same as last one
+	sample     sampler
+	highlights []*regexp.Regexp
+	where      whereExpr
+	alert      *alert
+	tp         *throughput
+	fields     []string // attribute paths to show
+	out        io.Writer
+	buf        bytes.Buffer
 }
 
+// output is where lines are written, stdout by default
+func (l *LogLine) output() io.Writer {
+	if l.out == nil {
+		return os.Stdout
+	}
+	return l.out
+}
+
+// structured is output in logfmt or csv format, which are
+// meant for other tools so service prefix is not printed, app key is there
+func (l *LogLine) structured() bool {
+	return !l.json && !l.pretty && (l.format == FormatLogfmt || l.format == FormatCSV)
+}
+
+// printMu serializes printing of lines from concurrent tails
+var printMu sync.Mutex
+
 func (l LogLine) show(key string) bool {
 	if len(l.exclude) > 0 {
 		for _, k := range l.exclude {
@@ -42,7 +80,7 @@ func (l LogLine) show(key string) bool {
 func NewLogLine(json, pretty bool, exclude, include []string) *LogLine {
 	return &LogLine{
 		sizes:     make(map[string]int),
-		knownKeys: []string{"time", "dc", "node", "host", "app", "file", "level", "msg"},
+		knownKeys: []string{"time", "dc", "node", "host", "app", "alloc", "file", "level", "msg"},
 		json:      json,
 		pretty:    pretty,
 		exclude:   exclude,
@@ -58,22 +96,85 @@ func formatTime(t time.Time) string {
 	return t.Format("15:04:05.999")
 }
 
-func (l *LogLine) Print(data []byte) error {
-	if l.json {
-		fmt.Printf("%s", data)
+func (l *LogLine) Print(data []byte) (err error) {
+	if l.skip(data) {
+		l.tp.add(true)
+		return nil
+	}
+	printMu.Lock()
+	defer printMu.Unlock()
+	// whole line is written at once, so it is not split between rotated files
+	l.buf.Reset()
+	l.alert.check(data)
+	if !l.sample.keep() {
+		l.tp.add(true)
+		return nil
+	}
+	l.tp.add(false)
+	if r := l.sample.report(time.Now()); r != "" {
+		if l.prefix != "" {
+			fmt.Fprintf(&l.buf, "%s ", l.prefix)
+		}
+		fmt.Fprintf(&l.buf, "%s\n", faint(r))
+	}
+	if l.dedup && !l.structured() {
+		key := dedupKey(data)
+		if key == l.lastKey {
+			l.repeated++
+			return nil
+		}
+		l.lastKey = key
+		l.writeRepeated()
+	}
+	defer func() {
+		if err == nil {
+			l.output().Write(l.buf.Bytes())
+		}
+	}()
+	if l.prefix != "" && !l.structured() {
+		fmt.Fprintf(&l.buf, "%s ", l.prefix)
+	}
+	if l.json && len(l.fields) == 0 {
+		fmt.Fprintf(&l.buf, "%s", l.highlight(string(data)))
 		return nil
 	}
 	var m map[string]interface{}
-	err := json.Unmarshal(data, &m)
+	err = json.Unmarshal(data, &m)
 	if err != nil {
 		return err
 	}
 
+	if len(l.fields) > 0 {
+		m = l.pickFields(m)
+		if l.json {
+			buf, err := json.Marshal(m)
+			fmt.Fprintf(&l.buf, "%s\n", l.highlight(string(buf)))
+			return err
+		}
+	}
+
 	if l.pretty {
 		buf, err := json.MarshalIndent(m, "", "  ")
-		fmt.Printf("%s\n", buf)
+		fmt.Fprintf(&l.buf, "%s\n", l.highlight(string(buf)))
 		return err
 	}
+	switch l.format {
+	case FormatLogfmt:
+		l.writeLogfmt(m)
+		return nil
+	case FormatCSV:
+		return l.writeCSV(m)
+	}
+
+	if len(l.fields) > 0 {
+		for _, f := range l.fields {
+			if v, ok := m[f]; ok {
+				l.print(f, v, true)
+			}
+		}
+		fmt.Fprintf(&l.buf, "\n")
+		return nil
+	}
 
 	for _, k := range l.knownKeys {
 		if !l.show(k) {
@@ -87,6 +188,8 @@ func (l *LogLine) Print(data []byte) error {
 					l.print(k, info(v), false)
 				case "error":
 					l.print(k, warn(v), false)
+				default:
+					l.print(k, v, false)
 				}
 			case "time":
 				t, err := time.Parse("2006-01-02T15:04:05.999999-07:00", v.(string))
@@ -112,11 +215,39 @@ func (l *LogLine) Print(data []byte) error {
 	for _, k := range otherKeys {
 		l.print(k, m[k], true)
 	}
-	fmt.Printf("\n")
+	fmt.Fprintf(&l.buf, "\n")
 
 	return nil
 }
 
+// timeKeys are ignored when comparing lines for duplicates
+var timeKeys = []string{"time", "ts", "timestamp", "@timestamp"}
+
+// dedupKey is line content without time attributes
+func dedupKey(data []byte) string {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return string(data)
+	}
+	for _, k := range timeKeys {
+		delete(m, k)
+	}
+	buf, _ := json.Marshal(m)
+	return string(buf)
+}
+
+// writeRepeated notes how many times the last line was repeated
+func (l *LogLine) writeRepeated() {
+	if l.repeated == 0 {
+		return
+	}
+	if l.prefix != "" {
+		fmt.Fprintf(&l.buf, "%s ", l.prefix)
+	}
+	fmt.Fprintf(&l.buf, "%s\n", faint(fmt.Sprintf("last line repeated %d times", l.repeated)))
+	l.repeated = 0
+}
+
 var maxValueSize = 50
 
 func (l *LogLine) print(key string, value interface{}, printKey bool) {
@@ -129,12 +260,12 @@ func (l *LogLine) print(key string, value interface{}, printKey bool) {
 	if len(strValue) == 0 {
 		return
 	}
-	strValue = l.formatSpaces(key, strValue)
+	strValue = l.highlight(l.formatSpaces(key, strValue))
 	if !printKey {
-		fmt.Printf("%v ", strValue)
+		fmt.Fprintf(&l.buf, "%v ", strValue)
 		return
 	}
-	fmt.Printf("%s%s%v ", faint(key), faint(":"), strValue)
+	fmt.Fprintf(&l.buf, "%s%s%v ", faint(key), faint(":"), strValue)
 }
 
 func (l *LogLine) formatSpaces(key, value string) string {
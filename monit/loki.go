@@ -0,0 +1,154 @@
+package monit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// lokiServiceLabel is stream label with service name
+	lokiServiceLabel = "app"
+	// lokiLimit is max number of lines in one query response
+	lokiLimit = 1000
+	// lokiPollInterval is interval of queries for new lines when tailing
+	lokiPollInterval = time.Second
+)
+
+// lokiSource reads logs from Grafana Loki
+type lokiSource struct {
+	url string
+}
+
+func newLokiSource(address string) lokiSource {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return lokiSource{url: strings.TrimSuffix(address, "/")}
+}
+
+type lokiEntry struct {
+	ts   int64
+	line string
+}
+
+// Tail polls Loki for lines newer than the last one received
+func (s lokiSource) Tail(q Query, handler func([]byte) error) error {
+	last := time.Now()
+	for {
+		time.Sleep(lokiPollInterval)
+		q.StartTime = last
+		q.EndTime = time.Now()
+		if err := s.Search(q, handler); err != nil {
+			return err
+		}
+		last = q.EndTime
+	}
+}
+
+// Search queries Loki for lines in time window, by pages of lokiLimit lines
+func (s lokiSource) Search(q Query, handler func([]byte) error) error {
+	end := q.EndTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	start := q.StartTime.UnixNano()
+	query := lokiQuery(q)
+	for {
+		es, err := s.queryRange(query, start, end.UnixNano())
+		if err != nil {
+			return err
+		}
+		for _, e := range es {
+			handler([]byte(e.line))
+		}
+		if len(es) < lokiLimit {
+			return nil
+		}
+		start = es[len(es)-1].ts + 1
+	}
+}
+
+// Services lists values of the service label
+func (s lokiSource) Services() ([]string, error) {
+	var rsp struct {
+		Data []string
+	}
+	if err := s.get(fmt.Sprintf("/loki/api/v1/label/%s/values", lokiServiceLabel), nil, &rsp); err != nil {
+		return nil, err
+	}
+	sort.Strings(rsp.Data)
+	return rsp.Data, nil
+}
+
+// lokiQuery builds LogQL query from service selector, line grep expressions and filter pipeline.
+// Attribute grep expressions are applied on the client.
+func lokiQuery(q Query) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "{%s=%s}", lokiServiceLabel, strconv.Quote(q.Service))
+	for i, exprs := range [][]string{q.Grep, q.GrepV} {
+		op := "|~"
+		if i == 1 {
+			op = "!~"
+		}
+		for _, e := range exprs {
+			if attrFilterRx.MatchString(e) {
+				continue
+			}
+			fmt.Fprintf(&b, " %s %s", op, strconv.Quote(e))
+		}
+	}
+	if q.Filter != "" {
+		fmt.Fprintf(&b, " %s", q.Filter)
+	}
+	return b.String()
+}
+
+func (s lokiSource) queryRange(query string, start, end int64) ([]lokiEntry, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start, 10))
+	params.Set("end", strconv.FormatInt(end, 10))
+	params.Set("limit", strconv.Itoa(lokiLimit))
+	params.Set("direction", "forward")
+	var rsp struct {
+		Data struct {
+			Result []struct {
+				Values [][2]string
+			}
+		}
+	}
+	if err := s.get("/loki/api/v1/query_range", params, &rsp); err != nil {
+		return nil, err
+	}
+	var es []lokiEntry
+	for _, r := range rsp.Data.Result {
+		for _, v := range r.Values {
+			ts, _ := strconv.ParseInt(v[0], 10, 64)
+			es = append(es, lokiEntry{ts: ts, line: v[1]})
+		}
+	}
+	sort.SliceStable(es, func(i, j int) bool { return es[i].ts < es[j].ts })
+	return es, nil
+}
+
+func (s lokiSource) get(path string, params url.Values, v interface{}) error {
+	u := s.url + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	rsp, err := http.Get(u)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("loki %s: %s", path, rsp.Status)
+	}
+	return json.NewDecoder(rsp.Body).Decode(v)
+}
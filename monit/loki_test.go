@@ -0,0 +1,40 @@
+package monit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLokiQuery(t *testing.T) {
+	q := Query{
+		Service: "backend_api",
+		Grep:    []string{"timeout", "level=error"},
+		GrepV:   []string{"health"},
+		Filter:  "| json",
+	}
+	assert.Equal(t, `{app="backend_api"} |~ "timeout" !~ "health" | json`, lokiQuery(q))
+}
+
+func TestLokiSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, `{app="api"}`, r.URL.Query().Get("query"))
+		w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"app":"api","node":"n2"},"values":[["20","{\"msg\":\"second\"}"]]},
+			{"stream":{"app":"api","node":"n1"},"values":[["10","{\"msg\":\"first\"}"]]}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	var lines []string
+	err := newLokiSource(srv.URL).Search(Query{Service: "api", StartTime: time.Now().Add(-time.Hour)}, func(data []byte) error {
+		lines = append(lines, string(data))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"msg":"first"}`, `{"msg":"second"}`}, lines)
+}
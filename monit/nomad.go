@@ -0,0 +1,127 @@
+package monit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// nomadLogTypes are streamed for each task
+var nomadLogTypes = []string{"stdout", "stderr"}
+
+// nomadSource streams logs of the running service allocations from Nomad
+type nomadSource struct {
+	address string
+}
+
+func newNomadSource(address string) nomadSource {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return nomadSource{address: address}
+}
+
+// Tail streams stdout and stderr of all tasks in running allocations of the service job.
+// Each line is labeled with alloc, task and stream attributes.
+// Lines which are not json are shown as msg.
+func (s nomadSource) Tail(q Query, handler func([]byte) error) error {
+	cli, err := api.NewClient(&api.Config{Address: s.address})
+	if err != nil {
+		return err
+	}
+	stubs, _, err := cli.Jobs().Allocations(q.Service, false, nil)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 1)
+	cancel := make(chan struct{})
+	defer close(cancel)
+	streams := 0
+	for _, stub := range stubs {
+		if stub.ClientStatus != "running" {
+			continue
+		}
+		alloc, _, err := cli.Allocations().Info(stub.ID, nil)
+		if err != nil {
+			return err
+		}
+		for task := range stub.TaskStates {
+			for _, logType := range nomadLogTypes {
+				frames, ferrs := cli.AllocFS().Logs(alloc, true, task, logType, "end", 0, cancel, nil)
+				labels := map[string]interface{}{"alloc": alloc.ID[:8], "task": task, "stream": logType}
+				streams++
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := readFrames(frames, ferrs, labels, handler); err != nil {
+						select {
+						case errs <- err:
+						default:
+						}
+					}
+				}()
+			}
+		}
+	}
+	if streams == 0 {
+		return fmt.Errorf("no running allocations of %s", q.Service)
+	}
+	wg.Wait()
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (s nomadSource) Search(q Query, handler func([]byte) error) error {
+	return fmt.Errorf("search is not supported by %s source", SourceNomad)
+}
+
+// readFrames splits log frames to lines, until the stream is closed
+func readFrames(frames <-chan *api.StreamFrame, errs <-chan error, labels map[string]interface{}, handler func([]byte) error) error {
+	var buf []byte
+	for {
+		select {
+		case f, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			if f == nil || f.IsHeartbeat() {
+				continue
+			}
+			buf = append(buf, f.Data...)
+			for {
+				i := bytes.IndexByte(buf, '\n')
+				if i < 0 {
+					break
+				}
+				if line := bytes.TrimSpace(buf[:i]); len(line) > 0 {
+					handler(labelLine(line, labels))
+				}
+				buf = buf[i+1:]
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// labelLine adds labels to json log line, or wraps plain text line in json msg
+func labelLine(line []byte, labels map[string]interface{}) []byte {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(line, &m); err != nil {
+		m = map[string]interface{}{"msg": string(line)}
+	}
+	for k, v := range labels {
+		m[k] = v
+	}
+	buf, _ := json.Marshal(m)
+	return buf
+}
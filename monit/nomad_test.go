@@ -0,0 +1,25 @@
+package monit
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFrames(t *testing.T) {
+	frames := make(chan *api.StreamFrame, 3)
+	frames <- &api.StreamFrame{Data: []byte("{\"msg\":\"first\"}\nplain ")}
+	frames <- &api.StreamFrame{Data: []byte("text\n")}
+	close(frames)
+	var lines []string
+	err := readFrames(frames, nil, map[string]interface{}{"alloc": "abcd1234"}, func(data []byte) error {
+		lines = append(lines, string(data))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"alloc":"abcd1234","msg":"first"}`,
+		`{"alloc":"abcd1234","msg":"plain text"}`,
+	}, lines)
+}
@@ -0,0 +1,112 @@
+package monit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/minus5/svckit/env"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConfigFile is pitwall CLI config file
+var ConfigFile = "~/.config/pitwall/config.yml"
+
+const profilesKey = "profiles"
+
+// Profile is saved combination of flags
+type Profile struct {
+	Services []string            `yaml:"services,omitempty"`
+	Flags    map[string][]string `yaml:"flags,omitempty"`
+}
+
+// readConfig reads CLI config file, empty if it doesn't exist
+func readConfig() (map[string]interface{}, error) {
+	cfg := make(map[string]interface{})
+	buf, err := ioutil.ReadFile(env.ExpandPath(ConfigFile))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(buf, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", ConfigFile, err)
+	}
+	return cfg, nil
+}
+
+func readProfiles(cfg map[string]interface{}) (map[string]*Profile, error) {
+	var profiles map[string]*Profile
+	buf, err := yaml.Marshal(cfg[profilesKey])
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(buf, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %v", profilesKey, ConfigFile, err)
+	}
+	if profiles == nil {
+		profiles = make(map[string]*Profile)
+	}
+	return profiles, nil
+}
+
+// LoadProfile finds profile by name in CLI config file
+func LoadProfile(name string) (*Profile, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := readProfiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s not found in %s", name, ConfigFile)
+	}
+	return p, nil
+}
+
+// SaveProfile stores profile into CLI config file,
+// replacing existing profile with the same name
+func SaveProfile(name string, p *Profile) error {
+	cfg, err := readConfig()
+	if err != nil {
+		return err
+	}
+	profiles, err := readProfiles(cfg)
+	if err != nil {
+		return err
+	}
+	profiles[name] = p
+	cfg[profilesKey] = profiles
+	return writeConfig(cfg)
+}
+
+// SetConfig sets top level key of the CLI config file, removes it if value is nil
+func SetConfig(key string, value interface{}) error {
+	cfg, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		delete(cfg, key)
+	} else {
+		cfg[key] = value
+	}
+	return writeConfig(cfg)
+}
+
+func writeConfig(cfg map[string]interface{}) error {
+	buf, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	fn := env.ExpandPath(ConfigFile)
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, buf, 0644)
+}
@@ -0,0 +1,38 @@
+package monit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer func(fn string) { ConfigFile = fn }(ConfigFile)
+	ConfigFile = filepath.Join(dir, "pitwall", "config.yml")
+	os.MkdirAll(filepath.Dir(ConfigFile), 0755)
+	assert.Nil(t, ioutil.WriteFile(ConfigFile, []byte("other: value\n"), 0644))
+
+	_, err = LoadProfile("api-errors")
+	assert.NotNil(t, err)
+
+	p := &Profile{
+		Services: []string{"backend_api"},
+		Flags:    map[string][]string{"level": {"error"}, "grep-v": {"url=/health", "url=/ping"}},
+	}
+	assert.Nil(t, SaveProfile("api-errors", p))
+	assert.Nil(t, SaveProfile("other", &Profile{}))
+
+	p2, err := LoadProfile("api-errors")
+	assert.Nil(t, err)
+	assert.Equal(t, p, p2)
+
+	cfg, err := readConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "value", cfg["other"])
+}
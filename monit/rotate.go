@@ -0,0 +1,100 @@
+package monit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// defaults for rotation of the tail output file
+const (
+	DefaultRotateSize = 100 * 1000 * 1000
+	DefaultRotateKeep = 5
+)
+
+// rotateWriter writes to file, when file reaches max size it is
+// renamed to file.1, previous file.1 to file.2... keeping last keep files.
+type rotateWriter struct {
+	fn      string
+	maxSize int64
+	keep    int
+	f       *os.File
+	size    int64
+	sync.Mutex
+}
+
+func newRotateWriter(fn string, maxSize int64, keep int) (*rotateWriter, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultRotateSize
+	}
+	if keep <= 0 {
+		keep = DefaultRotateKeep
+	}
+	w := &rotateWriter{fn: fn, maxSize: maxSize, keep: keep}
+	return w, w.open()
+}
+
+func (w *rotateWriter) open() error {
+	f, err := os.OpenFile(w.fn, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = fi.Size()
+	return nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", w.fn, w.keep))
+	for i := w.keep - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", w.fn, i), fmt.Sprintf("%s.%d", w.fn, i+1))
+	}
+	if err := os.Rename(w.fn, w.fn+".1"); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func (w *rotateWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.f.Close()
+}
+
+// ansiRx matches terminal color escape sequences
+var ansiRx = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// plainWriter removes terminal colors before writing to file
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := p.w.Write(ansiRx.ReplaceAll(b, nil)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,38 @@
+package monit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "tail.log")
+
+	w, err := newRotateWriter(fn, 10, 2)
+	assert.Nil(t, err)
+	p := plainWriter{w}
+	for _, l := range []string{"\x1b[36mline1\x1b[0m\n", "line2\n", "line3\n", "line4\n"} {
+		_, err := p.Write([]byte(l))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Close())
+
+	for f, expected := range map[string]string{
+		fn:        "line4\n",
+		fn + ".1": "line3\n",
+		fn + ".2": "line2\n",
+	} {
+		buf, err := ioutil.ReadFile(f)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(buf))
+	}
+	_, err = os.Stat(fn + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,61 @@
+package monit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sampleReport is how often drop counters are shown
+var sampleReport = 10 * time.Second
+
+// parseSample parses sample rate in form 1/N or N
+func parseSample(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n := strings.TrimPrefix(s, "1/")
+	rate, err := strconv.Atoi(n)
+	if err != nil || rate < 1 {
+		return 0, fmt.Errorf("invalid sample %s, expecting 1/N", s)
+	}
+	return rate, nil
+}
+
+// sampler keeps every Nth line and counts dropped
+type sampler struct {
+	rate       int
+	kept       int
+	dropped    int
+	lastReport time.Time
+}
+
+// keep counts the line, returns false if it should be dropped
+func (s *sampler) keep() bool {
+	if s.rate <= 1 {
+		return true
+	}
+	if (s.kept+s.dropped)%s.rate != 0 {
+		s.dropped++
+		return false
+	}
+	s.kept++
+	return true
+}
+
+// report returns drop counters note, if it is time to show it
+func (s *sampler) report(now time.Time) string {
+	if s.rate <= 1 {
+		return ""
+	}
+	if s.lastReport.IsZero() {
+		s.lastReport = now
+		return ""
+	}
+	if now.Sub(s.lastReport) < sampleReport {
+		return ""
+	}
+	s.lastReport = now
+	return fmt.Sprintf("sampling 1/%d: %d lines shown, %d dropped", s.rate, s.kept, s.dropped)
+}
@@ -0,0 +1,37 @@
+package monit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSample(t *testing.T) {
+	for s, expected := range map[string]int{"": 0, "1/100": 100, "10": 10, "1/1": 1} {
+		rate, err := parseSample(s)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, rate)
+	}
+	for _, s := range []string{"1/0", "2/100", "x"} {
+		_, err := parseSample(s)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := sampler{rate: 3}
+	var kept []int
+	for i := 0; i < 10; i++ {
+		if s.keep() {
+			kept = append(kept, i)
+		}
+	}
+	assert.Equal(t, []int{0, 3, 6, 9}, kept)
+	assert.Equal(t, 6, s.dropped)
+
+	now := time.Now()
+	assert.Equal(t, "", s.report(now))
+	assert.Equal(t, "", s.report(now.Add(time.Second)))
+	assert.Equal(t, "sampling 1/3: 4 lines shown, 6 dropped", s.report(now.Add(sampleReport)))
+}
@@ -0,0 +1,15 @@
+package monit
+
+import "fmt"
+
+// Search queries persistent log store for the time window.
+// Lines are filtered by the same include/exclude/grep/level options as in tail.
+func Search(o GrepOptions) error {
+	if o.StartTime.IsZero() {
+		return fmt.Errorf("search start time (--since) is required")
+	}
+	if !o.EndTime.IsZero() && o.EndTime.Before(o.StartTime) {
+		return fmt.Errorf("search end time is before start time")
+	}
+	return Grep(o)
+}
@@ -0,0 +1,15 @@
+package monit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTimeWindow(t *testing.T) {
+	assert.Error(t, Search(GrepOptions{}))
+	now := time.Now()
+	assert.Error(t, Search(GrepOptions{StartTime: now, EndTime: now.Add(-time.Hour)}))
+	assert.Error(t, Search(GrepOptions{StartTime: now, Level: "verbose"}))
+}
@@ -0,0 +1,98 @@
+package monit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// log sources
+const (
+	SourceNSQ   = "nsq"  // live logs from nsq_notifier, history from nsq_to_cloudwatch
+	SourceLoki  = "loki" // Grafana Loki
+	SourceES    = "elasticsearch"
+	SourceOS    = "opensearch"
+	SourceKafka = "kafka" // topic per service
+	SourceNomad = "nomad" // allocation logs directly from Nomad
+)
+
+// LogSource reads structured log lines of the service
+type LogSource interface {
+	// Tail calls handler for each new log line of the query service, until stream is closed
+	Tail(q Query, handler func([]byte) error) error
+	// Search calls handler for each log line of the service in the query time window
+	Search(q Query, handler func([]byte) error) error
+}
+
+// serviceLister is implemented by sources which can list services
+type serviceLister interface {
+	Services() ([]string, error)
+}
+
+// Query is search of the service logs
+type Query struct {
+	Service   string
+	Dc        string
+	Filter    string   // source specific filter, CloudWatch filter pattern or LogQL pipeline
+	Grep      []string // line regexps, sources which support it filter on the server side
+	GrepV     []string
+	Include   []string // attributes to show, sources which support it return only those
+	Exclude   []string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// newLogSource creates log source by name, nsq if name is empty
+func newLogSource(name, address string) (LogSource, error) {
+	switch name {
+	case "", SourceNSQ:
+		return nsqSource{address: address}, nil
+	case SourceLoki:
+		return newLokiSource(address), nil
+	case SourceES, SourceOS:
+		return newESSource(address), nil
+	case SourceKafka:
+		return newKafkaSource(address), nil
+	case SourceNomad:
+		return newNomadSource(address), nil
+	}
+	return nil, fmt.Errorf("unknown log source %s", name)
+}
+
+// nsqSource tails logs from the nsq_notifier server sent events,
+// and searches logs through nsq_to_cloudwatch
+type nsqSource struct {
+	address string
+}
+
+func (s nsqSource) Tail(q Query, handler func([]byte) error) error {
+	rsp, err := http.Get(fmt.Sprintf("http://%s/services/%s", s.address, q.Service))
+	if err != nil {
+		return err
+	}
+	readSse(rsp.Body, handler)
+	return nil
+}
+
+func (s nsqSource) Search(q Query, handler func([]byte) error) error {
+	r := cwReq{
+		Service: q.Service,
+		Dc:      q.Dc,
+		Filter:  q.Filter,
+	}
+	if !q.StartTime.IsZero() {
+		r.StartTime = &q.StartTime
+	}
+	if !q.EndTime.IsZero() {
+		r.Endtime = &q.EndTime
+	}
+	buf, _ := json.Marshal(r)
+	rsp, err := http.Post(fmt.Sprintf("http://%s/logs", s.address), "application/json", bytes.NewBuffer(buf))
+	if err != nil {
+		return err
+	}
+	readSse(rsp.Body, handler)
+	return nil
+}
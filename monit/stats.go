@@ -0,0 +1,167 @@
+package monit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stats refresh interval and number of top error messages shown
+var (
+	statsInterval = time.Second
+	statsTop      = 10
+)
+
+// lineStats aggregates tailed log lines
+type lineStats struct {
+	started time.Time
+	total   int
+	last    int // total at the last refresh
+	rate    float64
+	levels  map[string]int
+	loggers map[string]int
+	errors  map[string]int
+	sync.Mutex
+}
+
+func newLineStats() *lineStats {
+	return &lineStats{
+		started: time.Now(),
+		levels:  make(map[string]int),
+		loggers: make(map[string]int),
+		errors:  make(map[string]int),
+	}
+}
+
+// add counts the log line
+func (s *lineStats) add(data []byte) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.total++
+	lvl := strings.ToLower(formatValue(m["level"]))
+	if lvl == "" {
+		lvl = "none"
+	}
+	s.levels[lvl]++
+	if f := formatValue(m["file"]); f != "" {
+		s.loggers[f]++
+	}
+	if levels[lvl] >= levels["error"] {
+		if msg := formatValue(m["msg"]); msg != "" {
+			s.errors[msg]++
+		}
+	}
+}
+
+// tick calculates rate of lines since the last tick
+func (s *lineStats) tick(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.rate = float64(s.total-s.last) / d.Seconds()
+	s.last = s.total
+}
+
+type statCount struct {
+	key   string
+	count int
+}
+
+// sortedCounts returns counts sorted by count desc, limited to top n if n > 0
+func sortedCounts(m map[string]int, n int) []statCount {
+	var cs []statCount
+	for k, v := range m {
+		cs = append(cs, statCount{k, v})
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].count == cs[j].count {
+			return cs[i].key < cs[j].key
+		}
+		return cs[i].count > cs[j].count
+	})
+	if n > 0 && len(cs) > n {
+		cs = cs[:n]
+	}
+	return cs
+}
+
+func (s *lineStats) print(w io.Writer, service string) {
+	s.Lock()
+	defer s.Unlock()
+	elapsed := time.Since(s.started)
+	avg := 0.0
+	if elapsed.Seconds() > 0 {
+		avg = float64(s.total) / elapsed.Seconds()
+	}
+	fmt.Fprintf(w, "%s %s\n\n", info(service), faint(fmt.Sprintf("for %s", elapsed.Round(time.Second))))
+	fmt.Fprintf(w, "lines: %d  rate: %.1f/s  avg: %.1f/s\n", s.total, s.rate, avg)
+	s.printCounts(w, "levels", s.levels, 0)
+	s.printCounts(w, "loggers", s.loggers, statsTop)
+	s.printCounts(w, "top errors", s.errors, statsTop)
+}
+
+func (s *lineStats) printCounts(w io.Writer, title string, m map[string]int, n int) {
+	fmt.Fprintf(w, "\n%s:\n", faint(title))
+	for _, c := range sortedCounts(m, n) {
+		key := c.key
+		if len(key) > 100 {
+			key = key[:100] + "..."
+		}
+		if title == "levels" && levels[c.key] >= levels["error"] {
+			key = warn(key)
+		}
+		fmt.Fprintf(w, "%8d  %s\n", c.count, key)
+	}
+}
+
+// clearScreen moves cursor to the top and clears terminal
+const clearScreen = "\033[H\033[2J"
+
+// Stats tails service logs and instead of printing lines shows
+// rolling counts per level, logger, top error messages and rate.
+func Stats(o TailOptions) error {
+	l, err := o.logLine()
+	if err != nil {
+		return err
+	}
+	src, err := newLogSource(o.Source, o.Address)
+	if err != nil {
+		return err
+	}
+	if o.Service == "" {
+		if o.Service, err = selectSourceService(src, o); err != nil {
+			return err
+		}
+	}
+	s := newLineStats()
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(statsInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				s.tick(statsInterval)
+				fmt.Print(clearScreen)
+				s.print(os.Stdout, o.Service)
+			case <-done:
+				return
+			}
+		}
+	}()
+	defer close(done)
+	return src.Tail(o.query(), func(data []byte) error {
+		if !l.skip(data) {
+			s.add(data)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,30 @@
+package monit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineStats(t *testing.T) {
+	s := newLineStats()
+	for _, l := range []string{
+		`{"level":"info","file":"api.go:10","msg":"ok"}`,
+		`{"level":"info","file":"api.go:10","msg":"ok"}`,
+		`{"level":"error","file":"db.go:42","msg":"timeout"}`,
+		`{"level":"error","file":"db.go:42","msg":"timeout"}`,
+		`{"level":"fatal","file":"main.go:1","msg":"exit"}`,
+		`{"msg":"no level"}`,
+		`not json`,
+	} {
+		s.add([]byte(l))
+	}
+	s.tick(2 * time.Second)
+
+	assert.Equal(t, 6, s.total)
+	assert.Equal(t, 3.0, s.rate)
+	assert.Equal(t, map[string]int{"info": 2, "error": 2, "fatal": 1, "none": 1}, s.levels)
+	assert.Equal(t, []statCount{{"timeout", 2}, {"exit", 1}}, sortedCounts(s.errors, 0))
+	assert.Equal(t, []statCount{{"api.go:10", 2}}, sortedCounts(s.loggers, 1))
+}
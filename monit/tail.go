@@ -8,41 +8,257 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	units "github.com/docker/go-units"
 	"github.com/manifoldco/promptui"
+	"github.com/minus5/pitwall/fuzzy"
 )
 
 type TailOptions struct {
-	Address string
-	Service string
-	Json    bool
-	Pretty  bool
-	Exclude []string
-	Include []string
+	Source   string // log source, nsq (default) or loki
+	Address  string
+	Service  string
+	Services []string // tail multiple services at once, lines are prefixed with service name
+	// log source address by datacenter, to tail in multiple datacenters at once,
+	// lines are prefixed with datacenter name
+	DcAddresses map[string]string
+	Json        bool
+	Pretty      bool
+	Exclude     []string
+	Include     []string
+	Fields      []string // attribute paths to show, like req.headers.x-request-id or items[0].id
+	Grep        []string // show only lines matching all regexps, regexp or attribute=regexp
+	GrepV       []string // drop lines matching any of regexps
+	Level       string   // minimal level of lines to show, like warn
+	Format      string   // output format: text (default), logfmt or csv
+	Columns     []string // columns of logfmt and csv output
+	Dedup       bool     // collapse consecutive identical lines, ignoring time
+	Sample      string   // keep only every Nth matching line, like 1/100
+	Highlight   []string // colorize matches of regexps in lines
+	Where       string   // expression on attributes, like status >= 500 && duration > 200ms
+	Alert       string   // expression on attributes, terminal bell rings when matching line appears
+	AlertCmd    string   // shell command run on alert
+
+	Throughput time.Duration // interval of showing received and filtered messages rate, zero to disable
+	Nsqd       string        // nsqd http address, for channel depth
+	Lag        string        // nsq topic/channel for which depth is shown with throughput
+
+	Out        string // also write lines to this file
+	RotateSize int64  // rotate out file when it reaches size in bytes
+	RotateKeep int    // number of rotated files to keep
+
+	out io.Writer
+	tp  *throughput
 }
 
 func (o TailOptions) servicesUrl() string {
 	return fmt.Sprintf("http://%s/services", o.Address)
 }
 
-func (o TailOptions) logsUrl() string {
-	return fmt.Sprintf("http://%s/services/%s", o.Address, o.Service)
+// query of the tailed service logs
+func (o TailOptions) query() Query {
+	return Query{
+		Service: o.Service,
+		Grep:    o.Grep,
+		GrepV:   o.GrepV,
+		Include: o.Include,
+		Exclude: o.Exclude,
+	}
 }
 
-func Tail(o TailOptions) {
-	if o.Service == "" {
-		services, err := getServices(o)
+// logLine creates log line printer with tail options filters
+func (o TailOptions) logLine() (*LogLine, error) {
+	l := NewLogLine(o.Json, o.Pretty, o.Exclude, o.Include)
+	fs, err := parseFilters(o.Grep, o.GrepV)
+	if err != nil {
+		return nil, err
+	}
+	l.filters = fs
+	l.out = o.out
+	l.tp = o.tp
+	if err := validFormat(o.Format); err != nil {
+		return nil, err
+	}
+	l.format = o.Format
+	l.columns = o.Columns
+	l.fields = o.Fields
+	if len(l.columns) == 0 {
+		l.columns = o.Fields
+	}
+	l.dedup = o.Dedup
+	if l.sample.rate, err = parseSample(o.Sample); err != nil {
+		return nil, err
+	}
+	if l.highlights, err = parseHighlights(o.Highlight); err != nil {
+		return nil, err
+	}
+	if l.where, err = parseWhere(o.Where); err != nil {
+		return nil, err
+	}
+	if l.alert, err = newAlert(o.Alert, o.AlertCmd); err != nil {
+		return nil, err
+	}
+	if l.minLevel, err = parseLevel(o.Level); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func Tail(o TailOptions) error {
+	if _, err := o.logLine(); err != nil {
+		return err
+	}
+	src, err := newLogSource(o.Source, o.Address)
+	if err != nil {
+		return err
+	}
+	if o.Out != "" {
+		w, err := newRotateWriter(o.Out, o.RotateSize, o.RotateKeep)
 		if err != nil {
-			return
+			return err
+		}
+		defer w.Close()
+		o.out = io.MultiWriter(os.Stdout, plainWriter{w})
+	}
+	if o.Throughput > 0 {
+		if o.tp, err = newThroughput(o.Nsqd, o.Lag); err != nil {
+			return err
+		}
+		done := make(chan struct{})
+		defer close(done)
+		go o.tp.run(os.Stderr, o.Throughput, done)
+	}
+	if l, _ := o.logLine(); l.structured() {
+		if err := l.writeHeader(); err != nil {
+			return err
 		}
-		o.Service, err = selectService(services)
+	}
+	if len(o.Services) == 0 && o.Service == "" {
+		if o.Service, err = selectSourceService(src, o); err != nil {
+			return err
+		}
+	}
+	if len(o.Services) == 0 {
+		o.Services = []string{o.Service}
+	}
+	if len(o.Services) > 1 || len(o.DcAddresses) > 1 {
+		tailMany(o)
+		return nil
+	}
+	o.Service = o.Services[0]
+	if addr, ok := o.DcAddresses[firstDc(o.DcAddresses)]; ok {
+		o.Address = addr
+	}
+	l, _ := o.logLine()
+	return tail(o, l)
+}
+
+// selectSourceService asks for the service from the list of source services
+func selectSourceService(src LogSource, o TailOptions) (string, error) {
+	if sl, ok := src.(serviceLister); ok {
+		services, err := sl.Services()
 		if err != nil {
-			return
+			return "", err
 		}
+		return selectGrepService(services)
+	}
+	if _, ok := src.(nsqSource); !ok {
+		return "", fmt.Errorf("service is required for %s source", o.Source)
+	}
+	services, err := getServices(o)
+	if err != nil {
+		return "", err
+	}
+	return selectService(services)
+}
+
+// prefixColors are used to distinguish services when tailing multiple at once
+var prefixColors = []func(interface{}) string{
+	promptui.Styler(promptui.FGCyan),
+	promptui.Styler(promptui.FGMagenta),
+	promptui.Styler(promptui.FGYellow),
+	promptui.Styler(promptui.FGGreen),
+	promptui.Styler(promptui.FGBlue),
+}
+
+// firstDc returns first of sorted datacenters
+func firstDc(addrs map[string]string) string {
+	dcs := sortedDcs(addrs)
+	if len(dcs) == 0 {
+		return ""
 	}
-	tail(o)
+	return dcs[0]
+}
+
+func sortedDcs(addrs map[string]string) []string {
+	var dcs []string
+	for dc := range addrs {
+		dcs = append(dcs, dc)
+	}
+	sort.Strings(dcs)
+	return dcs
+}
+
+// tailStream is one of concurrently tailed streams
+type tailStream struct {
+	label string
+	o     TailOptions
+}
+
+// streams returns stream for each service in each datacenter.
+// Label is datacenter and service name, if there are multiple of them.
+func (o TailOptions) streams() []tailStream {
+	dcs := sortedDcs(o.DcAddresses)
+	if len(dcs) == 0 {
+		dcs = []string{""}
+	}
+	var ss []tailStream
+	for _, dc := range dcs {
+		for _, s := range o.Services {
+			so := o
+			so.Service = s
+			var label []string
+			if dc != "" {
+				so.Address = o.DcAddresses[dc]
+				label = append(label, dc)
+			}
+			if len(o.Services) > 1 {
+				label = append(label, s)
+			}
+			ss = append(ss, tailStream{label: strings.Join(label, " "), o: so})
+		}
+	}
+	return ss
+}
+
+// tailMany tails all streams concurrently, until all streams are closed
+func tailMany(o TailOptions) {
+	streams := o.streams()
+	width := 0
+	for _, s := range streams {
+		if len(s.label) > width {
+			width = len(s.label)
+		}
+	}
+	var wg sync.WaitGroup
+	for i, s := range streams {
+		so := s.o
+		l, _ := o.logLine()
+		l.prefix = prefixColors[i%len(prefixColors)](fmt.Sprintf("%-*s", width, s.label))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tail(so, l); err != nil {
+				fmt.Printf("%s %s\n", l.prefix, warn(err))
+			}
+		}()
+	}
+	wg.Wait()
 }
 
 type service struct {
@@ -57,6 +273,10 @@ func (s service) String() string {
 }
 
 func selectService(services []service) (string, error) {
+	var names []string
+	for _, s := range services {
+		names = append(names, s.Service)
+	}
 	prompt := promptui.Select{
 		Label: "Select service:",
 		Items: services,
@@ -64,6 +284,8 @@ func selectService(services []service) (string, error) {
 		Templates: &promptui.SelectTemplates{
 			Selected: string([]byte("\033[" + "1A")),
 		},
+		Searcher:          fuzzy.Searcher(names),
+		StartInSearchMode: true,
 	}
 	idx, _, err := prompt.Run()
 	if err != nil {
@@ -92,16 +314,12 @@ func getServices(o TailOptions) ([]service, error) {
 var dataLinePrefix = []byte("data: ")
 var heartbeatLinepPrefix = []byte("event: heartbeat")
 
-func tail(o TailOptions) error {
-	rsp, err := http.Get(o.logsUrl())
+func tail(o TailOptions, logLine *LogLine) error {
+	src, err := newLogSource(o.Source, o.Address)
 	if err != nil {
 		return err
 	}
-	logLine := NewLogLine(o.Json, o.Pretty, o.Exclude, o.Include)
-	readSse(rsp.Body, func(data []byte) error {
-		return logLine.Print(data)
-	})
-	return nil
+	return src.Tail(o.query(), logLine.Print)
 }
 
 func readSse(body io.ReadCloser, lineHanlder func([]byte) error) error {
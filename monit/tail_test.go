@@ -13,3 +13,23 @@ func TestTimeParse(t *testing.T) {
 	assert.Nil(t, err)
 	//fmt.Print(tm)
 }
+
+func TestStreams(t *testing.T) {
+	o := TailOptions{
+		Address:     "default:4151",
+		Services:    []string{"backend_api", "haproxy"},
+		DcAddresses: map[string]string{"pg2": "pg2:4151", "pg1": "pg1:4151"},
+	}
+	var labels, addrs []string
+	for _, s := range o.streams() {
+		labels = append(labels, s.label)
+		addrs = append(addrs, s.o.Address+"/"+s.o.Service)
+	}
+	assert.Equal(t, []string{"pg1 backend_api", "pg1 haproxy", "pg2 backend_api", "pg2 haproxy"}, labels)
+	assert.Equal(t, []string{"pg1:4151/backend_api", "pg1:4151/haproxy", "pg2:4151/backend_api", "pg2:4151/haproxy"}, addrs)
+
+	o.DcAddresses = nil
+	ss := o.streams()
+	assert.Equal(t, "haproxy", ss[1].label)
+	assert.Equal(t, "default:4151", ss[1].o.Address)
+}
@@ -0,0 +1,119 @@
+package monit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// throughput counts received and filtered lines of all tailed streams
+type throughput struct {
+	received int64
+	filtered int64
+	nsqd     string // nsqd http address
+	topic    string // nsq topic and channel for which depth is shown
+	channel  string
+}
+
+// newThroughput creates counters, lag is nsq topic/channel to show depth for
+func newThroughput(nsqd, lag string) (*throughput, error) {
+	t := &throughput{nsqd: nsqd}
+	if lag == "" {
+		return t, nil
+	}
+	parts := strings.Split(lag, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid lag %s, expecting topic/channel", lag)
+	}
+	if nsqd == "" {
+		return nil, fmt.Errorf("nsqd address is required for channel lag")
+	}
+	t.topic, t.channel = parts[0], parts[1]
+	return t, nil
+}
+
+func (t *throughput) add(filtered bool) {
+	if t == nil {
+		return
+	}
+	atomic.AddInt64(&t.received, 1)
+	if filtered {
+		atomic.AddInt64(&t.filtered, 1)
+	}
+}
+
+// report returns rates since the last report and resets counters
+func (t *throughput) report(d time.Duration) string {
+	received := atomic.SwapInt64(&t.received, 0)
+	filtered := atomic.SwapInt64(&t.filtered, 0)
+	s := fmt.Sprintf("%.1f msg/s received, %.1f msg/s filtered", float64(received)/d.Seconds(), float64(filtered)/d.Seconds())
+	if t.topic == "" {
+		return s
+	}
+	depth, err := nsqDepth(t.nsqd, t.topic, t.channel)
+	if err != nil {
+		return fmt.Sprintf("%s, %s/%s depth unknown: %v", s, t.topic, t.channel, err)
+	}
+	return fmt.Sprintf("%s, %s/%s depth %d", s, t.topic, t.channel, depth)
+}
+
+// run writes report to w each interval, until done is closed
+func (t *throughput) run(w io.Writer, interval time.Duration, done chan struct{}) {
+	tc := time.NewTicker(interval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-tc.C:
+			fmt.Fprintf(w, "%s\n", faint(t.report(interval)))
+		case <-done:
+			return
+		}
+	}
+}
+
+type nsqStats struct {
+	Topics []struct {
+		TopicName string `json:"topic_name"`
+		Channels  []struct {
+			ChannelName  string `json:"channel_name"`
+			Depth        int64  `json:"depth"`
+			BackendDepth int64  `json:"backend_depth"`
+		} `json:"channels"`
+	} `json:"topics"`
+	// older nsqd versions wrap response in data
+	Data *nsqStats `json:"data"`
+}
+
+// nsqDepth finds number of messages waiting in nsqd channel
+func nsqDepth(nsqd, topic, channel string) (int64, error) {
+	rsp, err := http.Get(fmt.Sprintf("http://%s/stats?format=json&topic=%s", nsqd, topic))
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("nsqd stats: %s", rsp.Status)
+	}
+	var s nsqStats
+	if err := json.NewDecoder(rsp.Body).Decode(&s); err != nil {
+		return 0, err
+	}
+	if s.Data != nil {
+		s = *s.Data
+	}
+	for _, t := range s.Topics {
+		if t.TopicName != topic {
+			continue
+		}
+		for _, c := range t.Channels {
+			if c.ChannelName == channel {
+				return c.Depth + c.BackendDepth, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("channel not found")
+}
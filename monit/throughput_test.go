@@ -0,0 +1,36 @@
+package monit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThroughput(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/stats", r.URL.Path)
+		assert.Equal(t, "log", r.URL.Query().Get("topic"))
+		w.Write([]byte(`{"topics":[{"topic_name":"log","channels":[
+			{"channel_name":"other","depth":1},
+			{"channel_name":"nsq_notifier","depth":120,"backend_depth":5}]}]}`))
+	}))
+	defer ts.Close()
+	nsqd := strings.TrimPrefix(ts.URL, "http://")
+
+	tp, err := newThroughput(nsqd, "log/nsq_notifier")
+	assert.Nil(t, err)
+	for i := 0; i < 20; i++ {
+		tp.add(i%4 == 0)
+	}
+	assert.Equal(t, "2.0 msg/s received, 0.5 msg/s filtered, log/nsq_notifier depth 125", tp.report(10*time.Second))
+	assert.Equal(t, "0.0 msg/s received, 0.0 msg/s filtered, log/nsq_notifier depth 125", tp.report(10*time.Second))
+
+	_, err = newThroughput(nsqd, "log")
+	assert.NotNil(t, err)
+	_, err = newThroughput("", "log/nsq_notifier")
+	assert.NotNil(t, err)
+}
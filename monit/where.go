@@ -0,0 +1,362 @@
+package monit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// whereExpr is parsed --where expression evaluated against json attributes.
+//
+// Supported are comparisons of attribute with literal: == != > >= < <=
+// and regexp match =~ !~, combined with && || ! and parentheses.
+// Literals are numbers, durations (200ms, 1.5s), quoted strings and true/false.
+// Numeric attribute compared with duration is in milliseconds.
+// Nested attributes are referenced by path, like request.method or items[0].id.
+// Comparison with missing attribute is false.
+type whereExpr interface {
+	eval(m map[string]interface{}) bool
+}
+
+type whereAnd struct{ l, r whereExpr }
+type whereOr struct{ l, r whereExpr }
+type whereNot struct{ e whereExpr }
+
+func (e whereAnd) eval(m map[string]interface{}) bool { return e.l.eval(m) && e.r.eval(m) }
+func (e whereOr) eval(m map[string]interface{}) bool  { return e.l.eval(m) || e.r.eval(m) }
+func (e whereNot) eval(m map[string]interface{}) bool { return !e.e.eval(m) }
+
+// literal kinds
+const (
+	litString = iota
+	litNumber
+	litDuration
+	litBool
+)
+
+type whereCmp struct {
+	attr string
+	op   string // empty when only attribute existence is checked
+	kind int
+	str  string
+	num  float64
+	dur  time.Duration
+	b    bool
+	rx   *regexp.Regexp
+}
+
+func (c whereCmp) eval(m map[string]interface{}) bool {
+	v, ok := lookup(m, c.attr)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "":
+		b, isBool := v.(bool)
+		return v != nil && (!isBool || b)
+	case "=~":
+		return c.rx.MatchString(formatValue(v))
+	case "!~":
+		return !c.rx.MatchString(formatValue(v))
+	}
+	switch c.kind {
+	case litNumber:
+		f, ok := toNumber(v)
+		return ok && compare(c.op, f, c.num)
+	case litDuration:
+		d, ok := toDuration(v)
+		return ok && compare(c.op, float64(d), float64(c.dur))
+	case litBool:
+		b, ok := v.(bool)
+		if !ok {
+			return false
+		}
+		return (c.op == "==") == (b == c.b)
+	}
+	s := formatValue(v)
+	switch c.op {
+	case "==":
+		return s == c.str
+	case "!=":
+		return s != c.str
+	}
+	return compare(c.op, float64(strings.Compare(s, c.str)), 0)
+}
+
+func compare(op string, a, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toDuration(v interface{}) (time.Duration, bool) {
+	switch t := v.(type) {
+	case float64:
+		return time.Duration(t * float64(time.Millisecond)), true
+	case string:
+		if d, err := time.ParseDuration(t); err == nil {
+			return d, true
+		}
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return time.Duration(f * float64(time.Millisecond)), true
+		}
+	}
+	return 0, false
+}
+
+// parseWhere parses --where expression, nil if it is empty
+func parseWhere(s string) (whereExpr, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	toks, err := tokenizeWhere(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{toks: toks}
+	e, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression: %v", err)
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("invalid where expression: unexpected %s", p.toks[p.pos].val)
+	}
+	return e, nil
+}
+
+type whereToken struct {
+	typ byte // o operator, i identifier, s string, n number or duration
+	val string
+}
+
+var whereOps = []string{"&&", "||", "==", "!=", ">=", "<=", "=~", "!~", ">", "<", "!", "(", ")"}
+
+func tokenizeWhere(s string) ([]whereToken, error) {
+	var toks []whereToken
+	isIdent := func(r rune, first bool) bool {
+		return unicode.IsLetter(r) || r == '_' || r == '@' ||
+			(!first && (unicode.IsDigit(r) || r == '.' || r == '-'))
+	}
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				if rs[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string in where expression")
+			}
+			val := string(rs[i+1 : j])
+			if r == '"' {
+				u, err := strconv.Unquote(string(rs[i : j+1]))
+				if err != nil {
+					return nil, fmt.Errorf("invalid string %s in where expression", string(rs[i:j+1]))
+				}
+				val = u
+			}
+			toks = append(toks, whereToken{'s', val})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i + 1
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || unicode.IsLetter(rs[j])) {
+				j++
+			}
+			toks = append(toks, whereToken{'n', string(rs[i:j])})
+			i = j
+		case isIdent(r, true):
+			j := i + 1
+			for j < len(rs) && isIdent(rs[j], false) {
+				j++
+			}
+			toks = append(toks, whereToken{'i', string(rs[i:j])})
+			i = j
+		default:
+			op := ""
+			for _, o := range whereOps {
+				if strings.HasPrefix(string(rs[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q in where expression", r)
+			}
+			toks = append(toks, whereToken{'o', op})
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+type whereParser struct {
+	toks []whereToken
+	pos  int
+}
+
+func (p *whereParser) peek(op string) bool {
+	return p.pos < len(p.toks) && p.toks[p.pos].typ == 'o' && p.toks[p.pos].val == op
+}
+
+func (p *whereParser) next() (whereToken, error) {
+	if p.pos >= len(p.toks) {
+		return whereToken{}, fmt.Errorf("unexpected end")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *whereParser) or() (whereExpr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = whereOr{l, r}
+	}
+	return l, nil
+}
+
+func (p *whereParser) and() (whereExpr, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = whereAnd{l, r}
+	}
+	return l, nil
+}
+
+func (p *whereParser) unary() (whereExpr, error) {
+	if p.peek("!") {
+		p.pos++
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return whereNot{e}, nil
+	}
+	if p.peek("(") {
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return e, nil
+	}
+	return p.cmp()
+}
+
+func (p *whereParser) cmp() (whereExpr, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.typ != 'i' {
+		return nil, fmt.Errorf("expecting attribute, got %s", t.val)
+	}
+	c := whereCmp{attr: t.val}
+	if p.pos >= len(p.toks) || p.toks[p.pos].typ != 'o' {
+		return c, nil
+	}
+	switch op := p.toks[p.pos].val; op {
+	case "==", "!=", ">", ">=", "<", "<=", "=~", "!~":
+		c.op = op
+		p.pos++
+	default:
+		return c, nil
+	}
+	lit, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.setLiteral(lit); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *whereCmp) setLiteral(t whereToken) error {
+	if c.op == "=~" || c.op == "!~" {
+		rx, err := regexp.Compile(t.val)
+		if err != nil {
+			return err
+		}
+		c.rx = rx
+		return nil
+	}
+	switch t.typ {
+	case 's':
+		c.kind, c.str = litString, t.val
+	case 'n':
+		if f, err := strconv.ParseFloat(t.val, 64); err == nil {
+			c.kind, c.num = litNumber, f
+			return nil
+		}
+		d, err := time.ParseDuration(t.val)
+		if err != nil {
+			return fmt.Errorf("invalid number or duration %s", t.val)
+		}
+		c.kind, c.dur = litDuration, d
+	case 'i':
+		switch t.val {
+		case "true", "false":
+			c.kind, c.b = litBool, t.val == "true"
+		default:
+			// unquoted word is compared as string
+			c.kind, c.str = litString, t.val
+		}
+	default:
+		return fmt.Errorf("expecting value, got %s", t.val)
+	}
+	return nil
+}
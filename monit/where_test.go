@@ -0,0 +1,43 @@
+package monit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhere(t *testing.T) {
+	var m map[string]interface{}
+	json.Unmarshal([]byte(`{"status":503,"duration":"250ms","took":120,"method":"POST","ok":false,
+		"url":"/api/v1/users","req":{"id":"49B6"}}`), &m)
+
+	cases := map[string]bool{
+		`status >= 500`:                                        true,
+		`status >= 500 && duration > 200ms && method != "GET"`: true,
+		`status < 500 || method == 'POST'`:                     true,
+		`!(status == 503)`:                                     false,
+		`took > 100ms && took < 1s`:                            true,
+		`duration > 1s`:                                        false,
+		`url =~ "^/api/v[0-9]+/"`:                              true,
+		`url !~ users`:                                         false,
+		`req.id == "49B6"`:                                     true,
+		`ok == false && !ok`:                                   true,
+		`missing != 1`:                                         false,
+		`method`:                                               true,
+		`status == -1 || (method == GET)`:                      false,
+	}
+	for s, expected := range cases {
+		e, err := parseWhere(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, e.eval(m), s)
+	}
+
+	for _, s := range []string{`status >=`, `(status > 1`, `status > 1 method`, `"x" == 1`, `a =~ "("`, `a > 1x`, `a == "x`} {
+		_, err := parseWhere(s)
+		assert.NotNil(t, err, s)
+	}
+	e, err := parseWhere("")
+	assert.Nil(t, err)
+	assert.Nil(t, e)
+}
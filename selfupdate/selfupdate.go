@@ -0,0 +1,298 @@
+// Package selfupdate replaces running pitwall binary with the release
+// downloaded from GitHub releases (created by goreleaser).
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// DefaultURL is GitHub API endpoint of pitwall releases
+const DefaultURL = "https://api.github.com/repos/minus5/pitwall/releases"
+
+const binary = "pitwall"
+
+// Options for the self update
+type Options struct {
+	URL        string // releases endpoint
+	Version    string // pinned release version, latest if empty
+	Current    string // running version
+	Token      string // GitHub token for private releases
+	Key        string // cosign public key, checksums signature is required if set
+	Check      bool   // only report available version
+	Force      bool   // update even if the version is the same
+	Executable string // file to replace, running binary if empty
+	GOOS       string
+	GOARCH     string
+}
+
+// Release is GitHub release
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is file attached to the release
+type Asset struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// Run checks release endpoint and replaces executable with the release binary
+func Run(o Options) error {
+	if o.URL == "" {
+		o.URL = DefaultURL
+	}
+	if o.GOOS == "" {
+		o.GOOS = runtime.GOOS
+	}
+	if o.GOARCH == "" {
+		o.GOARCH = runtime.GOARCH
+	}
+	r, err := o.release()
+	if err != nil {
+		return err
+	}
+	if o.Check {
+		fmt.Printf("current version %s, release %s\n", o.Current, r.Tag)
+		return nil
+	}
+	if sameVersion(o.Current, r.Tag) && !o.Force {
+		fmt.Printf("already at version %s\n", r.Tag)
+		return nil
+	}
+	a := r.asset(o.GOOS, o.GOARCH)
+	if a == nil {
+		return fmt.Errorf("release %s has no binary for %s/%s", r.Tag, o.GOOS, o.GOARCH)
+	}
+	sums, err := o.checksums(r)
+	if err != nil {
+		return err
+	}
+	buf, err := o.download(*a)
+	if err != nil {
+		return err
+	}
+	if err := verify(a.Name, buf, sums); err != nil {
+		return err
+	}
+	if strings.HasSuffix(a.Name, ".tar.gz") {
+		if buf, err = extract(buf, binary); err != nil {
+			return err
+		}
+	}
+	fn, err := o.executable()
+	if err != nil {
+		return err
+	}
+	if err := replace(fn, buf); err != nil {
+		return err
+	}
+	log.S("file", fn).S("from", o.Current).S("to", r.Tag).Info("updated")
+	fmt.Printf("updated %s from %s to %s\n", fn, o.Current, r.Tag)
+	return nil
+}
+
+// release gets pinned or latest release
+func (o Options) release() (*Release, error) {
+	url := o.URL + "/latest"
+	if o.Version != "" {
+		url = o.URL + "/tags/v" + strings.TrimPrefix(o.Version, "v")
+	}
+	buf, err := o.get(url, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	r := &Release{}
+	if err := json.Unmarshal(buf, r); err != nil {
+		return nil, fmt.Errorf("invalid release from %s: %v", url, err)
+	}
+	return r, nil
+}
+
+// checksums downloads release checksums and verifies their signature
+func (o Options) checksums(r *Release) (map[string]string, error) {
+	var sums, sig *Asset
+	for i, a := range r.Assets {
+		switch {
+		case strings.HasSuffix(a.Name, "checksums.txt"):
+			sums = &r.Assets[i]
+		case strings.HasSuffix(a.Name, "checksums.txt.sig"):
+			sig = &r.Assets[i]
+		}
+	}
+	if sums == nil {
+		return nil, fmt.Errorf("release %s has no checksums", r.Tag)
+	}
+	buf, err := o.download(*sums)
+	if err != nil {
+		return nil, err
+	}
+	if o.Key != "" {
+		if sig == nil {
+			return nil, fmt.Errorf("release %s checksums are not signed", r.Tag)
+		}
+		s, err := o.download(*sig)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifySignature(env.ExpandPath(o.Key), buf, s); err != nil {
+			return nil, err
+		}
+	}
+	return parseChecksums(buf), nil
+}
+
+func (o Options) download(a Asset) ([]byte, error) {
+	if a.URL != "" {
+		return o.get(a.URL, "application/octet-stream")
+	}
+	return o.get(a.DownloadURL, "application/octet-stream")
+}
+
+func (o Options) get(url, accept string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if o.Token != "" {
+		req.Header.Set("Authorization", "token "+o.Token)
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s failed: %s", url, rsp.Status)
+	}
+	return ioutil.ReadAll(rsp.Body)
+}
+
+func (o Options) executable() (string, error) {
+	if o.Executable != "" {
+		return o.Executable, nil
+	}
+	fn, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(fn)
+}
+
+// asset finds release binary for the platform.
+// Goreleaser names archives like pitwall_2.1.0_darwin_amd64.tar.gz.
+func (r *Release) asset(goos, goarch string) *Asset {
+	suffix := fmt.Sprintf("_%s_%s", goos, goarch)
+	for i, a := range r.Assets {
+		name := strings.ToLower(a.Name)
+		if strings.HasSuffix(name, suffix+".tar.gz") || strings.HasSuffix(name, suffix) {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// parseChecksums parses sha256sum output, file name to hex checksum
+func parseChecksums(buf []byte) map[string]string {
+	sums := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(buf))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) == 2 {
+			sums[strings.TrimPrefix(f[1], "*")] = f[0]
+		}
+	}
+	return sums
+}
+
+func verify(name string, buf []byte, sums map[string]string) error {
+	want, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("checksum for %s not found", name)
+	}
+	h := sha256.Sum256(buf)
+	if got := hex.EncodeToString(h[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+	return nil
+}
+
+// verifySignature verifies checksums file signature with cosign
+func verifySignature(key string, buf, sig []byte) error {
+	dir, err := ioutil.TempDir("", "pitwall-update")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "checksums.txt")
+	if err := ioutil.WriteFile(fn, buf, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fn+".sig", sig, 0644); err != nil {
+		return err
+	}
+	cmd := exec.Command("cosign", "verify-blob", "--key", key, "--signature", fn+".sig", fn)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("checksums signature verification failed: %v", err)
+	}
+	return nil
+}
+
+// extract finds file by name in tar.gz archive
+func extract(buf []byte, name string) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in archive", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(h.Name) == name && h.Typeflag == tar.TypeReg {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+// replace writes new binary next to the old one and renames it over
+func replace(fn string, buf []byte) error {
+	tmp := fn + ".new"
+	if err := ioutil.WriteFile(tmp, buf, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fn); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
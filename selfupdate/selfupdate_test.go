@@ -0,0 +1,88 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsset(t *testing.T) {
+	r := &Release{Assets: []Asset{
+		{Name: "pitwall_2.1.0_checksums.txt"},
+		{Name: "pitwall_2.1.0_darwin_amd64.tar.gz"},
+		{Name: "pitwall_2.1.0_linux_amd64.tar.gz"},
+	}}
+	assert.Equal(t, "pitwall_2.1.0_linux_amd64.tar.gz", r.asset("linux", "amd64").Name)
+	assert.Nil(t, r.asset("linux", "arm64"))
+}
+
+func TestParseChecksums(t *testing.T) {
+	sums := parseChecksums([]byte("abc  pitwall_linux_amd64.tar.gz\ndef *pitwall_darwin_amd64.tar.gz\n"))
+	assert.Equal(t, map[string]string{
+		"pitwall_linux_amd64.tar.gz":  "abc",
+		"pitwall_darwin_amd64.tar.gz": "def",
+	}, sums)
+	assert.Error(t, verify("pitwall_linux_amd64.tar.gz", []byte("x"), sums))
+	assert.Error(t, verify("other", []byte("x"), sums))
+}
+
+func TestRun(t *testing.T) {
+	archive := tarGz(t, binary, []byte("new binary"))
+	h := sha256.Sum256(archive)
+	sums := fmt.Sprintf("%s  pitwall_2.2.0_linux_amd64.tar.gz\n", hex.EncodeToString(h[:]))
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases/tags/v2.2.0":
+			json.NewEncoder(w).Encode(Release{Tag: "v2.2.0", Assets: []Asset{
+				{Name: "pitwall_2.2.0_checksums.txt", DownloadURL: ts.URL + "/checksums"},
+				{Name: "pitwall_2.2.0_linux_amd64.tar.gz", DownloadURL: ts.URL + "/archive"},
+			}})
+		case "/checksums":
+			w.Write([]byte(sums))
+		case "/archive":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "selfupdate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, binary)
+	assert.Nil(t, ioutil.WriteFile(fn, []byte("old binary"), 0755))
+
+	o := Options{URL: ts.URL + "/releases", Version: "2.2.0", Current: "v2.1.0", Executable: fn, GOOS: "linux", GOARCH: "amd64"}
+	assert.Nil(t, Run(o))
+	buf, _ := ioutil.ReadFile(fn)
+	assert.Equal(t, "new binary", string(buf))
+
+	o.Version = "2.3.0"
+	assert.Error(t, Run(o))
+}
+
+func tarGz(t *testing.T, name string, body []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+	tw.Write(body)
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}